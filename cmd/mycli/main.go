@@ -2,7 +2,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"flag"
 	"fmt"
@@ -10,234 +9,126 @@ import (
 	"strconv"
 	"strings"
 
-	goprompt "github.com/c-bata/go-prompt"
 	"github.com/tomblomfield/gocli/internal/cli"
 	"github.com/tomblomfield/gocli/internal/config"
 	"github.com/tomblomfield/gocli/internal/mysql"
 )
 
-var (
-	version = "0.1.0"
-
-	host       = flag.String("h", "", "Host address of the MySQL server")
-	port       = flag.Int("P", 3306, "Port number")
-	username   = flag.String("u", "", "Username")
-	password   = flag.Bool("p", false, "Prompt for password")
-	dbname     = flag.String("D", "", "Database name")
-	dsnAlias   = flag.String("d", "", "DSN alias from config")
-	socket     = flag.String("S", "", "Socket file path")
-	configPath = flag.String("myclirc", "", "Config file path")
-	lessChatty = flag.Bool("less-chatty", false, "Skip intro/goodbye")
-	promptFmt  = flag.String("R", "", "Prompt format")
-	autoVert   = flag.Bool("auto-vertical-output", false, "Auto vertical for wide results")
-	listDSN    = flag.Bool("list-dsn", false, "List DSN aliases and exit")
-	showVer    = flag.Bool("V", false, "Print version")
-	execute    = flag.String("e", "", "Execute command and exit")
-	tableOut   = flag.Bool("t", false, "Force table output")
-	csvOut     = flag.Bool("csv", false, "Force CSV output")
-	logFile    = flag.String("l", "", "Audit log file")
-	initCmd    = flag.String("init-command", "", "SQL to execute after connecting")
-	sslMode    = flag.String("ssl-mode", "auto", "SSL mode: auto, on, off")
-	sslCa      = flag.String("ssl-ca", "", "CA file in PEM format")
-	sslCert    = flag.String("ssl-cert", "", "Client X509 cert")
-	sslKey     = flag.String("ssl-key", "", "Client X509 key")
-	charset    = flag.String("charset", "", "Character set")
-	warn       = flag.Bool("warn", true, "Warn before destructive commands")
-	verbose    = flag.Bool("v", false, "Verbose output")
-	loginPath  = flag.String("g", "", "MySQL login path")
-)
+var version = "0.1.0"
 
 func main() {
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: mycli [OPTIONS] [DATABASE]\n\n")
-		fmt.Fprintf(os.Stderr, "A Go reimplementation of mycli - MySQL CLI with auto-completion.\n\n")
-		flag.PrintDefaults()
-	}
-	flag.Parse()
+	os.Exit(cli.Launch(cli.Backend{
+		Name:        "mycli",
+		Mode:        cli.MySQL,
+		Server:      "MySQL",
+		Version:     version,
+		Usage:       "[DATABASE]",
+		Description: "A Go reimplementation of mycli - MySQL CLI with auto-completion.",
+
+		Config:     config.DefaultMySQLConfig,
+		ConfigFlag: "myclirc",
+
+		VersionFlag:  "V",
+		PromptFlag:   "R",
+		PasswordFlag: "p",
+		LogFlag:      "l",
+
+		Register: register,
+	}, os.Args[1:]))
+}
 
-	if *showVer {
-		fmt.Printf("mycli (gocli) %s\n", version)
-		os.Exit(0)
-	}
+// target holds the MySQL connection flags and the connection parameters
+// resolved from them.
+type target struct {
+	host      *string
+	port      *int
+	username  *string
+	dbname    *string
+	dsnAlias  *string
+	socket    *string
+	loginPath *string
+	sslMode   *string
+	sslCa     *string
+	sslCert   *string
+	sslKey    *string
+	charset   *string
+	warn      *bool
+	tableOut  *bool
+	csvOut    *bool
+
+	cfg  *config.Config
+	conn mysql.ConnectionConfig
+}
 
-	// Load config
-	cfg := config.DefaultMySQLConfig()
-	cfgPath := *configPath
-	if err := cfg.Load(cfgPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not load config: %s\n", err)
-	}
+func register(fs *flag.FlagSet) cli.Target {
+	t := &target{
+		host:      fs.String("h", "", "Host address of the MySQL server"),
+		port:      fs.Int("P", 3306, "Port number"),
+		username:  fs.String("u", "", "Username"),
+		dbname:    fs.String("D", "", "Database name"),
+		dsnAlias:  fs.String("d", "", "DSN alias from config"),
+		socket:    fs.String("S", "", "Socket file path"),
+		loginPath: fs.String("g", "", "Read this login path from the option files"),
+		sslMode:   fs.String("ssl-mode", "auto", "SSL mode: auto, on, off"),
+		sslCa:     fs.String("ssl-ca", "", "CA file in PEM format"),
+		sslCert:   fs.String("ssl-cert", "", "Client X509 cert"),
+		sslKey:    fs.String("ssl-key", "", "Client X509 key"),
+		charset:   fs.String("charset", "", "Character set"),
+		warn:      fs.Bool("warn", true, "Warn before destructive commands"),
+		tableOut:  fs.Bool("t", false, "Force table output"),
+		csvOut:    fs.Bool("csv", false, "Force CSV output"),
+	}
+	fs.Bool("v", false, "Verbose output (accepted for compatibility)")
+	return t
+}
 
-	// Apply CLI flags
-	if *lessChatty {
-		cfg.LessChatty = true
-	}
-	if *promptFmt != "" {
-		cfg.Prompt = *promptFmt
-	}
-	if *autoVert {
-		cfg.AutoExpand = true
+func (t *target) Resolve(cfg *config.Config, args []string) error {
+	t.cfg = cfg
+	if *t.dsnAlias != "" {
+		cfg.ApplyAlias(*t.dsnAlias)
 	}
-	if !*warn {
+	if !*t.warn {
 		cfg.DestructiveWarning = false
 	}
-
-	// List DSN aliases
-	if *listDSN {
-		if len(cfg.DSNAliases) == 0 {
-			fmt.Println("No DSN aliases configured.")
-		} else {
-			for name, dsn := range cfg.DSNAliases {
-				fmt.Printf("  %s = %s\n", name, dsn)
-			}
-		}
-		os.Exit(0)
-	}
-
-	// Build connection config
-	connCfg := buildMySQLConfig(cfg)
-
-	// Prompt for password if -p flag
-	if *password {
-		fmt.Fprint(os.Stderr, "Password: ")
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() {
-			connCfg.Password = scanner.Text()
-		}
-	}
-
-	// Connect
-	executor, err := mysql.NewExecutor(connCfg)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Connection failed: %s\n", err)
-		os.Exit(1)
-	}
-	defer executor.Close()
-
-	// Init command
-	if *initCmd != "" {
-		if _, err := executor.Execute(context.Background(), *initCmd); err != nil {
-			fmt.Fprintf(os.Stderr, "Init command error: %s\n", err)
-		}
-	}
-
-	// Create app (used by both -e mode and interactive mode)
-	app := cli.NewApp(cli.MySQL, executor, executor, cfg)
-
-	// Execute mode
-	if *execute != "" {
-		if app.ExecuteNonInteractive(*execute) {
-			os.Exit(1)
-		}
-		os.Exit(0)
-	}
-
-	if !cfg.LessChatty {
-		ver, _ := executor.ServerVersion()
-		fmt.Printf("gocli (mycli) %s\n", version)
-		if ver != "" {
-			fmt.Printf("Server: MySQL %s\n", ver)
-		}
-		fmt.Printf("Database: %s\n", executor.Database())
-		fmt.Println("Type \\? for help.")
-		fmt.Println()
+	switch {
+	case *t.csvOut:
+		cfg.TableFormat = "csv"
+	case *t.tableOut:
+		cfg.TableFormat = "ascii"
 	}
-
-	// Refresh completions in background
-	go app.RefreshCompletions()
-
-	// Run interactive loop
-	runREPL(app, cfg)
-
-	if !cfg.LessChatty {
-		fmt.Println("Goodbye!")
-	}
-
-	_ = logFile
-	_ = tableOut
-	_ = csvOut
-	_ = verbose
-	_ = loginPath
+	t.conn = t.buildConfig(cfg, args)
+	return nil
 }
 
-func runREPL(app *cli.App, cfg *config.Config) {
-	fi, _ := os.Stdin.Stat()
-	if (fi.Mode() & os.ModeCharDevice) == 0 {
-		runBasicREPL(app, cfg)
-		return
-	}
+func (t *target) SetPassword(password string) {
+	t.conn.Password = password
+}
 
-	completer := func(d goprompt.Document) []goprompt.Suggest {
-		text := d.TextBeforeCursor()
-		suggestions := app.Complete(text, len(text))
-		s := make([]goprompt.Suggest, 0, len(suggestions))
-		for _, sg := range suggestions {
-			s = append(s, goprompt.Suggest{
-				Text:        sg.Text,
-				Description: sg.Description,
-			})
-		}
-		return s
+func (t *target) Open(_ context.Context, database string) (cli.Executor, error) {
+	c := t.conn
+	if database != "" {
+		c.Database = database
 	}
-
-	shouldQuit := false
-
-	executor := func(input string) {
-		input = strings.TrimSpace(input)
-		if input == "" {
-			return
-		}
-		if app.HandleInput(input) {
-			shouldQuit = true
-		}
+	c.ReadOnly = t.cfg.ReadOnly
+	c.InitCommand = t.cfg.InitCommand
+	e, err := mysql.NewExecutor(c)
+	if err != nil {
+		return nil, err
 	}
-
-	p := goprompt.New(
-		executor,
-		completer,
-		goprompt.OptionPrefix(app.GetPrompt()),
-		goprompt.OptionLivePrefix(func() (string, bool) {
-			return app.GetPrompt(), true
-		}),
-		goprompt.OptionTitle("gocli"),
-		goprompt.OptionPrefixTextColor(goprompt.Cyan),
-		goprompt.OptionSuggestionBGColor(goprompt.DarkGray),
-		goprompt.OptionSuggestionTextColor(goprompt.White),
-		goprompt.OptionSelectedSuggestionBGColor(goprompt.Blue),
-		goprompt.OptionSelectedSuggestionTextColor(goprompt.White),
-		goprompt.OptionDescriptionBGColor(goprompt.DarkGray),
-		goprompt.OptionDescriptionTextColor(goprompt.LightGray),
-		goprompt.OptionSelectedDescriptionBGColor(goprompt.Blue),
-		goprompt.OptionSelectedDescriptionTextColor(goprompt.White),
-		goprompt.OptionMaxSuggestion(10),
-		goprompt.OptionCompletionOnDown(),
-		goprompt.OptionSetExitCheckerOnInput(func(in string, breakline bool) bool {
-			return shouldQuit
-		}),
-	)
-
-	p.Run()
+	return e, nil
 }
 
-func runBasicREPL(app *cli.App, cfg *config.Config) {
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
-
-	for {
-		fmt.Fprint(os.Stdout, app.GetPrompt())
-
-		if !scanner.Scan() {
-			break
-		}
-
-		if shouldQuit := app.HandleInput(scanner.Text()); shouldQuit {
-			break
-		}
+func (t *target) CacheKey() string {
+	server := fmt.Sprintf("%s:%d", t.conn.Host, t.conn.Port)
+	if t.conn.Socket != "" {
+		server = t.conn.Socket
 	}
+	return fmt.Sprintf("mysql://%s@%s", t.conn.User, server)
 }
 
-func buildMySQLConfig(cfg *config.Config) mysql.ConnectionConfig {
-	// Start with my.cnf defaults
-	connCfg := mysql.ParseMyCnf()
+func (t *target) buildConfig(cfg *config.Config, args []string) mysql.ConnectionConfig {
+	// Start with my.cnf defaults and the login path, if any
+	connCfg := mysql.ParseLoginPath(*t.loginPath)
 
 	// Environment variables
 	if h := os.Getenv("MYSQL_HOST"); h != "" {
@@ -256,7 +147,6 @@ func buildMySQLConfig(cfg *config.Config) mysql.ConnectionConfig {
 	}
 
 	// Positional arguments
-	args := flag.Args()
 	if len(args) >= 1 {
 		if strings.HasPrefix(args[0], "mysql://") {
 			if parsed, err := mysql.ParseDSN(args[0]); err == nil {
@@ -267,8 +157,8 @@ func buildMySQLConfig(cfg *config.Config) mysql.ConnectionConfig {
 	}
 
 	// DSN alias
-	if *dsnAlias != "" {
-		if dsn, ok := cfg.DSNAliases[*dsnAlias]; ok {
+	if *t.dsnAlias != "" {
+		if dsn, ok := cfg.DSNAliases[*t.dsnAlias]; ok {
 			if parsed, err := mysql.ParseDSN(dsn); err == nil {
 				return parsed
 			}
@@ -276,37 +166,37 @@ func buildMySQLConfig(cfg *config.Config) mysql.ConnectionConfig {
 	}
 
 	// CLI flags override
-	if *host != "" {
-		connCfg.Host = *host
+	if *t.host != "" {
+		connCfg.Host = *t.host
 	}
-	if *port != 3306 {
-		connCfg.Port = *port
+	if *t.port != 3306 {
+		connCfg.Port = *t.port
 	}
-	if *username != "" {
-		connCfg.User = *username
+	if *t.username != "" {
+		connCfg.User = *t.username
 	}
-	if *dbname != "" {
-		connCfg.Database = *dbname
+	if *t.dbname != "" {
+		connCfg.Database = *t.dbname
 	}
-	if *socket != "" {
-		connCfg.Socket = *socket
+	if *t.socket != "" {
+		connCfg.Socket = *t.socket
 	}
-	if *charset != "" {
-		connCfg.Charset = *charset
+	if *t.charset != "" {
+		connCfg.Charset = *t.charset
 	}
 
 	// SSL
-	if *sslMode == "on" {
+	if *t.sslMode == "on" {
 		connCfg.SSL = true
 	}
-	if *sslCa != "" {
-		connCfg.SSLCa = *sslCa
+	if *t.sslCa != "" {
+		connCfg.SSLCa = *t.sslCa
 	}
-	if *sslCert != "" {
-		connCfg.SSLCert = *sslCert
+	if *t.sslCert != "" {
+		connCfg.SSLCert = *t.sslCert
 	}
-	if *sslKey != "" {
-		connCfg.SSLKey = *sslKey
+	if *t.sslKey != "" {
+		connCfg.SSLKey = *t.sslKey
 	}
 
 	return connCfg
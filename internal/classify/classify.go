@@ -0,0 +1,461 @@
+// Package classify tells what SQL statements do to the database, working
+// from the tokens the highlighter produces, so the CLI can warn before
+// destructive statements and refuse writes in read-only mode.
+package classify
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/tomblomfield/gocli/internal/highlight"
+	"github.com/tomblomfield/gocli/internal/statement"
+)
+
+// Action is one thing a statement does. A statement usually has one, but
+// data-modifying WITH queries and EXPLAIN ANALYZE add those of the
+// statements inside them.
+type Action struct {
+	Verb    string   // first keyword, lower-cased, such as "delete"
+	Object  string   // kind of object for DDL, lower-cased, such as "table"
+	Targets []string // objects acted on, as written
+	Where   bool     // an UPDATE or DELETE has a WHERE clause
+	Nested  bool     // inside a WITH query or EXPLAIN ANALYZE
+	Writes  bool     // may change data, schema, privileges or server state, or allow it
+}
+
+// Warning is an action that destructive_keywords asks to confirm.
+type Warning struct {
+	Action
+}
+
+// String describes the warning, naming the objects affected, such as
+// "DELETE without WHERE on every row of users".
+func (w Warning) String() string {
+	verb := strings.ToUpper(w.Verb)
+	targets := strings.Join(w.Targets, ", ")
+	var s string
+	switch {
+	case (w.Verb == "update" || w.Verb == "delete") && !w.Where:
+		s = verb + " without WHERE on every row of " + targets
+	case w.Object != "":
+		s = verb + " " + strings.ToUpper(w.Object) + " " + targets
+	default:
+		s = verb + " " + targets
+	}
+	s = strings.TrimSpace(s)
+	if w.Nested {
+		s += " (inside another statement)"
+	}
+	return s
+}
+
+// Warnings returns the actions in stmt that keywords mark destructive.
+// UPDATE and DELETE only count without a WHERE clause, since a WHERE
+// limits them to the rows it matches.
+func Warnings(stmt string, keywords []string) []Warning {
+	var warnings []Warning
+	for _, a := range Actions(stmt) {
+		if !slices.ContainsFunc(keywords, func(kw string) bool { return strings.EqualFold(kw, a.Verb) }) {
+			continue
+		}
+		if (a.Verb == "update" || a.Verb == "delete") && a.Where {
+			continue
+		}
+		warnings = append(warnings, Warning{a})
+	}
+	return warnings
+}
+
+// IsWrite reports whether input may change the database, or would let
+// later statements do so by turning off read-only transactions. Input
+// holding several statements is a write if any of them is, however the
+// backends' quoting rules split it. Keywords inside strings, comments and
+// quoted names are ignored.
+func IsWrite(input string) bool {
+	for _, syntax := range []statement.Syntax{statement.PostgreSQL, statement.MySQL} {
+		for _, stmt := range statement.Split(input, syntax) {
+			if slices.ContainsFunc(Actions(stmt), func(a Action) bool { return a.Writes }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Actions returns what stmt does, its main action first.
+func Actions(stmt string) []Action {
+	p := &parser{}
+	for _, t := range highlight.Tokenize(stmt) {
+		switch t.Type {
+		case highlight.TokenWhitespace, highlight.TokenComment:
+			continue
+		}
+		p.toks = append(p.toks, t)
+	}
+	return p.statement(0, len(p.toks), false)
+}
+
+// writeVerbs start statements that change data, schema, privileges or
+// server state.
+var writeVerbs = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true,
+	"upsert": true, "replace": true, "create": true, "alter": true,
+	"drop": true, "truncate": true, "rename": true, "comment": true,
+	"grant": true, "revoke": true, "load": true, "call": true,
+	"do": true, "reindex": true, "cluster": true, "refresh": true,
+	"vacuum": true, "import": true, "handler": true, "install": true,
+	"uninstall": true, "shutdown": true, "kill": true, "flush": true,
+	"reset": true, "purge": true, "optimize": true, "repair": true,
+	"lock": true, "security": true, "reassign": true, "prepare": true,
+	"execute": true,
+}
+
+// readOnlySettings are the variables that make transactions, or the
+// whole server, read-only.
+var readOnlySettings = map[string]bool{
+	"default_transaction_read_only": true, "transaction_read_only": true,
+	"tx_read_only": true, "read_only": true, "super_read_only": true,
+}
+
+// modifiers are the options that may come between a verb and its
+// object or target.
+var modifiers = map[string]bool{
+	"only": true, "low_priority": true, "quick": true, "ignore": true,
+	"delayed": true, "high_priority": true, "into": true, "table": true,
+}
+
+// objectKinds name the kinds of object DDL acts on.
+var objectKinds = map[string]bool{
+	"table": true, "view": true, "materialized": true, "index": true,
+	"schema": true, "database": true, "sequence": true, "function": true,
+	"procedure": true, "trigger": true, "type": true, "extension": true,
+	"role": true, "user": true, "domain": true, "foreign": true,
+	"event": true, "server": true, "tablespace": true, "policy": true,
+	"rule": true, "aggregate": true, "collation": true, "language": true,
+	"publication": true, "subscription": true, "statistics": true,
+	"routine": true, "owned": true, "by": true, "group": true,
+}
+
+// ddlModifiers may come before the kind of object in DDL.
+var ddlModifiers = map[string]bool{
+	"unique": true, "temporary": true, "temp": true, "unlogged": true,
+	"concurrently": true, "online": true, "offline": true, "or": true,
+	"replace": true, "global": true, "local": true, "recursive": true,
+}
+
+type parser struct {
+	toks []highlight.Token
+}
+
+// word returns the lower-cased keyword or name at i, or "" for anything
+// else, including quoted names.
+func (p *parser) word(i int) string {
+	if i >= len(p.toks) {
+		return ""
+	}
+	switch t := p.toks[i]; t.Type {
+	case highlight.TokenKeyword, highlight.TokenName, highlight.TokenFunction:
+		if strings.ContainsAny(t.Value[:1], "\"`") {
+			return ""
+		}
+		return strings.ToLower(t.Value)
+	}
+	return ""
+}
+
+func (p *parser) is(i int, value string) bool {
+	return i < len(p.toks) && p.toks[i].Value == value
+}
+
+// isName reports whether the token at i can be part of an object name.
+func (p *parser) isName(i int) bool {
+	if i >= len(p.toks) {
+		return false
+	}
+	switch p.toks[i].Type {
+	case highlight.TokenKeyword, highlight.TokenName, highlight.TokenFunction:
+		return true
+	}
+	return false
+}
+
+// close returns the index of the parenthesis closing the one at i, or end
+// if it is not closed.
+func (p *parser) close(i, end int) int {
+	depth := 0
+	for ; i < end; i++ {
+		switch p.toks[i].Value {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return end
+}
+
+// name reads a possibly qualified name at i, returning it as written and
+// the index after it.
+func (p *parser) name(i, end int) (string, int) {
+	if i >= end || !p.isName(i) {
+		return "", i
+	}
+	var b strings.Builder
+	b.WriteString(p.toks[i].Value)
+	i++
+	for i+1 < end && p.is(i, ".") && p.isName(i+1) {
+		b.WriteString("." + p.toks[i+1].Value)
+		i += 2
+	}
+	return b.String(), i
+}
+
+// names reads a comma-separated list of names at i.
+func (p *parser) names(i, end int) []string {
+	var names []string
+	for {
+		name, next := p.name(i, end)
+		if name == "" {
+			return names
+		}
+		names = append(names, name)
+		i = next
+		if p.is(i, "*") { // TRUNCATE t * includes descendants
+			i++
+		}
+		if !p.is(i, ",") {
+			return names
+		}
+		i++
+	}
+}
+
+// topLevel reports whether word appears in toks[i:end] outside of any
+// parentheses.
+func (p *parser) topLevel(i, end int, word string) bool {
+	depth := 0
+	for ; i < end; i++ {
+		switch p.toks[i].Value {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		default:
+			if depth == 0 && p.word(i) == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statement returns the actions of the statement in toks[i:end].
+func (p *parser) statement(i, end int, nested bool) []Action {
+	for i < end && p.is(i, "(") {
+		i++
+	}
+	start := i
+	verb := p.word(i)
+	switch verb {
+	case "":
+		return nil
+	case "with":
+		return p.with(i+1, end, nested)
+	case "explain":
+		return p.explain(i+1, end, nested)
+	}
+
+	a := Action{Verb: verb, Nested: nested, Writes: writeVerbs[verb]}
+	i++
+	switch verb {
+	case "update", "delete", "insert", "replace", "merge", "upsert", "truncate":
+		for modifiers[p.word(i)] {
+			i++
+		}
+		if verb == "delete" && p.word(i) != "from" {
+			// MySQL's DELETE t1, t2 FROM t1 JOIN t2 ...
+			a.Targets = p.names(i, end)
+		} else {
+			if p.word(i) == "from" {
+				i++
+				for modifiers[p.word(i)] {
+					i++
+				}
+			}
+			a.Targets = p.names(i, end)
+		}
+		if verb == "update" || verb == "delete" {
+			a.Where = p.topLevel(i, end, "where")
+		}
+	case "drop", "alter", "create":
+		var object []string
+		for objectKinds[p.word(i)] || ddlModifiers[p.word(i)] {
+			if objectKinds[p.word(i)] {
+				object = append(object, p.word(i))
+			}
+			i++
+		}
+		a.Object = strings.Join(object, " ")
+		for _, w := range []string{"if", "not", "exists", "only"} {
+			if p.word(i) == w {
+				i++
+			}
+		}
+		if verb == "drop" {
+			a.Targets = p.names(i, end)
+		} else if name, _ := p.name(i, end); name != "" {
+			a.Targets = []string{name}
+		}
+	case "select":
+		// SELECT INTO creates a table, or in MySQL writes a file
+		a.Writes = p.topLevel(i, end, "into")
+	case "copy":
+		a.Targets = p.names(i, end)
+		a.Writes = p.topLevel(i, end, "from")
+	case "set", "begin", "start":
+		// Making transactions read-write would let writes through, and SET
+		// GLOBAL changes the server for every session
+		a.Writes = p.readWrite(i, end) || p.leavesReadOnly(i, end) ||
+			p.topLevel(i, end, "global") || p.topLevel(i, end, "persist") || p.topLevel(i, end, "persist_only")
+	}
+	if p.setConfig(start, end) {
+		a.Writes = true
+	}
+	return []Action{a}
+}
+
+// setConfig reports whether toks[i:end] calls PostgreSQL's set_config on
+// a setting of transactions, which could make them read-write, or on a
+// setting it does not name with a literal.
+func (p *parser) setConfig(i, end int) bool {
+	for ; i+1 < end; i++ {
+		if p.word(i) != "set_config" || !p.is(i+1, "(") {
+			continue
+		}
+		if i+2 >= end || p.toks[i+2].Type != highlight.TokenString {
+			return true
+		}
+		name := strings.ToLower(strings.Trim(p.toks[i+2].Value, "'"))
+		if readOnlySettings[name] || strings.Contains(name, "transaction") {
+			return true
+		}
+	}
+	return false
+}
+
+// readWrite reports whether toks[i:end] has READ WRITE, as in SET
+// TRANSACTION READ WRITE or BEGIN READ WRITE.
+func (p *parser) readWrite(i, end int) bool {
+	for ; i+1 < end; i++ {
+		if p.word(i) == "read" && p.word(i+1) == "write" {
+			return true
+		}
+	}
+	return false
+}
+
+// leavesReadOnly reports whether toks[i:end] sets a variable making
+// transactions read-only to anything but on, as in SET
+// default_transaction_read_only = off.
+func (p *parser) leavesReadOnly(i, end int) bool {
+	for ; i < end; i++ {
+		if !readOnlySettings[p.word(i)] {
+			continue
+		}
+		j := i + 1
+		if p.is(j, "=") || p.is(j, ":=") || p.word(j) == "to" {
+			j++
+		}
+		if j >= end {
+			return true
+		}
+		switch strings.ToLower(strings.Trim(p.toks[j].Value, `'"`)) {
+		case "on", "true", "1", "yes":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// with parses a WITH query from the first CTE at i: each CTE's body is a
+// nested statement, followed by the main statement.
+func (p *parser) with(i, end int, nested bool) []Action {
+	if p.word(i) == "recursive" {
+		i++
+	}
+	var ctes []Action
+	for i < end {
+		// name [(columns)] AS [NOT] [MATERIALIZED] (body)
+		_, i = p.name(i, end)
+		if p.is(i, "(") {
+			i = p.close(i, end) + 1
+		}
+		for p.word(i) == "as" || p.word(i) == "not" || p.word(i) == "materialized" {
+			i++
+		}
+		if !p.is(i, "(") {
+			break
+		}
+		body := p.close(i, end)
+		ctes = append(ctes, p.statement(i+1, body, true)...)
+		i = body + 1
+		if !p.is(i, ",") {
+			break
+		}
+		i++
+	}
+	main := p.statement(i, end, nested)
+	if len(main) == 0 {
+		return ctes
+	}
+	// The main statement's action comes first; WITH ... SELECT writes if
+	// a CTE does
+	for _, c := range ctes {
+		main[0].Writes = main[0].Writes || c.Writes
+	}
+	return append(main, ctes...)
+}
+
+// explain parses EXPLAIN [ANALYZE] [options] statement. Only EXPLAIN
+// ANALYZE runs the statement, so only then does it act.
+func (p *parser) explain(i, end int, nested bool) []Action {
+	analyze := false
+	for {
+		switch w := p.word(i); {
+		case p.is(i, "("):
+			closing := p.close(i, end)
+			for j := i + 1; j < closing; j++ {
+				if p.word(j) == "analyze" {
+					// ANALYZE false turns it off again
+					analyze = p.word(j+1) != "false" && p.word(j+1) != "off" && !p.is(j+1, "0")
+				}
+			}
+			i = closing + 1
+			continue
+		case w == "analyze" || w == "analyse":
+			analyze = true
+			i++
+			continue
+		case w == "verbose" || w == "extended" || w == "partitions" || w == "format":
+			i++
+			if p.is(i, "=") {
+				i += 2
+			}
+			continue
+		}
+		break
+	}
+	explain := Action{Verb: "explain", Nested: nested}
+	if !analyze {
+		return []Action{explain}
+	}
+	inner := p.statement(i, end, true)
+	for _, a := range inner {
+		explain.Writes = explain.Writes || a.Writes
+	}
+	return append([]Action{explain}, inner...)
+}
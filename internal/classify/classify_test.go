@@ -0,0 +1,137 @@
+package classify
+
+import (
+	"strings"
+	"testing"
+)
+
+var defaultKeywords = []string{"drop", "shutdown", "delete", "truncate", "alter", "update"}
+
+func warningText(stmt string) string {
+	var s []string
+	for _, w := range Warnings(stmt, defaultKeywords) {
+		s = append(s, w.String())
+	}
+	return strings.Join(s, "; ")
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		stmt, want string
+	}{
+		{"SELECT * FROM users", ""},
+		{"UPDATE users SET admin = true", "UPDATE without WHERE on every row of users"},
+		{"UPDATE users SET admin = true WHERE id = 5", ""},
+		{"update public.users u set a = (select 1 from t where x) ", "UPDATE without WHERE on every row of public.users"},
+		{"DELETE FROM users", "DELETE without WHERE on every row of users"},
+		{"DELETE FROM ONLY users WHERE id = 1", ""},
+		{"DELETE LOW_PRIORITY FROM `order items`", "DELETE without WHERE on every row of `order items`"},
+		{"DROP TABLE IF EXISTS users, public.orders CASCADE", "DROP TABLE users, public.orders"},
+		{"DROP MATERIALIZED VIEW stats", "DROP MATERIALIZED VIEW stats"},
+		{"drop database shop", "DROP DATABASE shop"},
+		{"DROP INDEX CONCURRENTLY users_email_idx", "DROP INDEX users_email_idx"},
+		{"TRUNCATE TABLE users, orders", "TRUNCATE users, orders"},
+		{"ALTER TABLE users ADD COLUMN age int", "ALTER TABLE users"},
+		{"SHUTDOWN", "SHUTDOWN"},
+		{"INSERT INTO users VALUES (1)", ""},
+		{"CREATE TABLE t (id int)", ""},
+		{"-- clean up\n/* old rows */ DELETE FROM users", "DELETE without WHERE on every row of users"},
+		{"SELECT 'DROP TABLE users'", ""},
+		{"WITH gone AS (DELETE FROM sessions RETURNING id) SELECT count(*) FROM gone",
+			"DELETE without WHERE on every row of sessions (inside another statement)"},
+		{"WITH ids AS (SELECT id FROM old) DELETE FROM users WHERE id IN (SELECT id FROM ids)", ""},
+		{"WITH RECURSIVE a(x) AS (SELECT 1), b AS MATERIALIZED (UPDATE t SET x = 1) DELETE FROM u",
+			"DELETE without WHERE on every row of u; UPDATE without WHERE on every row of t (inside another statement)"},
+		{"EXPLAIN DELETE FROM users", ""},
+		{"EXPLAIN ANALYZE DELETE FROM users", "DELETE without WHERE on every row of users (inside another statement)"},
+		{"EXPLAIN (ANALYZE false) DELETE FROM users", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := warningText(tt.stmt); got != tt.want {
+			t.Errorf("Warnings(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestWarnings_Keywords(t *testing.T) {
+	if w := Warnings("DROP TABLE users", []string{"delete"}); len(w) != 0 {
+		t.Errorf("only the configured keywords should warn, got %v", w)
+	}
+	if w := Warnings("INSERT INTO users VALUES (1)", []string{"INSERT"}); len(w) != 1 || w[0].String() != "INSERT users" {
+		t.Errorf("keywords should match in any case, got %v", w)
+	}
+	if w := Warnings("DELETE FROM users", nil); len(w) != 0 {
+		t.Errorf("no keywords should mean no warnings, got %v", w)
+	}
+}
+
+func TestIsWrite(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{"SELECT * FROM users", false},
+		{"(SELECT 1) UNION (SELECT 2)", false},
+		{"SHOW TABLES", false},
+		{"EXPLAIN DELETE FROM users", false},
+		{"EXPLAIN ANALYZE DELETE FROM users", true},
+		{"EXPLAIN (ANALYZE, BUFFERS) INSERT INTO t VALUES (1)", true},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", false},
+		{"WITH gone AS (DELETE FROM users RETURNING *) SELECT count(*) FROM gone", true},
+		{"WITH t AS (SELECT 'delete' AS verb) SELECT * FROM t", false},
+		{"insert into users values (1)", true},
+		{"UPDATE users SET name = 'x' WHERE id = 1", true},
+		{"CREATE TABLE t (id int)", true},
+		{"GRANT SELECT ON t TO bob", true},
+		{"SELECT * INTO backup FROM users", true},
+		{"SELECT (SELECT 1 INTO x)", false},
+		{"COPY users TO STDOUT", false},
+		{"COPY users FROM '/tmp/users.csv'", true},
+		{"-- note\nTRUNCATE users", true},
+		{"SET search_path TO app", false},
+		{"BEGIN", false},
+		{"BEGIN READ ONLY", false},
+		{"SET default_transaction_read_only = on", false},
+		{"SET default_transaction_read_only = off", true},
+		{"set session default_transaction_read_only to 'false'", true},
+		{"SET default_transaction_read_only TO DEFAULT", true},
+		{"SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE", true},
+		{"SET TRANSACTION ISOLATION LEVEL SERIALIZABLE, READ WRITE", true},
+		{"SET SESSION TRANSACTION READ WRITE", true},
+		{"SET @@SESSION.transaction_read_only = 0", true},
+		{"SET autocommit = 0, tx_read_only = OFF", true},
+		{"BEGIN READ WRITE", true},
+		{"START TRANSACTION READ WRITE", true},
+		{"START TRANSACTION ISOLATION LEVEL REPEATABLE READ", false},
+		{"SET GLOBAL read_only = 0", true},
+		{"SET @@global.super_read_only = OFF", true},
+		{"SET PERSIST max_connections = 500", true},
+		{"SELECT set_config('default_transaction_read_only', 'off', false)", true},
+		{"SELECT pg_catalog.set_config('transaction_read_only', 'off', true)", true},
+		{"SELECT set_config(name, 'off', false) FROM settings", true},
+		{"SELECT set_config('application_name', 'report', false)", false},
+		{"SELECT 1; DELETE FROM t", true},
+		{"SELECT 1; SELECT 2", false},
+		{"SELECT 'a;b'; UPDATE t SET x = 1", true},
+		{"SELECT $$;$$; DROP TABLE t", true},
+		{"SELECT 'it\\'s; DROP TABLE t'", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsWrite(tt.stmt); got != tt.want {
+			t.Errorf("IsWrite(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestActions(t *testing.T) {
+	actions := Actions("CREATE OR REPLACE VIEW IF NOT EXISTS app.active AS SELECT 1")
+	if len(actions) != 1 {
+		t.Fatalf("got %+v", actions)
+	}
+	a := actions[0]
+	if a.Verb != "create" || a.Object != "view" || len(a.Targets) != 1 || a.Targets[0] != "app.active" || !a.Writes {
+		t.Errorf("unexpected action %+v", a)
+	}
+}
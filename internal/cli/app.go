@@ -2,22 +2,27 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
 
+	"github.com/tomblomfield/gocli/internal/classify"
 	"github.com/tomblomfield/gocli/internal/completion"
 	"github.com/tomblomfield/gocli/internal/config"
+	"github.com/tomblomfield/gocli/internal/dialect"
 	"github.com/tomblomfield/gocli/internal/format"
 	"github.com/tomblomfield/gocli/internal/highlight"
 	"github.com/tomblomfield/gocli/internal/special"
+	"github.com/tomblomfield/gocli/internal/statement"
 )
 
 // DBMode specifies the database type.
@@ -29,6 +34,10 @@ const (
 )
 
 // Executor is the interface that database executors must implement.
+// Execute runs every statement on the same connection, so USE, SET,
+// temporary tables and transactions carry over between statements; the
+// metadata queries of MetadataProvider, which run in the background while
+// the user works, must use other connections.
 type Executor interface {
 	Execute(ctx context.Context, query string) (*format.QueryResult, error)
 	Close() error
@@ -46,6 +55,36 @@ type MetadataProvider interface {
 	Datatypes(ctx context.Context) []string
 }
 
+// ForeignKeyProvider is implemented by metadata providers that can list
+// foreign keys for JOIN completion.
+type ForeignKeyProvider interface {
+	ForeignKeys(ctx context.Context) ([]completion.ForeignKey, error)
+}
+
+// SearchPathProvider is implemented by metadata providers that know which
+// schemas unqualified names resolve against.
+type SearchPathProvider interface {
+	SearchPath(ctx context.Context) ([]string, error)
+}
+
+// RelationsProvider is implemented by metadata providers that can list
+// every table and view with its columns in one catalog query.
+type RelationsProvider interface {
+	Relations(ctx context.Context) ([]completion.Relation, error)
+}
+
+// FunctionSignatureProvider is implemented by metadata providers that can
+// list function arguments and result types for completion hints.
+type FunctionSignatureProvider interface {
+	FunctionSignatures(ctx context.Context) ([]completion.Function, error)
+}
+
+// UniqueKeyProvider is implemented by metadata providers that can list
+// primary keys and unique indexes for ON CONFLICT completion.
+type UniqueKeyProvider interface {
+	UniqueKeys(ctx context.Context) ([]completion.UniqueKey, error)
+}
+
 // App is the main CLI application.
 type App struct {
 	mode     DBMode
@@ -55,12 +94,28 @@ type App struct {
 	special  *special.Registry
 	comp     *completion.Completer
 	compMeta *completion.Metadata
+	dialect  *dialect.Dialect // keywords and functions for this server
+
+	// Completion refresh
+	refreshMu sync.Mutex     // serializes metadata refreshes
+	refreshWG sync.WaitGroup // background refreshes in flight
+	cacheKey  string         // connection identity for the completion cache
+
+	usage     *completion.Usage // names used in executed statements
+	usagePath string            // where usage is saved; "" keeps it in memory
 
 	// State
 	multiLineBuffer strings.Builder
 	inMultiLine     bool
 	lastQuery       string
-	outputFile      *os.File
+	output          io.WriteCloser // \o file or pipe for all results
+	tee             io.WriteCloser // tee file that also receives results
+	redirect        io.WriteCloser // \g file or pipe for the current query
+	audit           *AuditLog      // records every statement run
+	connector       Connector
+	inTransaction   bool          // an explicit transaction is open
+	confirmInput    func() string // reads answers at the interactive prompt
+	summaryOut      io.Writer     // JSON summary of each -e run
 
 	// I/O (can be overridden for testing)
 	Stdin  io.Reader
@@ -72,7 +127,13 @@ type App struct {
 func NewApp(mode DBMode, executor Executor, meta MetadataProvider, cfg *config.Config) *App {
 	reg := special.NewRegistry()
 	reg.Timing = cfg.Timing
+	reg.Expanded = cfg.ExpandedOutput
+	reg.ExpandedAuto = cfg.AutoExpand && !cfg.ExpandedOutput
+	reg.Timeout = cfg.StatementTimeout
+	reg.LockTimeout = cfg.LockTimeout
 	reg.Pager = cfg.Pager
+	reg.TableFormat = cfg.TableFormat
+	reg.Display = displayOptions(cfg)
 
 	switch mode {
 	case PostgreSQL:
@@ -83,6 +144,8 @@ func NewApp(mode DBMode, executor Executor, meta MetadataProvider, cfg *config.C
 
 	compMeta := completion.NewMetadata()
 	comp := completion.NewCompleter(compMeta, cfg.SmartCompletion)
+	usage := completion.NewUsage()
+	comp.SetUsage(usage)
 	comp.SetKeywordCasing(cfg.KeywordCasing)
 
 	app := &App{
@@ -93,11 +156,15 @@ func NewApp(mode DBMode, executor Executor, meta MetadataProvider, cfg *config.C
 		special:  reg,
 		comp:     comp,
 		compMeta: compMeta,
+		usage:    usage,
 		Stdin:    os.Stdin,
 		Stdout:   os.Stdout,
 		Stderr:   os.Stderr,
 	}
 
+	app.installHooks()
+	app.detectDialect()
+
 	// Load favorites into special registry
 	for name, query := range cfg.NamedQueries {
 		reg.Favorites[name] = query
@@ -106,49 +173,38 @@ func NewApp(mode DBMode, executor Executor, meta MetadataProvider, cfg *config.C
 	return app
 }
 
-// RefreshCompletions reloads schema metadata for auto-completion.
-func (a *App) RefreshCompletions() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	meta := completion.NewMetadata()
-
-	if tables, err := a.meta.Tables(ctx, ""); err == nil {
-		meta.Tables = tables
-	}
-	if schemas, err := a.meta.Schemas(ctx); err == nil {
-		meta.Schemas = schemas
-	}
-	if funcs, err := a.meta.Functions(ctx, ""); err == nil {
-		meta.Functions = funcs
-	}
-	if dbs, err := a.meta.Databases(ctx); err == nil {
-		meta.Databases = dbs
-	}
-	meta.Datatypes = a.meta.Datatypes(ctx)
-
-	// Load columns for each table
-	for _, table := range meta.Tables {
-		if cols, err := a.meta.Columns(ctx, table); err == nil {
-			meta.Columns[table] = cols
-		}
-	}
-
-	// Add special commands
-	for _, cmd := range a.special.Commands() {
-		meta.Specials = append(meta.Specials, completion.SpecialCmd{
-			Name:        cmd.Name,
-			Description: cmd.Description,
-		})
+// detectDialect picks the keywords, functions and datatypes offered for
+// completion and highlighting from the backend and its server version.
+func (a *App) detectDialect() {
+	name := dialect.PostgreSQL
+	if a.mode == MySQL {
+		name = dialect.MySQL
 	}
-
-	// Add favorites
-	for name := range a.special.Favorites {
-		meta.Favorites = append(meta.Favorites, name)
+	var version string
+	if a.executor != nil {
+		version, _ = a.executor.ServerVersion()
 	}
+	a.dialect = dialect.New(name, version)
+	a.comp.SetDialect(a.dialect)
+}
 
-	a.compMeta = meta
-	a.comp.UpdateMetadata(meta)
+// displayOptions builds the NULL, number and date settings from the config.
+func displayOptions(cfg *config.Config) format.Options {
+	opts := format.DefaultOptions()
+	opts.NullValue = cfg.NullString
+	opts.NullValues[format.CSVFormat] = cfg.NullStringCSV
+	opts.NullValues[format.TSVFormat] = cfg.NullStringCSV
+	opts.NullValues[format.JSONFormat] = cfg.NullStringJSON
+	if cfg.FloatPrecision >= 0 {
+		opts.FloatFmt = fmt.Sprintf("%%.%df", cfg.FloatPrecision)
+	}
+	opts.ThousandsSep = cfg.ThousandsSeparator
+	opts.DecimalMark = cfg.DecimalMark
+	opts.DateFormat = cfg.DateFormat
+	opts.TimestampFormat = cfg.TimestampFormat
+	opts.TimeZone = cfg.TimeZone
+	opts.Theme = format.ThemeFromColors(cfg.Colors)
+	return opts
 }
 
 // Complete returns completions for the given text at cursor position.
@@ -175,16 +231,35 @@ func (a *App) HandleInput(input string) (shouldQuit bool) {
 	return a.executeInput(strings.TrimSpace(input), forceVertical)
 }
 
+// handleMultiLine buffers lines until they end a statement. A delimiter
+// only counts outside strings, comments and dollar quotes; backslash
+// commands and \g end the buffer too unless one of those is still open.
 func (a *App) handleMultiLine(input string) bool {
-	// In psql mode: execute when semicolon is at end
 	trimmed := strings.TrimSpace(input)
+	syntax := a.syntax()
+
+	// In safe mode Enter only adds a line; SubmitInput runs the buffer
+	if a.config.MultiLineMode == "safe" {
+		if a.inMultiLine {
+			a.multiLineBuffer.WriteString("\n")
+			a.multiLineBuffer.WriteString(input)
+		} else if trimmed != "" {
+			a.multiLineBuffer.WriteString(input)
+			a.inMultiLine = true
+		}
+		return false
+	}
 
 	if a.inMultiLine {
 		a.multiLineBuffer.WriteString("\n")
 		a.multiLineBuffer.WriteString(input)
+		query := a.multiLineBuffer.String()
+		if statement.Open(query, syntax) {
+			return false
+		}
 
-		if strings.HasSuffix(trimmed, ";") || strings.HasPrefix(trimmed, `\`) {
-			query := a.multiLineBuffer.String()
+		_, goCmd := a.splitQueryCommand(trimmed)
+		if statement.Complete(query, syntax) || strings.HasPrefix(trimmed, `\`) || goCmd != "" {
 			a.multiLineBuffer.Reset()
 			a.inMultiLine = false
 			return a.executeInput(strings.TrimSpace(query), false)
@@ -193,7 +268,10 @@ func (a *App) handleMultiLine(input string) bool {
 	}
 
 	// Check if this starts a multi-line query
-	if trimmed != "" && !strings.HasSuffix(trimmed, ";") && !strings.HasPrefix(trimmed, `\`) {
+	if _, goCmd := a.splitQueryCommand(trimmed); goCmd != "" {
+		return a.executeInput(trimmed, false)
+	}
+	if trimmed != "" && !statement.Complete(trimmed, syntax) && !strings.HasPrefix(trimmed, `\`) && !a.special.IsSpecial(trimmed) {
 		a.multiLineBuffer.WriteString(input)
 		a.inMultiLine = true
 		return false
@@ -202,11 +280,31 @@ func (a *App) handleMultiLine(input string) bool {
 	return a.executeInput(trimmed, false)
 }
 
+// SubmitInput runs the query buffer ended by input as it stands, whether
+// or not it is a complete statement. It is what Alt-Enter does.
+func (a *App) SubmitInput(input string) (shouldQuit bool) {
+	input = strings.TrimRight(input, "\n\r")
+	if a.inMultiLine {
+		a.multiLineBuffer.WriteString("\n")
+		a.multiLineBuffer.WriteString(input)
+		input = a.multiLineBuffer.String()
+		a.multiLineBuffer.Reset()
+		a.inMultiLine = false
+	}
+	return a.executeInput(strings.TrimSpace(input), false)
+}
+
 func (a *App) executeInput(input string, forceVertical bool) bool {
 	if input == "" {
 		return false
 	}
 
+	// A trailing \g or \gx ends the query buffer
+	query, goCmd := a.splitQueryCommand(input)
+	if goCmd != "" {
+		input = goCmd
+	}
+
 	// Check for special commands
 	if a.special.IsSpecial(input) {
 		results, err := a.special.Execute(context.Background(), a.executor, input)
@@ -214,54 +312,265 @@ func (a *App) executeInput(input string, forceVertical bool) bool {
 			if err == special.ErrQuit {
 				return true
 			}
-			fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+			a.reportError(err)
+			return false
+		}
+		if out := a.special.TakeQueryOutput(); out != nil {
+			a.executeToOutput(query, out)
 			return false
 		}
 		a.displayResults(results, forceVertical)
 		return false
 	}
 
-	// Check destructive warning
-	if a.config.IsDestructive(input) {
-		fmt.Fprintf(a.Stdout, "You're about to run a destructive command.\nDo you want to proceed? (y/n): ")
-		// In a real implementation, we'd read confirmation here
+	a.executeSQL(input, forceVertical)
+	return false
+}
+
+// executeSQL runs one or more SQL statements and displays their results.
+// It returns true if a statement failed.
+func (a *App) executeSQL(input string, forceVertical bool) bool {
+	// Split on semicolons for multi-statement
+	queries := statement.Split(input, a.syntax())
+	if !a.confirmDestructive(queries) {
+		return false
 	}
 
 	// Execute SQL query
 	a.lastQuery = input
-	start := time.Now()
+	summary := newRunSummary()
 	ctx := context.Background()
 
-	// Split on semicolons for multi-statement
-	queries := SplitStatements(input)
+	hasError := false
+	refresh := false
 	for _, query := range queries {
 		query = strings.TrimSpace(query)
 		if query == "" {
 			continue
 		}
 
-		result, err := a.executor.Execute(ctx, query)
+		result, err := a.run(ctx, query)
+		summary.add(result, err)
 		if err != nil {
-			fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+			a.reportError(err)
+			hasError = true
 			if a.config.OnError == "STOP" {
 				break
 			}
 			continue
 		}
-		if result != nil {
-			a.displayResults([]*format.QueryResult{result}, forceVertical)
+		a.comp.RecordUsage(query)
+		if changesSchema(query) {
+			refresh = true
 		}
+		a.showResult(result, forceVertical, a.special.Timing)
+	}
+	if refresh {
+		a.refreshInBackground()
 	}
 
-	if a.special.Timing {
-		elapsed := time.Since(start)
-		fmt.Fprintln(a.Stdout, special.FormatTiming(elapsed))
+	if summary.Statements > 1 {
+		fmt.Fprintln(a.Stdout, summary)
 	}
+	return hasError
+}
+
+// showResult displays the result of one statement, followed by how long
+// it took when timing is set.
+func (a *App) showResult(result *format.QueryResult, forceVertical, timing bool) {
+	if result == nil {
+		return
+	}
+	if len(result.Columns) > 0 {
+		a.special.LastResult = result
+	}
+	render := a.displayResults([]*format.QueryResult{result}, forceVertical)
+	if timing {
+		fmt.Fprintln(a.Stdout, statementTiming(result, render))
+	}
+}
 
+// confirmDestructive lists what queries would do that destructive_keywords
+// flags, naming the objects affected, and asks whether to go on. A query
+// holding several statements, as a favorite may, is checked in full.
+// Without an interactive prompt to answer at, as for piped input, it goes
+// on.
+func (a *App) confirmDestructive(queries []string) bool {
+	if a.confirmInput == nil {
+		return true
+	}
+	var warnings []string
+	for _, query := range queries {
+		for _, stmt := range statement.Split(query, a.syntax()) {
+			for _, w := range a.config.DestructiveWarnings(stmt) {
+				warnings = append(warnings, w.String())
+			}
+		}
+	}
+	if len(warnings) == 0 {
+		return true
+	}
+	fmt.Fprintln(a.Stdout, "You're about to run a destructive command:")
+	for _, w := range warnings {
+		fmt.Fprintf(a.Stdout, "  %s\n", w)
+	}
+	fmt.Fprint(a.Stdout, "Do you want to proceed? (y/n): ")
+	switch strings.ToLower(strings.TrimSpace(a.confirmInput())) {
+	case "y", "yes":
+		return true
+	}
+	fmt.Fprintln(a.Stdout, "Wise choice!")
 	return false
 }
 
-func (a *App) displayResults(results []*format.QueryResult, forceVertical bool) {
+// errNotRun reports a destructive statement the user chose not to run.
+var errNotRun = errors.New("not run")
+
+// runConfirmed runs a query on the user's behalf, as a favorite does,
+// after asking as for typed statements if it is destructive.
+func (a *App) runConfirmed(ctx context.Context, query string) (*format.QueryResult, error) {
+	if !a.confirmDestructive([]string{query}) {
+		return nil, errNotRun
+	}
+	return a.run(ctx, query)
+}
+
+// errReadOnly refuses a write in read-only mode.
+var errReadOnly = errors.New("read-only mode: this statement could change the database and was not run")
+
+// run executes one statement, recording it in the audit log. In read-only
+// mode writes are refused before they reach the server.
+func (a *App) run(ctx context.Context, query string) (*format.QueryResult, error) {
+	start := time.Now()
+	var result *format.QueryResult
+	var err error
+	if a.config.ReadOnly && classify.IsWrite(query) {
+		err = errReadOnly
+	} else {
+		result, err = a.executeWithTimeout(ctx, query)
+		if err != nil {
+			result, err = a.reconnectAfter(ctx, query, err)
+		}
+		a.noteTransaction(query, err)
+		if result != nil && result.ServerTime == 0 {
+			// The executor did not time the server, so count all of it
+			result.ServerTime = time.Since(start) - result.FetchTime
+		}
+	}
+	if a.audit != nil {
+		a.recordAudit(query, start, result, err)
+	}
+	return result, err
+}
+
+func (a *App) recordAudit(query string, start time.Time, result *format.QueryResult, err error) {
+	entry := AuditEntry{
+		Time:      start,
+		Database:  a.executor.Database(),
+		Statement: query,
+		Duration:  time.Since(start),
+	}
+	if u, ok := a.executor.(UserProvider); ok {
+		entry.User = u.User()
+	}
+	if result != nil {
+		entry.Rows = result.RowCount
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := a.audit.Record(entry); err != nil {
+		fmt.Fprintf(a.Stderr, "Warning: could not write audit log: %s\n", err)
+	}
+}
+
+// SetSummaryOutput writes a JSON summary of the statements run by each
+// ExecuteNonInteractive call to w, for scripts. A nil w stops it.
+func (a *App) SetSummaryOutput(w io.Writer) {
+	a.summaryOut = w
+}
+
+// SetAuditLog records every statement run from now on in l. A nil l
+// stops recording.
+func (a *App) SetAuditLog(l *AuditLog) {
+	a.audit = l
+}
+
+// splitQueryCommand splits "SELECT ... \g file" into the query and the
+// \g command. cmd is empty when input has no trailing \g, \gx or (in
+// mycli) \G outside of quotes.
+func (a *App) splitQueryCommand(input string) (query, cmd string) {
+	var quote byte
+	for i := 0; i < len(input); i++ {
+		ch := input[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '\\':
+			// The first backslash command ends the query, as in psql
+			name := strings.Fields(input[i:])[0]
+			if (name == `\g` || name == `\gx` || name == `\G`) && a.special.IsSpecial(name) {
+				return strings.TrimSpace(input[:i]), input[i:]
+			}
+			return input, ""
+		}
+	}
+	return input, ""
+}
+
+// executeToOutput runs query for \g and \gx, sending the results to the
+// requested file or command with that call's \pset options. An empty
+// query re-runs the previous one, as in psql. It returns true on error.
+func (a *App) executeToOutput(query string, out *special.QueryOutput) bool {
+	if query == "" {
+		query = a.lastQuery
+	}
+	if query == "" {
+		fmt.Fprintln(a.Stderr, "Error: query buffer is empty")
+		return true
+	}
+
+	restore, err := a.special.ApplyQueryOutput(out)
+	if err != nil {
+		fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+		return true
+	}
+	defer restore()
+
+	switch {
+	case out.Command != "":
+		pw, err := a.startPipe(out.Command)
+		if err != nil {
+			fmt.Fprintf(a.Stderr, "Error: could not run %s: %s\n", out.Command, err)
+			return true
+		}
+		a.redirect = pw
+	case out.File != "":
+		f, err := os.Create(out.File)
+		if err != nil {
+			fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+			return true
+		}
+		a.redirect = f
+	}
+	if a.redirect != nil {
+		defer func() {
+			a.redirect.Close()
+			a.redirect = nil
+		}()
+	}
+
+	return a.executeSQL(query, false)
+}
+
+// displayResults writes results and returns how long formatting them
+// took, leaving out the time spent waiting on a pager.
+func (a *App) displayResults(results []*format.QueryResult, forceVertical bool) time.Duration {
+	var render time.Duration
 	for _, result := range results {
 		if result == nil {
 			continue
@@ -270,12 +579,12 @@ func (a *App) displayResults(results []*format.QueryResult, forceVertical bool)
 		// Determine output writer (pager or stdout)
 		writer := a.getOutputWriter(result)
 
+		opts := a.special.Display
 		if len(result.Columns) > 0 {
-			opts := format.DefaultOptions()
-			opts.NullValue = a.config.NullString
+			opts.Color = a.colorEnabled(writer)
 
 			// Determine format
-			switch a.config.TableFormat {
+			switch a.special.TableFormat {
 			case "ascii":
 				opts.Style = format.ASCIIStyle
 			case "psql":
@@ -292,46 +601,61 @@ func (a *App) displayResults(results []*format.QueryResult, forceVertical bool)
 				opts.Format = format.VerticalFormat
 			}
 
-			if forceVertical || a.special.Expanded || a.config.ExpandedOutput {
+			// Wrap expanded values to the terminal, but not in files
+			if a.output == nil && a.redirect == nil {
+				opts.MaxWidth = terminalWidth()
+			}
+
+			if forceVertical || a.special.Expanded {
 				opts.Expanded = true
 			}
 
-			// Auto-expand: switch to vertical if result is wider than terminal
-			if !opts.Expanded && a.config.AutoExpand && len(result.Columns) > 0 {
-				tableWidth := 1 // leading border
-				for _, col := range result.Columns {
-					w := len(col)
-					for _, row := range result.Rows {
-						for ci, cell := range row {
-							if ci < len(result.Columns) && len(cell) > w {
-								w = len(cell)
-							}
-						}
-					}
-					tableWidth += w + 3 // cell + padding + border
-				}
-				if termWidth := getTerminalWidth(); termWidth > 0 && tableWidth > termWidth {
-					opts.Expanded = true
-				}
+			// \x auto: switch to vertical if the table is wider than the terminal
+			isTable := opts.Format == format.TableFormat || opts.Format == format.AlignedFormat
+			if !opts.Expanded && a.special.ExpandedAuto && isTable && opts.MaxWidth > 0 &&
+				format.TableWidth(result, opts) > opts.MaxWidth {
+				opts.Expanded = true
 			}
 
-			format.Format(writer, result, opts)
 		}
-
-		if result.StatusText != "" {
-			fmt.Fprintln(writer, result.StatusText)
+		start := time.Now()
+		if a.isPager(writer) {
+			// Format before paging, as the pager blocks while it is read
+			var buf bytes.Buffer
+			a.writeResult(&buf, result, opts)
+			render += time.Since(start)
+			buf.WriteTo(writer)
+			writer.(*pagerWriter).Close()
+			start = time.Now()
+		} else {
+			a.writeResult(writer, result, opts)
 		}
-
-		// Close pager if we opened one
-		if closer, ok := writer.(io.Closer); ok && writer != a.Stdout {
-			closer.Close()
+		if a.tee != nil {
+			opts.Color = false
+			a.writeResult(a.tee, result, opts)
 		}
+		render += time.Since(start)
+	}
+	return render
+}
+
+// writeResult writes one result table and its status line.
+func (a *App) writeResult(w io.Writer, result *format.QueryResult, opts format.Options) {
+	if len(result.Columns) > 0 {
+		format.Format(w, result, opts)
+	}
+	// Tuples-only output drops the row count footer
+	if result.StatusText != "" && !(opts.TuplesOnly && len(result.Columns) > 0) {
+		fmt.Fprintln(w, result.StatusText)
 	}
 }
 
 func (a *App) getOutputWriter(result *format.QueryResult) io.Writer {
-	if a.outputFile != nil {
-		return a.outputFile
+	if a.redirect != nil {
+		return a.redirect
+	}
+	if a.output != nil {
+		return a.output
 	}
 
 	if !a.config.EnablePager || a.special.Pager == "" {
@@ -351,6 +675,33 @@ func (a *App) getOutputWriter(result *format.QueryResult) io.Writer {
 	return a.openPager()
 }
 
+// colorEnabled reports whether output written to w should contain ANSI
+// colors. --color=always and never win; otherwise color is used only for
+// terminals, and NO_COLOR (https://no-color.org) turns it off.
+func (a *App) colorEnabled(w io.Writer) bool {
+	switch a.config.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	// The pager writes to stdout and is started with -R by default
+	if a.isPager(w) {
+		w = a.Stdout
+	}
+	return isTerminal(w)
+}
+
+// isPager reports whether w is a pager opened for one result, as opposed
+// to a \g or \o pipe.
+func (a *App) isPager(w io.Writer) bool {
+	_, ok := w.(*pagerWriter)
+	return ok && w != a.redirect && w != a.output
+}
+
 func (a *App) openPager() io.Writer {
 	pagerCmd := a.special.Pager
 	if pagerCmd == "" {
@@ -377,6 +728,23 @@ func (a *App) openPager() io.Writer {
 	return &pagerWriter{pipe: pipe, cmd: cmd}
 }
 
+// startPipe starts a shell command that reads results on its stdin, for
+// \g |command and \o |command.
+func (a *App) startPipe(command string) (*pagerWriter, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = a.Stdout
+	cmd.Stderr = a.Stderr
+	pipe, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pagerWriter{pipe: pipe, cmd: cmd}, nil
+}
+
+// pagerWriter feeds a command's stdin: the pager, or a \g or \o |command.
 type pagerWriter struct {
 	pipe io.WriteCloser
 	cmd  *exec.Cmd
@@ -412,7 +780,25 @@ func (a *App) GetPrompt() string {
 		port = "3306"
 	}
 
-	return config.FormatPrompt(a.config.Prompt, user, host, database, port, false)
+	prompt := config.FormatPrompt(a.config.Prompt, user, host, database, port, false)
+	if a.special != nil {
+		if d := a.special.Delimiter; d != "" && d != ";" {
+			prompt = fmt.Sprintf("[delimiter %s] %s", d, prompt)
+		}
+	}
+	if a.config.ReadOnly {
+		prompt = "[read-only] " + prompt
+	}
+	return prompt
+}
+
+// LivePrompt returns the prompt for the next line of input, which is the
+// continuation prompt while a statement is being buffered.
+func (a *App) LivePrompt() string {
+	if a.inMultiLine {
+		return a.GetContinuationPrompt()
+	}
+	return a.GetPrompt()
 }
 
 // GetContinuationPrompt returns the prompt for multi-line continuation.
@@ -432,20 +818,39 @@ func (a *App) ExecuteNonInteractive(input string) bool {
 	if input == "" {
 		return false
 	}
+	summary := newRunSummary()
+	if a.summaryOut != nil {
+		defer func() { fmt.Fprintln(a.summaryOut, summary.JSON()) }()
+	}
 
 	// Special commands are not split on semicolons
-	if a.special.IsSpecial(input) {
+	query, goCmd := a.splitQueryCommand(input)
+	if goCmd != "" {
+		input = goCmd
+	}
+	// A multi-line script may start with a command such as delimiter
+	if a.special.IsSpecial(input) && (goCmd != "" || !strings.Contains(input, "\n")) {
 		results, err := a.special.Execute(context.Background(), a.executor, input)
+		summary.add(nil, err)
 		if err != nil {
-			fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+			a.reportError(err)
 			return true
 		}
+		if out := a.special.TakeQueryOutput(); out != nil {
+			return a.executeToOutput(query, out)
+		}
 		a.displayResults(results, false)
 		return false
 	}
 
+	return a.executeStatements(statement.Split(input, a.syntax()), summary, false)
+}
+
+// executeStatements runs statements and special commands one by one,
+// printing their results and, when timing is set, how long each took.
+// It counts them in summary and returns true if any of them failed.
+func (a *App) executeStatements(queries []string, summary *runSummary, timing bool) bool {
 	hasError := false
-	queries := SplitStatements(input)
 	for _, query := range queries {
 		query = strings.TrimSpace(query)
 		if query == "" {
@@ -455,8 +860,9 @@ func (a *App) ExecuteNonInteractive(input string) bool {
 		// Check if this individual statement is a special command
 		if a.special.IsSpecial(query) {
 			results, err := a.special.Execute(context.Background(), a.executor, query)
+			summary.add(nil, err)
 			if err != nil {
-				fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+				a.reportError(err)
 				hasError = true
 				continue
 			}
@@ -464,15 +870,14 @@ func (a *App) ExecuteNonInteractive(input string) bool {
 			continue
 		}
 
-		result, err := a.executor.Execute(context.Background(), query)
+		result, err := a.run(context.Background(), query)
+		summary.add(result, err)
 		if err != nil {
-			fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+			a.reportError(err)
 			hasError = true
 			continue
 		}
-		if result != nil {
-			a.displayResults([]*format.QueryResult{result}, false)
-		}
+		a.showResult(result, false, timing)
 	}
 	return hasError
 }
@@ -480,131 +885,31 @@ func (a *App) ExecuteNonInteractive(input string) bool {
 // HighlightInput applies syntax highlighting to input text.
 func (a *App) HighlightInput(input string) string {
 	style := highlight.GetStyle(a.config.SyntaxStyle)
-	return highlight.Highlight(input, style)
+	return highlight.HighlightDialect(input, style, a.dialect)
 }
 
-// Run starts the interactive REPL loop.
-func (a *App) Run() error {
-	// Setup signal handling
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// Print welcome message
-	if !a.config.LessChatty {
-		version, _ := a.executor.ServerVersion()
-		fmt.Fprintf(a.Stdout, "gocli %s\n", a.mode)
-		if version != "" {
-			fmt.Fprintf(a.Stdout, "Server: %s\n", version)
-		}
-		fmt.Fprintln(a.Stdout, "Type \\? for help.")
-		fmt.Fprintln(a.Stdout)
+// syntax returns the lexical rules statements are split by, including
+// any delimiter set with MySQL's delimiter command.
+func (a *App) syntax() statement.Syntax {
+	if a.mode == MySQL {
+		return statement.MySQL.WithDelimiter(a.special.Delimiter)
 	}
-
-	// Initial completion refresh
-	go a.RefreshCompletions()
-
-	// Main REPL loop - uses readline when available
-	// For now, use simple line reading (readline integration in cmd/)
-	return nil
+	return statement.PostgreSQL
 }
 
-// SplitStatements splits SQL input on semicolons, respecting strings and comments.
-func SplitStatements(input string) []string {
-	var statements []string
-	var current strings.Builder
-	inSingleQuote := false
-	inDoubleQuote := false
-	inLineComment := false
-	inBlockComment := false
-	i := 0
-
-	for i < len(input) {
-		ch := input[i]
-
-		if inLineComment {
-			current.WriteByte(ch)
-			if ch == '\n' {
-				inLineComment = false
-			}
-			i++
-			continue
-		}
-
-		if inBlockComment {
-			current.WriteByte(ch)
-			if ch == '*' && i+1 < len(input) && input[i+1] == '/' {
-				current.WriteByte('/')
-				inBlockComment = false
-				i += 2
-				continue
-			}
-			i++
-			continue
-		}
-
-		if inSingleQuote {
-			current.WriteByte(ch)
-			if ch == '\'' {
-				if i+1 < len(input) && input[i+1] == '\'' {
-					current.WriteByte('\'')
-					i += 2
-					continue
-				}
-				inSingleQuote = false
-			}
-			i++
-			continue
-		}
-
-		if inDoubleQuote {
-			current.WriteByte(ch)
-			if ch == '"' {
-				inDoubleQuote = false
-			}
-			i++
-			continue
-		}
-
-		switch {
-		case ch == '-' && i+1 < len(input) && input[i+1] == '-':
-			current.WriteByte(ch)
-			inLineComment = true
-			i++
-		case ch == '/' && i+1 < len(input) && input[i+1] == '*':
-			current.WriteByte(ch)
-			current.WriteByte('*')
-			inBlockComment = true
-			i += 2
-		case ch == '\'':
-			current.WriteByte(ch)
-			inSingleQuote = true
-			i++
-		case ch == '"':
-			current.WriteByte(ch)
-			inDoubleQuote = true
-			i++
-		case ch == ';':
-			stmt := strings.TrimSpace(current.String())
-			if stmt != "" {
-				statements = append(statements, stmt)
-			}
-			current.Reset()
-			i++
-		default:
-			current.WriteByte(ch)
-			i++
-		}
-	}
-
-	// Don't forget the last statement (without trailing semicolon)
-	stmt := strings.TrimSpace(current.String())
-	if stmt != "" {
-		statements = append(statements, stmt)
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
 	}
-
-	return statements
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
 
+// terminalWidth is a variable so tests can simulate a terminal.
+var terminalWidth = getTerminalWidth
+
 type winsize struct {
 	Row    uint16
 	Col    uint16
@@ -3,15 +3,22 @@ package cli
 import (
 	"bytes"
 	"context"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/tomblomfield/gocli/internal/completion"
 	"github.com/tomblomfield/gocli/internal/config"
 	"github.com/tomblomfield/gocli/internal/format"
+	"github.com/tomblomfield/gocli/internal/highlight"
+	"github.com/tomblomfield/gocli/internal/statement"
 )
 
 func TestSplitStatements_Single(t *testing.T) {
-	stmts := SplitStatements("SELECT * FROM users")
+	stmts := statement.Split("SELECT * FROM users", statement.PostgreSQL)
 	if len(stmts) != 1 {
 		t.Errorf("expected 1 statement, got %d: %v", len(stmts), stmts)
 	}
@@ -21,70 +28,70 @@ func TestSplitStatements_Single(t *testing.T) {
 }
 
 func TestSplitStatements_Multiple(t *testing.T) {
-	stmts := SplitStatements("SELECT 1; SELECT 2; SELECT 3")
+	stmts := statement.Split("SELECT 1; SELECT 2; SELECT 3", statement.PostgreSQL)
 	if len(stmts) != 3 {
 		t.Errorf("expected 3 statements, got %d: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_WithTrailingSemicolon(t *testing.T) {
-	stmts := SplitStatements("SELECT 1;")
+	stmts := statement.Split("SELECT 1;", statement.PostgreSQL)
 	if len(stmts) != 1 {
 		t.Errorf("expected 1 statement, got %d: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_StringLiteral(t *testing.T) {
-	stmts := SplitStatements("SELECT 'hello; world'")
+	stmts := statement.Split("SELECT 'hello; world'", statement.PostgreSQL)
 	if len(stmts) != 1 {
 		t.Errorf("semicolon in string should not split: got %d statements: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_DoubleQuoted(t *testing.T) {
-	stmts := SplitStatements(`SELECT "col;name" FROM t`)
+	stmts := statement.Split(`SELECT "col;name" FROM t`, statement.PostgreSQL)
 	if len(stmts) != 1 {
 		t.Errorf("semicolon in double-quoted identifier should not split: got %d", len(stmts))
 	}
 }
 
 func TestSplitStatements_SingleLineComment(t *testing.T) {
-	stmts := SplitStatements("SELECT 1; -- comment with ; in it\nSELECT 2")
+	stmts := statement.Split("SELECT 1; -- comment with ; in it\nSELECT 2", statement.PostgreSQL)
 	if len(stmts) != 2 {
 		t.Errorf("expected 2 statements, got %d: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_BlockComment(t *testing.T) {
-	stmts := SplitStatements("SELECT /* comment; here */ 1; SELECT 2")
+	stmts := statement.Split("SELECT /* comment; here */ 1; SELECT 2", statement.PostgreSQL)
 	if len(stmts) != 2 {
 		t.Errorf("expected 2 statements, got %d: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_Empty(t *testing.T) {
-	stmts := SplitStatements("")
+	stmts := statement.Split("", statement.PostgreSQL)
 	if len(stmts) != 0 {
 		t.Errorf("expected 0 statements, got %d", len(stmts))
 	}
 }
 
 func TestSplitStatements_OnlySemicolons(t *testing.T) {
-	stmts := SplitStatements(";;;")
+	stmts := statement.Split(";;;", statement.PostgreSQL)
 	if len(stmts) != 0 {
 		t.Errorf("expected 0 statements from only semicolons, got %d: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_EscapedQuotes(t *testing.T) {
-	stmts := SplitStatements("SELECT 'it''s a test; really'; SELECT 2")
+	stmts := statement.Split("SELECT 'it''s a test; really'; SELECT 2", statement.PostgreSQL)
 	if len(stmts) != 2 {
 		t.Errorf("expected 2 statements, got %d: %v", len(stmts), stmts)
 	}
 }
 
 func TestSplitStatements_Whitespace(t *testing.T) {
-	stmts := SplitStatements("  SELECT 1  ;  SELECT 2  ")
+	stmts := statement.Split("  SELECT 1  ;  SELECT 2  ", statement.PostgreSQL)
 	if len(stmts) != 2 {
 		t.Errorf("expected 2 statements, got %d", len(stmts))
 	}
@@ -102,14 +109,14 @@ SELECT *
 FROM orders
 WHERE total > 100`
 
-	stmts := SplitStatements(query)
+	stmts := statement.Split(query, statement.PostgreSQL)
 	if len(stmts) != 2 {
 		t.Errorf("expected 2 statements, got %d", len(stmts))
 	}
 }
 
 func TestSplitStatements_NestedComments(t *testing.T) {
-	stmts := SplitStatements("SELECT /* outer /* inner */ */ 1; SELECT 2")
+	stmts := statement.Split("SELECT /* outer /* inner */ */ 1; SELECT 2", statement.PostgreSQL)
 	if len(stmts) < 1 {
 		t.Error("should produce at least 1 statement")
 	}
@@ -118,7 +125,7 @@ func TestSplitStatements_NestedComments(t *testing.T) {
 func TestSplitStatements_ExecuteMode(t *testing.T) {
 	// Simulates how -e flag input should be split before execution
 	input := "SELECT 1 AS a; SELECT 2 AS b; SELECT 3 AS c"
-	stmts := SplitStatements(input)
+	stmts := statement.Split(input, statement.PostgreSQL)
 	if len(stmts) != 3 {
 		t.Fatalf("expected 3 statements, got %d: %v", len(stmts), stmts)
 	}
@@ -136,7 +143,7 @@ func TestSplitStatements_ExecuteMode(t *testing.T) {
 func TestSplitStatements_ExecuteModeMixed(t *testing.T) {
 	// Mix of DML and SELECT, as might be passed via -e
 	input := "CREATE TABLE t (id int); INSERT INTO t VALUES (1); SELECT * FROM t"
-	stmts := SplitStatements(input)
+	stmts := statement.Split(input, statement.PostgreSQL)
 	if len(stmts) != 3 {
 		t.Fatalf("expected 3 statements, got %d: %v", len(stmts), stmts)
 	}
@@ -217,15 +224,15 @@ func (m *mockExecutor) Execute(_ context.Context, query string) (*format.QueryRe
 	}, nil
 }
 
-func (m *mockExecutor) Close() error              { return nil }
-func (m *mockExecutor) Database() string           { return m.database }
-func (m *mockExecutor) ServerVersion() (string, error) { return m.version, nil }
-func (m *mockExecutor) Tables(_ context.Context, _ string) ([]string, error) { return nil, nil }
-func (m *mockExecutor) Columns(_ context.Context, _ string) ([]string, error) { return nil, nil }
-func (m *mockExecutor) Schemas(_ context.Context) ([]string, error) { return nil, nil }
+func (m *mockExecutor) Close() error                                            { return nil }
+func (m *mockExecutor) Database() string                                        { return m.database }
+func (m *mockExecutor) ServerVersion() (string, error)                          { return m.version, nil }
+func (m *mockExecutor) Tables(_ context.Context, _ string) ([]string, error)    { return nil, nil }
+func (m *mockExecutor) Columns(_ context.Context, _ string) ([]string, error)   { return nil, nil }
+func (m *mockExecutor) Schemas(_ context.Context) ([]string, error)             { return nil, nil }
 func (m *mockExecutor) Functions(_ context.Context, _ string) ([]string, error) { return nil, nil }
-func (m *mockExecutor) Databases(_ context.Context) ([]string, error) { return nil, nil }
-func (m *mockExecutor) Datatypes(_ context.Context) []string { return nil }
+func (m *mockExecutor) Databases(_ context.Context) ([]string, error)           { return nil, nil }
+func (m *mockExecutor) Datatypes(_ context.Context) []string                    { return nil }
 
 func newTestApp(mode DBMode) (*App, *bytes.Buffer) {
 	cfg := config.DefaultPGConfig()
@@ -241,6 +248,30 @@ func newTestApp(mode DBMode) (*App, *bytes.Buffer) {
 	return app, &buf
 }
 
+func TestNewApp_ExpandedFromConfig(t *testing.T) {
+	cfg := config.DefaultPGConfig()
+	cfg.AutoExpand = true
+	mock := &mockExecutor{database: "testdb"}
+	app := NewApp(PostgreSQL, mock, mock, cfg)
+	app.Stdout, app.Stderr = io.Discard, io.Discard
+	if !app.special.ExpandedAuto || app.special.Expanded {
+		t.Fatalf("auto_expand should start \\x auto, got %v, %v", app.special.Expanded, app.special.ExpandedAuto)
+	}
+	app.HandleInput(`\x off`)
+	if app.special.ExpandedAuto || app.special.Expanded {
+		t.Errorf("\\x off should turn auto_expand off, got %v, %v", app.special.Expanded, app.special.ExpandedAuto)
+	}
+
+	cfg = config.DefaultPGConfig()
+	cfg.ExpandedOutput = true
+	app = NewApp(PostgreSQL, mock, mock, cfg)
+	app.Stdout, app.Stderr = io.Discard, io.Discard
+	app.HandleInput(`\x off`)
+	if app.special.Expanded {
+		t.Error("\\x off should turn expanded_output off")
+	}
+}
+
 func TestNewApp_RegistersPGCommands(t *testing.T) {
 	app, _ := newTestApp(PostgreSQL)
 	// PG-specific commands should be registered
@@ -252,6 +283,42 @@ func TestNewApp_RegistersPGCommands(t *testing.T) {
 	}
 }
 
+func TestNewApp_DialectFromMode(t *testing.T) {
+	hasText := func(suggestions []completion.Suggestion, text string) bool {
+		for _, s := range suggestions {
+			if s.Text == text {
+				return true
+			}
+		}
+		return false
+	}
+
+	pgApp, _ := newTestApp(PostgreSQL)
+	if !hasText(pgApp.Complete("SELECT * FROM t WHERE a IL", 26), "ILIKE") {
+		t.Error("PostgreSQL sessions should be offered ILIKE")
+	}
+	if !hasText(pgApp.Complete("MERG", 4), "MERGE") {
+		t.Error("PostgreSQL 15 sessions should be offered MERGE")
+	}
+	if !strings.Contains(pgApp.HighlightInput("a ILIKE b"), highlight.GetStyle(pgApp.config.SyntaxStyle).Keyword+"ILIKE") {
+		t.Error("ILIKE should be highlighted as a PostgreSQL keyword")
+	}
+
+	myApp, _ := newTestApp(MySQL)
+	if hasText(myApp.Complete("SELECT * FROM t WHERE a IL", 26), "ILIKE") {
+		t.Error("MySQL sessions should not be offered ILIKE")
+	}
+	if strings.Contains(myApp.HighlightInput("a ILIKE b"), "ILIKE"+highlight.Reset) {
+		t.Error("ILIKE should not be highlighted in MySQL sessions")
+	}
+
+	old := &mockExecutor{database: "testdb", version: "14.2"}
+	oldApp := NewApp(PostgreSQL, old, old, config.DefaultPGConfig())
+	if hasText(oldApp.Complete("MERG", 4), "MERGE") {
+		t.Error("PostgreSQL 14 sessions should not be offered MERGE")
+	}
+}
+
 func TestNewApp_RegistersMySQLCommands(t *testing.T) {
 	app, _ := newTestApp(MySQL)
 	// Common commands should be registered
@@ -466,3 +533,463 @@ func TestFormatTimingOutput(t *testing.T) {
 		t.Error("should show timing when enabled")
 	}
 }
+
+func TestDisplayResults_Color(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+	result := &format.QueryResult{Columns: []string{"id"}, Rows: [][]string{{"1"}}}
+
+	// Buffers are not terminals, so auto mode writes plain text
+	app.displayResults([]*format.QueryResult{result}, false)
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("auto color should be off for non-TTY output, got %q", buf.String())
+	}
+
+	buf.Reset()
+	app.config.Color = "always"
+	t.Setenv("NO_COLOR", "1")
+	app.displayResults([]*format.QueryResult{result}, false)
+	if !strings.Contains(buf.String(), "\033[") {
+		t.Errorf("--color=always should override NO_COLOR, got %q", buf.String())
+	}
+
+	app.config.Color = "auto"
+	if app.colorEnabled(os.Stdout) {
+		t.Error("NO_COLOR should disable auto color")
+	}
+}
+
+func TestDisplayResults_RenderLeavesOutPager(t *testing.T) {
+	app, _ := newTestApp(PostgreSQL)
+	app.config.EnablePager = true
+	app.special.Pager = "sleep 0.3" // a user reading for a while
+	result := &format.QueryResult{Columns: []string{"id"}}
+	for i := 0; i < 1000; i++ {
+		result.Rows = append(result.Rows, []string{"1"})
+	}
+
+	start := time.Now()
+	render := app.displayResults([]*format.QueryResult{result}, false)
+	if time.Since(start) < 300*time.Millisecond {
+		t.Skip("the pager did not run")
+	}
+	if render >= 300*time.Millisecond {
+		t.Errorf("render time should not include the pager, got %s", render)
+	}
+}
+
+func TestDisplayResults_ExpandedAuto(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+	app.special.ExpandedAuto = true
+	defer func(orig func() int) { terminalWidth = orig }(terminalWidth)
+	terminalWidth = func() int { return 30 }
+
+	narrow := &format.QueryResult{Columns: []string{"id"}, Rows: [][]string{{"1"}}}
+	app.displayResults([]*format.QueryResult{narrow}, false)
+	if strings.Contains(buf.String(), "RECORD") {
+		t.Errorf("narrow result should stay a table, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	wide := &format.QueryResult{Columns: []string{"id", "note"}, Rows: [][]string{{"1", strings.Repeat("x", 40)}}}
+	app.displayResults([]*format.QueryResult{wide}, false)
+	if !strings.Contains(buf.String(), "RECORD 1") {
+		t.Errorf("result wider than the terminal should be expanded, got:\n%s", buf.String())
+	}
+}
+
+func TestHandleInput_Crosstabview(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+	mock := app.executor.(*mockExecutor)
+	mock.results = []*format.QueryResult{{
+		Columns: []string{"v", "h", "d"},
+		Rows:    [][]string{{"a", "x", "1"}, {"a", "y", "2"}},
+	}}
+
+	app.HandleInput("SELECT v, h, d FROM t")
+	buf.Reset()
+	app.HandleInput(`\crosstabview`)
+	if !strings.Contains(buf.String(), "(1 row)") || !strings.Contains(buf.String(), "y") {
+		t.Errorf("expected pivoted result, got:\n%s", buf.String())
+	}
+}
+
+func TestHandleInput_QueryToFile(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+	app.special.Timing = false
+	mock := app.executor.(*mockExecutor)
+	mock.results = []*format.QueryResult{{
+		Columns:    []string{"id", "name"},
+		Rows:       [][]string{{"1", "Alice"}},
+		StatusText: "(1 row)",
+	}}
+
+	path := filepath.Join(t.TempDir(), "out.csv")
+	app.HandleInput(`SELECT id, name FROM users \g (format=csv) ` + path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("output file not written: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "id,name\n1,Alice\n") {
+		t.Errorf("unexpected file contents: %q", data)
+	}
+	if strings.Contains(buf.String(), "Alice") {
+		t.Errorf("results should not go to stdout, got %q", buf.String())
+	}
+	if app.special.TableFormat == "csv" {
+		t.Error("\\g options should only apply to that query")
+	}
+	if app.lastQuery != "SELECT id, name FROM users" {
+		t.Errorf("query before \\g should become the last query, got %q", app.lastQuery)
+	}
+}
+
+func TestHandleInput_QueryToPipe(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+	app.special.Timing = false
+	app.lastQuery = "SELECT 1"
+
+	// \gx with no query re-runs the previous one
+	app.HandleInput(`\gx | tr a-z A-Z`)
+	if !strings.Contains(buf.String(), "RECORD 1") || !strings.Contains(buf.String(), "RESULT") {
+		t.Errorf("expected upper-cased expanded output, got %q", buf.String())
+	}
+}
+
+func TestSplitQueryCommand(t *testing.T) {
+	app, _ := newTestApp(PostgreSQL)
+
+	tests := []struct {
+		input, query, cmd string
+	}{
+		{`SELECT 1 \g out.txt`, "SELECT 1", `\g out.txt`},
+		{`SELECT '\g' \gx`, `SELECT '\g'`, `\gx`},
+		{`SELECT 1`, "SELECT 1", ""},
+		{`\echo \g`, `\echo \g`, ""},
+	}
+	for _, tt := range tests {
+		query, cmd := app.splitQueryCommand(tt.input)
+		if query != tt.query || cmd != tt.cmd {
+			t.Errorf("splitQueryCommand(%q) = %q, %q; want %q, %q", tt.input, query, cmd, tt.query, tt.cmd)
+		}
+	}
+}
+
+type fkMockExecutor struct {
+	mockExecutor
+}
+
+func (m *fkMockExecutor) Tables(_ context.Context, _ string) ([]string, error) {
+	return []string{"orders", "users"}, nil
+}
+
+func (m *fkMockExecutor) ForeignKeys(_ context.Context) ([]completion.ForeignKey, error) {
+	return []completion.ForeignKey{{
+		ParentTable: "users", ParentColumn: "id",
+		ChildTable: "orders", ChildColumn: "user_id",
+	}}, nil
+}
+
+func TestRefreshCompletions_ForeignKeys(t *testing.T) {
+	cfg := config.DefaultPGConfig()
+	mock := &fkMockExecutor{mockExecutor{database: "testdb"}}
+	app := NewApp(PostgreSQL, mock, mock, cfg)
+	app.RefreshCompletions()
+
+	text := "SELECT * FROM orders o JOIN users u ON "
+	suggestions := app.Complete(text, len(text))
+	if len(suggestions) == 0 || suggestions[0].Text != "o.user_id = u.id" {
+		t.Errorf("foreign keys should be loaded for completion, got %v", suggestions)
+	}
+}
+
+type schemaMockExecutor struct {
+	mockExecutor
+}
+
+func (m *schemaMockExecutor) Schemas(_ context.Context) ([]string, error) {
+	return []string{"auth", "information_schema", "pg_catalog", "public"}, nil
+}
+
+func (m *schemaMockExecutor) Tables(_ context.Context, schema string) ([]string, error) {
+	switch schema {
+	case "public":
+		return []string{"users"}, nil
+	case "auth":
+		return []string{"sessions", "users"}, nil
+	}
+	return []string{"catalog_table"}, nil
+}
+
+func (m *schemaMockExecutor) Columns(_ context.Context, table string) ([]string, error) {
+	return []string{strings.ReplaceAll(table, ".", "_") + "_id"}, nil
+}
+
+func (m *schemaMockExecutor) SearchPath(_ context.Context) ([]string, error) {
+	return []string{"pg_catalog", "public"}, nil
+}
+
+func TestRefreshCompletions_Schemas(t *testing.T) {
+	cfg := config.DefaultPGConfig()
+	mock := &schemaMockExecutor{mockExecutor{database: "testdb"}}
+	app := NewApp(PostgreSQL, mock, mock, cfg)
+	app.RefreshCompletions()
+
+	texts := make(map[string]bool)
+	for _, s := range app.Complete("SELECT * FROM ", 14) {
+		texts[s.Text] = true
+	}
+	for _, want := range []string{"users", "auth.sessions", "auth.users"} {
+		if !texts[want] {
+			t.Errorf("expected %q in %v", want, texts)
+		}
+	}
+	if texts["catalog_table"] || texts["pg_catalog.catalog_table"] {
+		t.Error("system schemas should not be loaded")
+	}
+
+	suggestions := app.Complete("SELECT auth.users.", 18)
+	if len(suggestions) != 1 || suggestions[0].Text != "auth_users_id" {
+		t.Errorf("expected auth.users columns, got %v", suggestions)
+	}
+}
+
+// recordingExecutor remembers every query it is sent.
+type recordingExecutor struct {
+	mockExecutor
+	queries []string
+}
+
+func (m *recordingExecutor) Execute(ctx context.Context, query string) (*format.QueryResult, error) {
+	m.queries = append(m.queries, query)
+	return m.mockExecutor.Execute(ctx, query)
+}
+
+func newMultiLineApp(mode DBMode) (*App, *recordingExecutor) {
+	cfg := config.DefaultPGConfig()
+	if mode == MySQL {
+		cfg = config.DefaultMySQLConfig()
+	}
+	cfg.MultiLine = true
+	cfg.LessChatty = true
+	mock := &recordingExecutor{mockExecutor: mockExecutor{database: "testdb"}}
+	app := NewApp(mode, mock, mock, cfg)
+	var buf bytes.Buffer
+	app.Stdout = &buf
+	app.Stderr = &buf
+	return app, mock
+}
+
+func TestRunScript_BackslashCommandEndsAtNewline(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	app.config.EnablePager = false
+	file := filepath.Join(t.TempDir(), "script.sql")
+	os.WriteFile(file, []byte("\\x on\nSELECT 1;\nSELECT 2;\n"), 0644)
+
+	app.HandleInput(`\i ` + file)
+	if !app.special.Expanded {
+		t.Error("the backslash command should run on its own")
+	}
+	if len(mock.queries) != 2 || mock.queries[0] != "SELECT 1" {
+		t.Errorf("the SQL after it should run, got %q", mock.queries)
+	}
+}
+
+func TestHandleMultiLine_DollarQuotedBody(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	lines := []string{
+		"CREATE FUNCTION one() RETURNS int AS $$",
+		"BEGIN",
+		"  RETURN 1;",
+		"END;",
+		"$$ LANGUAGE plpgsql;",
+	}
+	for i, line := range lines {
+		app.HandleInput(line)
+		if i < len(lines)-1 && len(mock.queries) != 0 {
+			t.Fatalf("executed early after %q: %q", line, mock.queries)
+		}
+	}
+	if len(mock.queries) != 1 || !strings.Contains(mock.queries[0], "RETURN 1;\nEND;\n$$") {
+		t.Errorf("the function should be sent as one statement, got %q", mock.queries)
+	}
+}
+
+func TestHandleMultiLine_OpenStringAndComment(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	app.HandleInput("SELECT 'a;")
+	app.HandleInput("b' /* note;")
+	if len(mock.queries) != 0 {
+		t.Fatalf("a ; in a string or comment should not end the statement, got %q", mock.queries)
+	}
+	app.HandleInput(`\still in the comment */;`)
+	if len(mock.queries) != 1 {
+		t.Errorf("the statement should run once the comment closes, got %q", mock.queries)
+	}
+}
+
+func TestHandleMultiLine_MySQLBackslashEscape(t *testing.T) {
+	app, mock := newMultiLineApp(MySQL)
+	app.HandleInput(`SELECT 'it\'s;`)
+	if len(mock.queries) != 0 {
+		t.Fatalf("an escaped quote should keep the string open, got %q", mock.queries)
+	}
+	app.HandleInput(`';`)
+	if len(mock.queries) != 1 || mock.queries[0] != "SELECT 'it\\'s;\n'" {
+		t.Errorf("unexpected queries %q", mock.queries)
+	}
+}
+
+func TestHandleMultiLine_MySQLDelimiter(t *testing.T) {
+	app, mock := newMultiLineApp(MySQL)
+	for _, line := range []string{"delimiter //", "CREATE PROCEDURE p()", "BEGIN", "  SELECT 1;"} {
+		app.HandleInput(line)
+	}
+	if len(mock.queries) != 0 {
+		t.Fatalf("a ; inside the body should not end the statement, got %q", mock.queries)
+	}
+	if !strings.Contains(app.GetPrompt(), "[delimiter //]") {
+		t.Errorf("the prompt should show the delimiter, got %q", app.GetPrompt())
+	}
+	app.HandleInput("END //")
+	if len(mock.queries) != 1 || mock.queries[0] != "CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND" {
+		t.Fatalf("unexpected queries %q", mock.queries)
+	}
+
+	app.HandleInput("delimiter ;")
+	if strings.Contains(app.GetPrompt(), "delimiter") {
+		t.Errorf("the default delimiter should not be shown, got %q", app.GetPrompt())
+	}
+	app.HandleInput("CALL p();")
+	if len(mock.queries) != 2 || mock.queries[1] != "CALL p()" {
+		t.Errorf("unexpected queries %q", mock.queries)
+	}
+}
+
+func TestExecuteNonInteractive_DelimiterScript(t *testing.T) {
+	app, mock := newMultiLineApp(MySQL)
+	failed := app.ExecuteNonInteractive("DELIMITER $$\nCREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET @a = 1; END $$\nDELIMITER ;\nSELECT 1;")
+	if failed {
+		t.Fatal("the script should succeed")
+	}
+	want := []string{"CREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET @a = 1; END", "SELECT 1"}
+	if strings.Join(mock.queries, "|") != strings.Join(want, "|") {
+		t.Errorf("queries = %q, want %q", mock.queries, want)
+	}
+}
+
+func TestHandleMultiLine_SafeMode(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	app.config.MultiLineMode = "safe"
+
+	for _, line := range []string{"SELECT 1;", "", "SELECT 2;"} {
+		app.HandleInput(line)
+	}
+	if len(mock.queries) != 0 {
+		t.Fatalf("Enter should never execute in safe mode, got %q", mock.queries)
+	}
+	if app.LivePrompt() != app.GetContinuationPrompt() {
+		t.Errorf("a buffered statement should show the continuation prompt, got %q", app.LivePrompt())
+	}
+	app.SubmitInput("")
+	if len(mock.queries) != 2 || mock.queries[0] != "SELECT 1" || mock.queries[1] != "SELECT 2" {
+		t.Errorf("a forced submit should run the buffer, got %q", mock.queries)
+	}
+	if app.LivePrompt() != app.GetPrompt() {
+		t.Errorf("the buffer should be empty after a submit, got prompt %q", app.LivePrompt())
+	}
+}
+
+func TestSubmitInput_IncompleteStatement(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	app.HandleInput("SELECT *")
+	app.SubmitInput("FROM users")
+	if len(mock.queries) != 1 || mock.queries[0] != "SELECT *\nFROM users" {
+		t.Errorf("a forced submit should not need a semicolon, got %q", mock.queries)
+	}
+	if app.SubmitInput("") || len(mock.queries) != 1 {
+		t.Errorf("submitting an empty buffer should do nothing, got %q", mock.queries)
+	}
+}
+
+func TestReadOnly_RefusesWrites(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	out := app.Stdout.(*bytes.Buffer)
+	app.config.ReadOnly = true
+	app.special.Favorites["purge"] = "DELETE FROM sessions"
+
+	if !strings.HasPrefix(app.GetPrompt(), "[read-only] ") {
+		t.Errorf("the prompt should show read-only mode, got %q", app.GetPrompt())
+	}
+
+	app.HandleInput("SELECT 1; UPDATE users SET admin = true;")
+	app.HandleInput(`\f purge`)
+	app.HandleInput("SET default_transaction_read_only = off;")
+	if failed := app.ExecuteNonInteractive("INSERT INTO t VALUES (1)"); !failed {
+		t.Error("a refused write should fail -e")
+	}
+	if len(mock.queries) != 1 || mock.queries[0] != "SELECT 1" {
+		t.Errorf("only reads should reach the server, got %q", mock.queries)
+	}
+	if strings.Count(out.String(), "read-only mode") != 4 {
+		t.Errorf("each refused write should be reported, got %q", out.String())
+	}
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	out := app.Stdout.(*bytes.Buffer)
+	answer := "n"
+	app.confirmInput = func() string { return answer }
+
+	app.HandleInput("UPDATE users SET name = 'x' WHERE id = 1;")
+	if len(mock.queries) != 1 || strings.Contains(out.String(), "destructive") {
+		t.Fatalf("a qualified UPDATE should run without asking: %q, %q", mock.queries, out.String())
+	}
+	out.Reset()
+
+	app.HandleInput("SELECT 1; DROP TABLE users, orders; DELETE FROM sessions;")
+	want := "You're about to run a destructive command:\n" +
+		"  DROP TABLE users, orders\n" +
+		"  DELETE without WHERE on every row of sessions\n" +
+		"Do you want to proceed? (y/n): Wise choice!\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	if len(mock.queries) != 1 {
+		t.Errorf("declining should run none of the input, got %q", mock.queries)
+	}
+
+	answer = "y"
+	app.HandleInput("TRUNCATE sessions;")
+	if len(mock.queries) != 2 || mock.queries[1] != "TRUNCATE sessions" {
+		t.Errorf("accepting should run the input, got %q", mock.queries)
+	}
+
+	app.config.DestructiveKeywords = []string{"drop"}
+	answer = "n"
+	app.HandleInput("DELETE FROM sessions;")
+	if len(mock.queries) != 3 {
+		t.Errorf("destructive_keywords should choose the rules, got %q", mock.queries)
+	}
+}
+
+func TestConfirmDestructive_FavoritesAndScripts(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	out := app.Stdout.(*bytes.Buffer)
+	app.confirmInput = func() string { return "n" }
+	app.special.Favorites["purge"] = "SELECT 1; DROP TABLE sessions"
+	script := filepath.Join(t.TempDir(), "cleanup.sql")
+	os.WriteFile(script, []byte("SELECT 1;\nTRUNCATE sessions;\n"), 0644)
+
+	app.HandleInput(`\f purge`)
+	app.HandleInput(`\i ` + script)
+	if len(mock.queries) != 0 {
+		t.Errorf("declining should run nothing, got %q", mock.queries)
+	}
+	for _, want := range []string{"DROP TABLE sessions", "TRUNCATE sessions"} {
+		if !strings.Contains(out.String(), "  "+want+"\n") {
+			t.Errorf("%s should be confirmed, got %q", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactPasswords(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{`\c postgres://alice:s3cret@db:5432/shop`, `\c postgres://alice:***@db:5432/shop`},
+		{"SELECT * FROM dblink('host=db user=a password=s3cret dbname=x', 'SELECT 1')",
+			"SELECT * FROM dblink('host=db user=a password=*** dbname=x', 'SELECT 1')"},
+		{"CREATE SUBSCRIPTION s CONNECTION 'password = \"p w\" host=db'",
+			"CREATE SUBSCRIPTION s CONNECTION 'password = *** host=db'"},
+		{"ALTER ROLE alice WITH PASSWORD 'it''s secret'", "ALTER ROLE alice WITH PASSWORD '***'"},
+		{"CREATE USER bob IDENTIFIED BY 'hunter2'", "CREATE USER bob IDENTIFIED BY '***'"},
+		{"ALTER USER bob IDENTIFIED WITH mysql_native_password BY 'x'", "ALTER USER bob IDENTIFIED WITH mysql_native_password BY '***'"},
+		{"SELECT password FROM users WHERE id = 1", "SELECT password FROM users WHERE id = 1"},
+		{"SELECT 'mysql://root@localhost'", "SELECT 'mysql://root@localhost'"},
+	}
+	for _, tt := range tests {
+		if got := RedactPasswords(tt.input); got != tt.want {
+			t.Errorf("RedactPasswords(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func testEntry() AuditEntry {
+	return AuditEntry{
+		Time:      time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		Database:  "shop",
+		User:      "alice",
+		Statement: "ALTER ROLE bob PASSWORD 'pw'",
+		Duration:  1500 * time.Microsecond,
+		Rows:      0,
+	}
+}
+
+func TestAuditLog_Text(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewAuditLog(&buf, "text", "INFO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Record(testEntry())
+	e := testEntry()
+	e.Statement, e.Rows, e.Error = "SELECT\n1", 1, "boom"
+	l.Record(e)
+
+	want := "2026-10-18T09:30:00Z db=shop user=alice duration=1.5ms rows=0 statement=\"ALTER ROLE bob PASSWORD '***'\"\n" +
+		"2026-10-18T09:30:00Z db=shop user=alice duration=1.5ms rows=1 error=\"boom\" statement=\"SELECT\\n1\"\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestAuditLog_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := NewAuditLog(&buf, "json", "INFO")
+	l.Record(testEntry())
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("each entry should be a JSON line: %v, %q", err, buf.String())
+	}
+	if got["statement"] != "ALTER ROLE bob PASSWORD '***'" || got["duration_ms"] != 1.5 ||
+		got["database"] != "shop" || got["user"] != "alice" || got["time"] != "2026-10-18T09:30:00Z" {
+		t.Errorf("unexpected entry %v", got)
+	}
+	if _, ok := got["error"]; ok {
+		t.Error("successful statements should have no error")
+	}
+}
+
+func TestAuditLog_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := NewAuditLog(&buf, "text", "error")
+	l.Record(testEntry())
+	if buf.Len() != 0 {
+		t.Errorf("log_level ERROR should skip successful statements, got %q", buf.String())
+	}
+	e := testEntry()
+	e.Error = "permission denied"
+	l.Record(e)
+	if !strings.Contains(buf.String(), "permission denied") {
+		t.Errorf("log_level ERROR should record failures, got %q", buf.String())
+	}
+
+	if l, err := NewAuditLog(&buf, "text", "NONE"); l != nil || err != nil {
+		t.Errorf("log_level NONE should disable the log, got %v, %v", l, err)
+	}
+	if _, err := NewAuditLog(&buf, "xml", "INFO"); err == nil {
+		t.Error("unknown formats should be rejected")
+	}
+}
+
+func TestOpenAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	l, err := OpenAuditLog(path, "json", "INFO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Record(testEntry())
+	l.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("the log should only be readable by its owner, got %v", fi.Mode().Perm())
+	}
+}
+
+type userExecutor struct {
+	mockExecutor
+}
+
+func (u *userExecutor) User() string { return "alice" }
+
+func TestApp_RecordsStatements(t *testing.T) {
+	app, _ := newTestApp(PostgreSQL)
+	exec := &userExecutor{mockExecutor{database: "shop"}}
+	app.executor = exec
+	var buf bytes.Buffer
+	l, _ := NewAuditLog(&buf, "json", "INFO")
+	app.SetAuditLog(l)
+
+	app.HandleInput("SELECT 1; SELECT 2")
+	exec.err = errors.New("relation \"nope\" does not exist")
+	app.ExecuteNonInteractive("SELECT * FROM nope")
+	app.HandleInput(`\timing`)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("each statement should be recorded once, got %q", lines)
+	}
+	var first, last AuditEntry
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[2]), &last)
+	if first.Statement != "SELECT 1" || first.Database != "shop" || first.User != "alice" || first.Rows != 1 {
+		t.Errorf("unexpected entry %+v", first)
+	}
+	if last.Statement != "SELECT * FROM nope" || !strings.Contains(last.Error, "does not exist") {
+		t.Errorf("failed statements should record their error, got %+v", last)
+	}
+}
+
+func TestApp_RecordsUseAndInitCommand(t *testing.T) {
+	app, _ := newTestApp(MySQL)
+	app.config.EnablePager = false
+	var buf bytes.Buffer
+	l, _ := NewAuditLog(&buf, "text", "INFO")
+	app.SetAuditLog(l)
+
+	app.config.InitCommand = "SET NAMES utf8mb4"
+	app.auditInitCommand()
+	app.HandleInput("use shop")
+
+	for _, want := range []string{`statement="SET NAMES utf8mb4"`, "statement=\"USE `shop`\""} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in the audit log, got %q", want, buf.String())
+		}
+	}
+}
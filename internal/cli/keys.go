@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"strings"
+
+	goprompt "github.com/c-bata/go-prompt"
+)
+
+// Keys holds the key bindings of the interactive prompt. Ctrl-J and
+// Alt-Enter, or Esc then Enter outside vi mode, submit the query buffer
+// whatever multi_line_mode says, and vi bindings replace the emacs ones
+// when the config asks for them.
+type Keys struct {
+	app    *App
+	vi     bool
+	force  bool // the line being executed was force-submitted
+	normal bool // vi normal mode rather than insert mode
+}
+
+// Keys returns the key bindings for a's interactive prompt.
+func (a *App) Keys() *Keys {
+	return &Keys{app: a, vi: a.config.ViMode || a.config.KeyBindings == "vi"}
+}
+
+// Options returns the go-prompt options that install the bindings.
+func (k *Keys) Options() []goprompt.Option {
+	opts := []goprompt.Option{
+		goprompt.OptionParser(&submitParser{
+			ConsoleParser: goprompt.NewStandardInputParser(),
+			escEnter:      !k.vi,
+		}),
+		// go-prompt reads Ctrl-J (a line feed) as Enter and the Enter
+		// key (a carriage return) as Ctrl-M, and runs the line on both
+		goprompt.OptionAddKeyBind(goprompt.KeyBind{
+			Key: goprompt.Enter,
+			Fn:  func(*goprompt.Buffer) { k.force = true },
+		}),
+	}
+	if k.vi {
+		opts = append(opts,
+			goprompt.OptionSwitchKeyBindMode(goprompt.CommonKeyBind),
+			goprompt.OptionAddKeyBind(goprompt.KeyBind{
+				Key: goprompt.Escape,
+				Fn: func(buf *goprompt.Buffer) {
+					if !k.normal {
+						k.normal = true
+						buf.CursorLeft(1)
+					}
+				},
+			}),
+			goprompt.OptionAddASCIICodeBind(k.viBindings()...),
+		)
+	}
+	return opts
+}
+
+// Execute handles a line from the prompt. A force-submitted line runs
+// the whole query buffer; otherwise the line goes to HandleInput.
+func (k *Keys) Execute(input string) (shouldQuit bool) {
+	forced := k.force
+	k.force, k.normal = false, false
+	input = strings.TrimSpace(input)
+	if forced {
+		return k.app.SubmitInput(input)
+	}
+	if input == "" {
+		return false
+	}
+	return k.app.HandleInput(input)
+}
+
+// viBindings binds every printable character, inserting it in insert
+// mode and running it as a command in normal mode.
+func (k *Keys) viBindings() []goprompt.ASCIICodeBind {
+	var binds []goprompt.ASCIICodeBind
+	for ch := byte(' '); ch <= '~'; ch++ {
+		binds = append(binds, goprompt.ASCIICodeBind{
+			ASCIICode: []byte{ch},
+			Fn: func(buf *goprompt.Buffer) {
+				if k.normal {
+					k.viCommand(buf, ch)
+				} else {
+					buf.InsertText(string(ch), false, true)
+				}
+			},
+		})
+	}
+	return binds
+}
+
+// viCommand runs a normal mode command. Unknown keys do nothing.
+func (k *Keys) viCommand(buf *goprompt.Buffer, ch byte) {
+	d := buf.Document()
+	toLineStart := func() { buf.CursorLeft(len([]rune(d.CurrentLineBeforeCursor()))) }
+	toLineEnd := func() { buf.CursorRight(len([]rune(d.CurrentLineAfterCursor()))) }
+
+	switch ch {
+	case 'h':
+		buf.CursorLeft(1)
+	case 'l', ' ':
+		buf.CursorRight(1)
+	case '0', '^':
+		toLineStart()
+	case '$':
+		toLineEnd()
+	case 'w':
+		buf.CursorRight(nextWordStart(d.CurrentLineAfterCursor()))
+	case 'b':
+		buf.CursorLeft(len([]rune(d.GetWordBeforeCursorWithSpace())))
+	case 'x':
+		buf.Delete(1)
+	case 'X':
+		buf.DeleteBeforeCursor(1)
+	case 'D':
+		buf.Delete(len([]rune(d.CurrentLineAfterCursor())))
+	case 'i':
+		k.normal = false
+	case 'a':
+		buf.CursorRight(1)
+		k.normal = false
+	case 'I':
+		toLineStart()
+		k.normal = false
+	case 'A':
+		toLineEnd()
+		k.normal = false
+	case 'C':
+		buf.Delete(len([]rune(d.CurrentLineAfterCursor())))
+		k.normal = false
+	case 'S':
+		toLineStart()
+		buf.Delete(len([]rune(d.CurrentLine())))
+		k.normal = false
+	}
+}
+
+// nextWordStart returns how many runes of after to skip to reach the
+// start of the next word, as vi's w does.
+func nextWordStart(after string) int {
+	runes := []rune(after)
+	i := 0
+	for i < len(runes) && runes[i] != ' ' && runes[i] != '\t' {
+		i++
+	}
+	for i < len(runes) && (runes[i] == ' ' || runes[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// submitParser reads Alt-Enter as Ctrl-J so one key binding forces a
+// submit. With escEnter it does the same for Esc followed by Enter, which
+// vi mode leaves alone since Esc there just leaves insert mode.
+type submitParser struct {
+	goprompt.ConsoleParser
+	escEnter bool
+	escaped  bool // the last read was a lone Esc
+}
+
+func (p *submitParser) Read() ([]byte, error) {
+	b, err := p.ConsoleParser.Read()
+	if err != nil || len(b) == 0 {
+		return b, err
+	}
+	escaped := p.escaped
+	p.escaped = p.escEnter && string(b) == "\x1b"
+	if s := string(b); s == "\x1b\r" || (escaped && s == "\r") {
+		return []byte{'\n'}, nil
+	}
+	return b, nil
+}
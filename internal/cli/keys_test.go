@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"testing"
+
+	goprompt "github.com/c-bata/go-prompt"
+)
+
+func TestKeys_Execute(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	keys := app.Keys()
+
+	keys.Execute("SELECT *")
+	keys.Execute("  ")
+	if len(mock.queries) != 0 {
+		t.Fatalf("an incomplete statement should be buffered, got %q", mock.queries)
+	}
+	keys.force = true
+	keys.Execute("FROM users")
+	if len(mock.queries) != 1 || mock.queries[0] != "SELECT *\nFROM users" {
+		t.Fatalf("a forced line should submit the buffer, got %q", mock.queries)
+	}
+	if keys.force {
+		t.Error("a forced submit should only apply to one line")
+	}
+}
+
+func TestKeys_ViMode(t *testing.T) {
+	app, _ := newMultiLineApp(PostgreSQL)
+	if app.Keys().vi {
+		t.Error("emacs bindings are the default")
+	}
+	app.config.KeyBindings = "vi"
+	keys := app.Keys()
+	if !keys.vi {
+		t.Fatal("key_bindings = vi should enable vi mode")
+	}
+
+	binds := make(map[byte]func(*goprompt.Buffer))
+	for _, b := range keys.viBindings() {
+		binds[b.ASCIICode[0]] = b.Fn
+	}
+	buf := goprompt.NewBuffer()
+	typeKeys := func(s string) {
+		for i := 0; i < len(s); i++ {
+			binds[s[i]](buf)
+		}
+	}
+
+	typeKeys("select 1")
+	if buf.Text() != "select 1" {
+		t.Fatalf("insert mode should insert text, got %q", buf.Text())
+	}
+	keys.normal = true
+	typeKeys("0x")
+	if buf.Text() != "elect 1" || !keys.normal {
+		t.Errorf("0x should delete the first character, got %q", buf.Text())
+	}
+	typeKeys("iS")
+	if buf.Text() != "Select 1" || keys.normal {
+		t.Errorf("i should return to insert mode, got %q", buf.Text())
+	}
+	keys.normal = true
+	typeKeys("0wD")
+	if buf.Text() != "Select " {
+		t.Errorf("wD should delete the second word, got %q", buf.Text())
+	}
+	typeKeys("A2")
+	if buf.Text() != "Select 2" {
+		t.Errorf("A should append at the end of the line, got %q", buf.Text())
+	}
+}
+
+type fakeParser struct {
+	goprompt.ConsoleParser
+	reads [][]byte
+}
+
+func (p *fakeParser) Read() ([]byte, error) {
+	b := p.reads[0]
+	p.reads = p.reads[1:]
+	return b, nil
+}
+
+func TestSubmitParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		escEnter bool
+		reads    []string
+		want     string // the last read as seen by go-prompt
+	}{
+		{"enter", true, []string{"\r"}, "\r"},
+		{"alt-enter", false, []string{"\x1b\r"}, "\n"},
+		{"esc then enter", true, []string{"\x1b", "", "\r"}, "\n"},
+		{"esc then enter in vi mode", false, []string{"\x1b", "\r"}, "\r"},
+		{"esc, key, enter", true, []string{"\x1b", "a", "\r"}, "\r"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeParser{}
+			for _, r := range tt.reads {
+				fake.reads = append(fake.reads, []byte(r))
+			}
+			p := &submitParser{ConsoleParser: fake, escEnter: tt.escEnter}
+			var got []byte
+			for range tt.reads {
+				got, _ = p.Read()
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tomblomfield/gocli/internal/config"
+)
+
+// Backend describes a client binary to Launch: what it is called, where
+// its config lives and how its flags become a connection. Options every
+// backend has are registered by Launch under the names given here, so
+// they behave the same in every binary.
+type Backend struct {
+	Name        string // binary name, such as "pgcli"
+	Mode        DBMode
+	Server      string // product name shown at startup, such as "PostgreSQL"
+	Version     string
+	Usage       string // what follows the binary name in the usage line
+	Description string
+
+	Config     func() *config.Config // defaults before the config file loads
+	ConfigFlag string                // flag naming the config file
+	ConfigEnv  string                // variable naming it when the flag is unset
+
+	// Names of the shared flags that differ between binaries. An empty
+	// name leaves the option out.
+	VersionFlag       string
+	PromptFlag        string
+	PasswordFlag      string // prompt for a password before connecting
+	LogFlag           string // audit log file, overriding log_file
+	ListDatabasesFlag string
+
+	// Register adds the backend's connection flags to fs and returns the
+	// target they describe, which Launch resolves once flags are parsed.
+	Register func(fs *flag.FlagSet) Target
+}
+
+// Target is a backend's connection parameters, gathered from flags, the
+// config, the environment and the command line's arguments.
+type Target interface {
+	// Resolve settles the parameters once flags are parsed. It may also
+	// apply backend-only flags to cfg, and the options of the DSN alias
+	// it connects through with cfg.ApplyAlias. Shared flags are applied
+	// after it, so they override the alias.
+	Resolve(cfg *config.Config, args []string) error
+	// SetPassword sets the password typed at the password prompt.
+	SetPassword(password string)
+	// Open connects to database, or to the resolved database when it is
+	// empty.
+	Open(ctx context.Context, database string) (Executor, error)
+	// CacheKey names the server and user for the completion cache.
+	CacheKey() string
+}
+
+// launchFlags holds the options every backend shares.
+type launchFlags struct {
+	showVersion bool
+	configPath  string
+	prompt      string
+	password    bool
+	logFile     string
+	logFormat   string
+	listDBs     bool
+	lessChatty  bool
+	autoVert    bool
+	listDSN     bool
+	rowLimit    int
+	initCmd     string
+	execute     string
+	jsonSummary bool
+	ping        bool
+	color       string
+	readOnly    bool
+	timeout     string
+}
+
+func (b Backend) register(fs *flag.FlagSet) *launchFlags {
+	f := &launchFlags{}
+	optional := func(p *bool, name, usage string) {
+		if name != "" {
+			fs.BoolVar(p, name, false, usage)
+		}
+	}
+	optional(&f.showVersion, b.VersionFlag, "Print version")
+	optional(&f.password, b.PasswordFlag, "Prompt for password")
+	optional(&f.listDBs, b.ListDatabasesFlag, "List databases and exit")
+	if b.ConfigFlag != "" {
+		fs.StringVar(&f.configPath, b.ConfigFlag, "", "Config file path")
+	}
+	if b.PromptFlag != "" {
+		fs.StringVar(&f.prompt, b.PromptFlag, "", "Prompt format")
+	}
+	if b.LogFlag != "" {
+		fs.StringVar(&f.logFile, b.LogFlag, "", "Audit log file for every statement run")
+	}
+	fs.StringVar(&f.logFormat, "log-format", "", "Audit log format: text or json")
+	fs.BoolVar(&f.lessChatty, "less-chatty", false, "Skip intro/goodbye")
+	fs.BoolVar(&f.autoVert, "auto-vertical-output", false, "Auto vertical for wide results")
+	fs.BoolVar(&f.listDSN, "list-dsn", false, "List DSN aliases and exit")
+	fs.IntVar(&f.rowLimit, "row-limit", 0, "Row limit (0=from config)")
+	fs.StringVar(&f.initCmd, "init-command", "", "SQL to execute after connecting")
+	fs.StringVar(&f.execute, "e", "", "Execute command and exit")
+	fs.BoolVar(&f.jsonSummary, "json-summary", false, "Print a JSON summary of -e statements to stderr")
+	fs.BoolVar(&f.ping, "ping", false, "Check connectivity and exit")
+	fs.StringVar(&f.color, "color", "", "Colorize output: auto, always or never")
+	fs.BoolVar(&f.readOnly, "readonly", false, "Read-only session that refuses writes")
+	fs.StringVar(&f.timeout, "statement-timeout", "", "Cancel statements running longer than this, e.g. 30s (off disables)")
+	return f
+}
+
+// Launch runs the client described by b with the command-line arguments
+// args and returns its exit status.
+func Launch(b Backend, args []string) int {
+	l := &launcher{backend: b, stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	return l.run(args)
+}
+
+type launcher struct {
+	backend Backend
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func (l *launcher) run(args []string) int {
+	b := l.backend
+	fs := flag.NewFlagSet(b.Name, flag.ContinueOnError)
+	fs.SetOutput(l.stderr)
+	flags := b.register(fs)
+	target := b.Register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(l.stderr, "Usage: %s [OPTIONS] %s\n\n", b.Name, b.Usage)
+		fmt.Fprintf(l.stderr, "%s\n\n", b.Description)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if flags.showVersion {
+		fmt.Fprintf(l.stdout, "%s (gocli) %s\n", b.Name, b.Version)
+		return 0
+	}
+
+	// Load config
+	cfg := b.Config()
+	cfgPath := flags.configPath
+	if cfgPath == "" && b.ConfigEnv != "" {
+		cfgPath = os.Getenv(b.ConfigEnv)
+	}
+	if err := cfg.Load(cfgPath); err != nil {
+		fmt.Fprintf(l.stderr, "Warning: could not load config: %s\n", err)
+	}
+
+	// List DSN aliases
+	if flags.listDSN {
+		l.listDSNAliases(cfg)
+		return 0
+	}
+
+	if err := target.Resolve(cfg, fs.Args()); err != nil {
+		fmt.Fprintf(l.stderr, "Error: %s\n", err)
+		return 2
+	}
+
+	// Apply CLI flags
+	if flags.lessChatty {
+		cfg.LessChatty = true
+	}
+	if flags.prompt != "" {
+		cfg.Prompt = flags.prompt
+	}
+	if flags.autoVert {
+		cfg.AutoExpand = true
+	}
+	switch flags.color {
+	case "":
+	case "auto", "always", "never":
+		cfg.Color = flags.color
+	default:
+		fmt.Fprintf(l.stderr, "Invalid --color value %q: use auto, always or never\n", flags.color)
+		return 2
+	}
+	if flags.rowLimit > 0 {
+		cfg.RowLimit = flags.rowLimit
+	}
+	if flags.readOnly {
+		cfg.ReadOnly = true
+	}
+	if flags.initCmd != "" {
+		cfg.InitCommand = flags.initCmd
+	}
+	if flags.timeout != "" {
+		timeout, err := config.ParseTimeout(flags.timeout)
+		if err != nil {
+			fmt.Fprintf(l.stderr, "Invalid --statement-timeout: %s\n", err)
+			return 2
+		}
+		cfg.StatementTimeout = timeout
+	}
+
+	if flags.password {
+		fmt.Fprint(l.stderr, "Password: ")
+		target.SetPassword(readLine(l.stdin))
+	}
+
+	// Connect
+	ctx := context.Background()
+	executor, err := target.Open(ctx, "")
+	if err != nil {
+		fmt.Fprintf(l.stderr, "Connection failed: %s\n", err)
+		return 1
+	}
+	if flags.ping {
+		executor.Close()
+		fmt.Fprintln(l.stdout, "Connection successful.")
+		return 0
+	}
+	defer func() { executor.Close() }()
+
+	if flags.listDBs {
+		return l.listDatabases(ctx, executor)
+	}
+
+	// Create app (used by both -e mode and interactive mode)
+	meta, _ := executor.(MetadataProvider)
+	app := NewApp(b.Mode, executor, meta, cfg)
+	app.Stdin, app.Stdout, app.Stderr = l.stdin, l.stdout, l.stderr
+	app.SetConnector(target.Open)
+
+	// Audit log, only when asked for: statements may hold sensitive data
+	logPath := cfg.LogFile
+	if flags.logFile != "" {
+		logPath = flags.logFile
+	}
+	if flags.logFormat != "" {
+		cfg.LogFormat = flags.logFormat
+	}
+	if logPath != "" {
+		audit, err := OpenAuditLog(logPath, cfg.LogFormat, cfg.LogLevel)
+		if err != nil {
+			fmt.Fprintf(l.stderr, "Warning: could not open log file: %s\n", err)
+		} else if audit != nil {
+			defer audit.Close()
+			app.SetAuditLog(audit)
+		}
+	}
+	app.auditInitCommand()
+	// \c and \r replace the executor; close whichever is current at exit
+	defer func() { executor = app.executor }()
+
+	// Execute mode
+	if flags.execute != "" {
+		if flags.jsonSummary {
+			app.SetSummaryOutput(l.stderr)
+		}
+		if app.ExecuteNonInteractive(flags.execute) {
+			return 1
+		}
+		return 0
+	}
+
+	if !cfg.LessChatty {
+		ver, _ := executor.ServerVersion()
+		fmt.Fprintf(l.stdout, "gocli (%s) %s\n", b.Name, b.Version)
+		if ver != "" {
+			fmt.Fprintf(l.stdout, "Server: %s %s\n", b.Server, ver)
+		}
+		fmt.Fprintf(l.stdout, "Database: %s\n", executor.Database())
+		fmt.Fprintln(l.stdout, "Type \\? for help.")
+		fmt.Fprintln(l.stdout)
+	}
+
+	// Start from cached completions, then refresh in background
+	app.SetCompletionCacheKey(target.CacheKey())
+	app.LoadCompletionCache()
+	app.LoadUsage()
+	app.refreshInBackground()
+
+	// Run interactive loop
+	app.Run()
+	// Let a refresh finish writing the completion cache
+	app.refreshWG.Wait()
+
+	if !cfg.LessChatty {
+		fmt.Fprintln(l.stdout, "Goodbye!")
+	}
+	return 0
+}
+
+// readLine reads one line from r a byte at a time, leaving the rest of
+// piped input for the REPL.
+func readLine(r io.Reader) string {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if n, err := r.Read(b); n == 0 || err != nil || b[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r")
+		}
+		line = append(line, b[0])
+	}
+}
+
+func (l *launcher) listDSNAliases(cfg *config.Config) {
+	if len(cfg.DSNAliases) == 0 {
+		fmt.Fprintln(l.stdout, "No DSN aliases configured.")
+		return
+	}
+	names := make([]string, 0, len(cfg.DSNAliases))
+	for name := range cfg.DSNAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(l.stdout, "  %s = %s\n", name, cfg.DSNAliases[name])
+	}
+}
+
+func (l *launcher) listDatabases(ctx context.Context, executor Executor) int {
+	meta, ok := executor.(MetadataProvider)
+	if !ok {
+		fmt.Fprintln(l.stderr, "Error: listing databases is not supported")
+		return 1
+	}
+	dbs, err := meta.Databases(ctx)
+	if err != nil {
+		fmt.Fprintf(l.stderr, "Error: %s\n", err)
+		return 1
+	}
+	for _, db := range dbs {
+		fmt.Fprintln(l.stdout, db)
+	}
+	return 0
+}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tomblomfield/gocli/internal/config"
+)
+
+type fakeTarget struct {
+	cfg      *config.Config
+	database *string
+	password string
+	resolved []string
+	opened   []string
+	err      error
+}
+
+func (t *fakeTarget) Resolve(cfg *config.Config, args []string) error {
+	t.cfg = cfg
+	t.resolved = args
+	return nil
+}
+
+func (t *fakeTarget) SetPassword(password string) { t.password = password }
+
+func (t *fakeTarget) Open(_ context.Context, database string) (Executor, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if database == "" {
+		database = *t.database
+	}
+	t.opened = append(t.opened, database)
+	return &mockExecutor{database: database, version: "16.1"}, nil
+}
+
+func (t *fakeTarget) CacheKey() string { return "fake://" + *t.database }
+
+// launchTest runs a fake backend with args and stdin, returning its exit
+// status, output and target.
+func launchTest(t *testing.T, stdin string, args ...string) (int, string, *fakeTarget) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	cfgPath := filepath.Join(dir, "config")
+	os.WriteFile(cfgPath, []byte("[main]\nmulti_line = True\n[alias_dsn]\nstaging = fake://staging\nlocal = fake://local\n"), 0644)
+
+	target := &fakeTarget{}
+	b := Backend{
+		Name:        "fakecli",
+		Mode:        PostgreSQL,
+		Server:      "FakeSQL",
+		Version:     "1.2.3",
+		Usage:       "[DBNAME]",
+		Description: "A fake client.",
+		Config:      config.DefaultPGConfig,
+		ConfigFlag:  "fakeclirc",
+		VersionFlag: "v",
+		PromptFlag:  "prompt",
+		LogFlag:     "log-file",
+
+		PasswordFlag:      "W",
+		ListDatabasesFlag: "l",
+
+		Register: func(fs *flag.FlagSet) Target {
+			target.database = fs.String("d", "main", "Database name")
+			return target
+		},
+	}
+	var out bytes.Buffer
+	l := &launcher{backend: b, stdin: strings.NewReader(stdin), stdout: &out, stderr: &out}
+	code := l.run(append([]string{"-fakeclirc", cfgPath}, args...))
+	return code, out.String(), target
+}
+
+func TestLaunch_Execute(t *testing.T) {
+	code, out, target := launchTest(t, "", "-d", "shop", "-e", "SELECT 1; SELECT 2")
+	if code != 0 {
+		t.Fatalf("exit status %d, output %q", code, out)
+	}
+	if strings.Count(out, "(1 row)") != 2 {
+		t.Errorf("both statements should run, got %q", out)
+	}
+	if len(target.opened) != 1 || target.opened[0] != "shop" {
+		t.Errorf("the flag's database should be opened, got %v", target.opened)
+	}
+}
+
+func TestLaunch_LogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	code, out, _ := launchTest(t, "", "-log-file", path, "-log-format", "json", "-e", "SELECT 1")
+	if code != 0 {
+		t.Fatalf("exit status %d, output %q", code, out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"statement":"SELECT 1"`) || !strings.Contains(string(data), `"database":"main"`) {
+		t.Errorf("the log should record the statement, got %q", data)
+	}
+
+	// Without the flag or log_file nothing is logged
+	code, out, _ = launchTest(t, "", "-e", "SELECT 2")
+	if _, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".config", "pgcli", "log")); code != 0 || err == nil {
+		t.Errorf("the audit log should be opt-in: %d, %q, %v", code, out, err)
+	}
+
+	// log_file opts in, and the init command is recorded too
+	cfgPath := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(cfgPath, []byte("[main]\nlog_file = "+path+"\n"), 0644)
+	code, out, _ = launchTest(t, "", "-fakeclirc", cfgPath, "-init-command", "SET search_path = app", "-e", "SELECT 2")
+	data, err = os.ReadFile(path)
+	if code != 0 || err != nil || !strings.Contains(string(data), `statement="SELECT 2"`) || !strings.Contains(string(data), `statement="SET search_path = app"`) {
+		t.Errorf("the config's log_file should record every statement: %d, %q, %q, %v", code, out, data, err)
+	}
+}
+
+func TestLaunch_InitCommand(t *testing.T) {
+	code, out, target := launchTest(t, "", "-init-command", "SET search_path = app", "-e", "SELECT 1")
+	if code != 0 {
+		t.Fatalf("exit status %d, output %q", code, out)
+	}
+	// The target passes it to the connector, which runs it on each new
+	// connection
+	if target.cfg.InitCommand != "SET search_path = app" {
+		t.Errorf("the init command should reach the connection config, got %q", target.cfg.InitCommand)
+	}
+}
+
+func TestLaunch_Interactive(t *testing.T) {
+	code, out, target := launchTest(t, "hunter2\nSELECT\n1;\n", "-W", "-less-chatty", "extra")
+	if code != 0 {
+		t.Fatalf("exit status %d, output %q", code, out)
+	}
+	if target.password != "hunter2" {
+		t.Errorf("the first line should be the password, got %q", target.password)
+	}
+	if len(target.resolved) != 1 || target.resolved[0] != "extra" {
+		t.Errorf("positional arguments should reach the target, got %v", target.resolved)
+	}
+	if !strings.Contains(out, "(1 row)") {
+		t.Errorf("piped input should run through the REPL, got %q", out)
+	}
+	if strings.Contains(out, "Goodbye") {
+		t.Errorf("--less-chatty should skip the goodbye, got %q", out)
+	}
+}
+
+func TestLaunch_Banner(t *testing.T) {
+	_, out, _ := launchTest(t, "")
+	for _, want := range []string{"gocli (fakecli) 1.2.3", "Server: FakeSQL 16.1", "Database: main", "Goodbye!"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestLaunch_ExitEarly(t *testing.T) {
+	code, out, _ := launchTest(t, "", "-v")
+	if code != 0 || out != "fakecli (gocli) 1.2.3\n" {
+		t.Errorf("-v: %d, %q", code, out)
+	}
+	code, out, target := launchTest(t, "", "-list-dsn")
+	if code != 0 || out != "  local = fake://local\n  staging = fake://staging\n" || len(target.opened) != 0 {
+		t.Errorf("--list-dsn should list aliases in order without connecting: %d, %q", code, out)
+	}
+	code, out, _ = launchTest(t, "", "-ping")
+	if code != 0 || !strings.Contains(out, "Connection successful.") {
+		t.Errorf("--ping: %d, %q", code, out)
+	}
+	code, out, _ = launchTest(t, "", "-color", "sometimes")
+	if code != 2 || !strings.Contains(out, "Invalid --color") {
+		t.Errorf("a bad --color should be a usage error: %d, %q", code, out)
+	}
+	code, _, _ = launchTest(t, "", "-no-such-flag")
+	if code != 2 {
+		t.Errorf("an unknown flag should be a usage error, got %d", code)
+	}
+}
+
+func TestLaunch_ConnectionFailed(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	b := Backend{
+		Name:   "fakecli",
+		Mode:   MySQL,
+		Config: config.DefaultMySQLConfig,
+		Register: func(fs *flag.FlagSet) Target {
+			name := ""
+			return &fakeTarget{database: &name, err: errors.New("connection refused")}
+		},
+	}
+	var out bytes.Buffer
+	l := &launcher{backend: b, stdin: strings.NewReader(""), stdout: &out, stderr: &out}
+	if code := l.run([]string{"-e", "SELECT 1"}); code != 1 {
+		t.Errorf("exit status %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "Connection failed: connection refused") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestLaunch_ReadOnly(t *testing.T) {
+	code, out, _ := launchTest(t, "", "-readonly", "-e", "DROP TABLE users")
+	if code != 1 || !strings.Contains(out, "read-only mode") {
+		t.Errorf("--readonly should refuse writes: %d, %q", code, out)
+	}
+}
+
+func TestLaunch_StatementTimeout(t *testing.T) {
+	code, out, _ := launchTest(t, "", "-statement-timeout", "30s", "-e", `\timeout`)
+	if code != 0 || !strings.Contains(out, "Statement timeout is 30s.") {
+		t.Errorf("--statement-timeout should set the timeout: %d, %q", code, out)
+	}
+	code, out, _ = launchTest(t, "", "-statement-timeout", "soon", "-e", "SELECT 1")
+	if code != 2 || !strings.Contains(out, "Invalid --statement-timeout") {
+		t.Errorf("an invalid timeout should be rejected: %d, %q", code, out)
+	}
+}
+
+func TestLaunch_JSONSummary(t *testing.T) {
+	code, out, _ := launchTest(t, "", "-json-summary", "-e", "SELECT 1; SELECT 2")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	last := lines[len(lines)-1]
+	if code != 0 || !strings.HasPrefix(last, `{"statements":2,"failed":0,"timed_out":0,"rows_affected":0,"rows_returned":2,"total_ms":`) {
+		t.Errorf("-e should end with a JSON summary: %d, %q", code, out)
+	}
+}
@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomblomfield/gocli/internal/completion"
+)
+
+// refreshTimeout bounds one metadata refresh.
+const refreshTimeout = 30 * time.Second
+
+// RefreshCompletions reloads schema metadata for auto-completion. The
+// metadata categories are fetched concurrently, and the result replaces
+// the completer's metadata and is written to the completion cache.
+func (a *App) RefreshCompletions() {
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	meta := completion.NewMetadata()
+	var wg sync.WaitGroup
+	load := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	// Each loader writes only its own fields of meta.
+	load(func() { a.loadRelations(ctx, meta) })
+	load(func() {
+		if schemas, err := a.meta.Schemas(ctx); err == nil {
+			meta.Schemas = schemas
+		}
+	})
+	load(func() {
+		if funcs, err := a.meta.Functions(ctx, ""); err == nil {
+			meta.Functions = funcs
+		}
+	})
+	load(func() {
+		if dbs, err := a.meta.Databases(ctx); err == nil {
+			meta.Databases = dbs
+		}
+	})
+	load(func() { meta.Datatypes = a.meta.Datatypes(ctx) })
+	if fkp, ok := a.meta.(ForeignKeyProvider); ok {
+		load(func() {
+			if fks, err := fkp.ForeignKeys(ctx); err == nil {
+				meta.ForeignKeys = fks
+			}
+		})
+	}
+	if ukp, ok := a.meta.(UniqueKeyProvider); ok {
+		load(func() {
+			if keys, err := ukp.UniqueKeys(ctx); err == nil {
+				meta.UniqueKeys = keys
+			}
+		})
+	}
+	if fsp, ok := a.meta.(FunctionSignatureProvider); ok {
+		load(func() {
+			if fns, err := fsp.FunctionSignatures(ctx); err == nil {
+				for _, f := range fns {
+					meta.AddFunction(f)
+				}
+			}
+		})
+	}
+	if spp, ok := a.meta.(SearchPathProvider); ok {
+		load(func() {
+			if path, err := spp.SearchPath(ctx); err == nil {
+				meta.SearchPath = path
+			}
+		})
+	}
+	wg.Wait()
+
+	a.saveCompletionCache(meta)
+	a.addCommandCompletions(meta)
+	a.compMeta = meta
+	a.comp.UpdateMetadata(meta)
+}
+
+// refreshInBackground starts RefreshCompletions without waiting for it.
+func (a *App) refreshInBackground() {
+	a.refreshWG.Add(1)
+	go func() {
+		defer a.refreshWG.Done()
+		a.RefreshCompletions()
+	}()
+}
+
+// addCommandCompletions adds the special commands and favorites, which
+// come from the app rather than the database.
+func (a *App) addCommandCompletions(meta *completion.Metadata) {
+	for _, cmd := range a.special.Commands() {
+		meta.Specials = append(meta.Specials, completion.SpecialCmd{
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			Aliases:     cmd.Aliases,
+			Arg:         cmd.Arg,
+			Choices:     cmd.Choices,
+		})
+	}
+	for name := range a.special.Favorites {
+		meta.Favorites = append(meta.Favorites, name)
+	}
+}
+
+// loadRelations loads the tables and views with their columns. Backends
+// that can list them in one catalog query do so; otherwise the tables of
+// every user schema are listed and their columns fetched one by one.
+func (a *App) loadRelations(ctx context.Context, meta *completion.Metadata) {
+	if rp, ok := a.meta.(RelationsProvider); ok {
+		if rels, err := rp.Relations(ctx); err == nil {
+			for i := range rels {
+				meta.AddRelation(&rels[i])
+			}
+			return
+		}
+	}
+
+	schemas, _ := a.meta.Schemas(ctx)
+	loaded := false
+	for _, schema := range schemas {
+		if a.systemSchema(schema) {
+			continue
+		}
+		tables, err := a.meta.Tables(ctx, schema)
+		if err != nil {
+			continue
+		}
+		loaded = true
+		for _, table := range tables {
+			cols, _ := a.meta.Columns(ctx, schema+"."+table)
+			meta.AddTable(schema, table, cols)
+		}
+	}
+	if loaded {
+		return
+	}
+
+	// The schemas could not be listed: use the tables the backend
+	// reports by default.
+	tables, err := a.meta.Tables(ctx, "")
+	if err != nil {
+		return
+	}
+	for _, table := range tables {
+		cols, _ := a.meta.Columns(ctx, table)
+		schema, name := "", table
+		if i := strings.LastIndex(table, "."); i >= 0 {
+			schema, name = table[:i], table[i+1:]
+		}
+		meta.AddTable(schema, name, cols)
+	}
+}
+
+// systemSchema reports whether schema holds the backend's own catalogs,
+// which are left out of completion.
+func (a *App) systemSchema(schema string) bool {
+	switch strings.ToLower(schema) {
+	case "information_schema":
+		return true
+	case "mysql", "performance_schema", "sys":
+		return a.mode == MySQL
+	}
+	return a.mode == PostgreSQL && strings.HasPrefix(schema, "pg_")
+}
+
+// changesSchema reports whether a successful query may have changed the
+// objects or search path that completion knows about.
+func changesSchema(query string) bool {
+	fields := strings.Fields(strings.ToUpper(query))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "CREATE", "ALTER", "DROP", "RENAME", "USE":
+		return true
+	case "SET":
+		return strings.Contains(strings.ToLower(query), "search_path")
+	}
+	return false
+}
+
+// SetCompletionCacheKey identifies the server whose completion metadata is
+// cached, e.g. "postgresql://user@host:5432". Each database on it has its
+// own cache file. An empty key disables the cache.
+func (a *App) SetCompletionCacheKey(key string) {
+	a.cacheKey = key
+}
+
+// completionCachePath returns the cache file for the current connection,
+// or "" when caching is off.
+func (a *App) completionCachePath() string {
+	if a.config.CompletionCacheDir == "" || a.cacheKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(a.cacheKey + "/" + a.executor.Database()))
+	return filepath.Join(a.config.CompletionCacheDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// LoadCompletionCache loads the cached metadata for the current connection
+// so completions are available before the first refresh finishes. It
+// reports whether a cache was found.
+func (a *App) LoadCompletionCache() bool {
+	path := a.completionCachePath()
+	if path == "" {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	meta := completion.NewMetadata()
+	if err := json.Unmarshal(data, meta); err != nil {
+		return false
+	}
+	a.addCommandCompletions(meta)
+	a.comp.UpdateMetadata(meta)
+	return true
+}
+
+// saveCompletionCache writes the database metadata to the cache. Errors
+// are ignored: the cache only speeds up the next start.
+func (a *App) saveCompletionCache(meta *completion.Metadata) {
+	path := a.completionCachePath()
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tomblomfield/gocli/internal/completion"
+	"github.com/tomblomfield/gocli/internal/config"
+)
+
+// relationsMockExecutor lists its relations in one call and counts the
+// per-table column queries and refreshes.
+type relationsMockExecutor struct {
+	mockExecutor
+	relations   []completion.Relation
+	columnCalls atomic.Int32
+	refreshes   atomic.Int32
+}
+
+func (m *relationsMockExecutor) Relations(_ context.Context) ([]completion.Relation, error) {
+	m.refreshes.Add(1)
+	return m.relations, nil
+}
+
+func (m *relationsMockExecutor) Columns(_ context.Context, _ string) ([]string, error) {
+	m.columnCalls.Add(1)
+	return nil, nil
+}
+
+func newRelationsMock() *relationsMockExecutor {
+	return &relationsMockExecutor{
+		mockExecutor: mockExecutor{database: "testdb"},
+		relations: []completion.Relation{
+			{Schema: "public", Name: "users", Columns: []string{"id", "email"}},
+			{Schema: "public", Name: "active_users", View: true, Columns: []string{"id"}},
+		},
+	}
+}
+
+func TestRefreshCompletions_SingleCatalogQuery(t *testing.T) {
+	mock := newRelationsMock()
+	app := NewApp(PostgreSQL, mock, mock, config.DefaultPGConfig())
+	app.RefreshCompletions()
+
+	if n := mock.columnCalls.Load(); n != 0 {
+		t.Errorf("columns should come from the catalog query, got %d Columns calls", n)
+	}
+	suggestions := app.Complete("SELECT users.", 13)
+	if len(suggestions) != 2 {
+		t.Errorf("expected users columns, got %v", suggestions)
+	}
+	found := false
+	for _, s := range app.Complete("SELECT * FROM act", 17) {
+		if s.Text == "active_users" && s.Type == completion.SuggestView {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("views from the catalog query should be suggested as views")
+	}
+}
+
+func TestCompletionCache(t *testing.T) {
+	cfg := config.DefaultPGConfig()
+	cfg.CompletionCacheDir = t.TempDir()
+
+	mock := newRelationsMock()
+	app := NewApp(PostgreSQL, mock, mock, cfg)
+	app.SetCompletionCacheKey("postgresql://me@localhost:5432")
+	app.RefreshCompletions()
+
+	// A new session for the same connection starts from the cache
+	empty := &mockExecutor{database: "testdb"}
+	cached := NewApp(PostgreSQL, empty, empty, cfg)
+	cached.SetCompletionCacheKey("postgresql://me@localhost:5432")
+	if !cached.LoadCompletionCache() {
+		t.Fatal("expected a completion cache")
+	}
+	if suggestions := cached.Complete("SELECT users.", 13); len(suggestions) != 2 {
+		t.Errorf("expected cached users columns, got %v", suggestions)
+	}
+	if suggestions := cached.Complete(`\d`, 2); len(suggestions) == 0 {
+		t.Error("special commands should be available with cached metadata")
+	}
+
+	// Other databases have their own cache
+	otherDB := &mockExecutor{database: "otherdb"}
+	other := NewApp(PostgreSQL, otherDB, otherDB, cfg)
+	other.SetCompletionCacheKey("postgresql://me@localhost:5432")
+	if other.LoadCompletionCache() {
+		t.Error("cache should be per connection")
+	}
+}
+
+func TestExecute_DDLRefreshesCompletions(t *testing.T) {
+	mock := newRelationsMock()
+	app := NewApp(PostgreSQL, mock, mock, config.DefaultPGConfig())
+	app.Stdout = io.Discard
+	app.Stderr = io.Discard
+
+	app.HandleInput("SELECT 1;")
+	app.refreshWG.Wait()
+	if n := mock.refreshes.Load(); n != 0 {
+		t.Errorf("queries should not refresh completions, got %d refreshes", n)
+	}
+
+	app.HandleInput("CREATE TABLE t (id int);")
+	app.refreshWG.Wait()
+	if n := mock.refreshes.Load(); n != 1 {
+		t.Errorf("DDL should refresh completions once, got %d", n)
+	}
+}
+
+func TestChangesSchema(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"CREATE TABLE t (id int)", true},
+		{"alter table t add column x int", true},
+		{"DROP VIEW v", true},
+		{"USE shop", true},
+		{"SET search_path TO app, public", true},
+		{"SET statement_timeout = 0", false},
+		{"SELECT * FROM created", false},
+		{"INSERT INTO t VALUES (1)", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := changesSchema(tt.query); got != tt.want {
+			t.Errorf("changesSchema(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+type signatureMockExecutor struct {
+	mockExecutor
+}
+
+func (m *signatureMockExecutor) Functions(_ context.Context, _ string) ([]string, error) {
+	return []string{"add_tax"}, nil
+}
+
+func (m *signatureMockExecutor) FunctionSignatures(_ context.Context) ([]completion.Function, error) {
+	return []completion.Function{
+		{Schema: "public", Name: "add_tax", Arguments: "amount numeric, rate numeric", Result: "numeric"},
+	}, nil
+}
+
+func TestRefreshCompletions_FunctionSignatures(t *testing.T) {
+	mock := &signatureMockExecutor{mockExecutor{database: "testdb"}}
+	app := NewApp(PostgreSQL, mock, mock, config.DefaultPGConfig())
+	app.RefreshCompletions()
+
+	suggestions := app.Complete("SELECT add_", 11)
+	if len(suggestions) == 0 || suggestions[0].Description != "(amount numeric, rate numeric) → numeric" {
+		t.Errorf("expected signature in description, got %v", suggestions)
+	}
+
+	text := "SELECT add_tax(price, "
+	suggestions = app.Complete(text, len(text))
+	if len(suggestions) == 0 || suggestions[0].Description != "add_tax(amount numeric, [rate numeric]) → numeric" {
+		t.Errorf("expected parameter hint, got %v", suggestions)
+	}
+}
+
+type uniqueKeyMockExecutor struct {
+	*relationsMockExecutor
+}
+
+func (m *uniqueKeyMockExecutor) UniqueKeys(_ context.Context) ([]completion.UniqueKey, error) {
+	return []completion.UniqueKey{{Schema: "public", Table: "users", Columns: []string{"email"}}}, nil
+}
+
+func TestRefreshCompletions_UniqueKeys(t *testing.T) {
+	mock := &uniqueKeyMockExecutor{newRelationsMock()}
+	app := NewApp(PostgreSQL, mock, mock, config.DefaultPGConfig())
+	app.RefreshCompletions()
+
+	text := "INSERT INTO users (email) VALUES ('a@b.c') ON CONFLICT ("
+	suggestions := app.Complete(text, len(text))
+	if len(suggestions) != 1 || suggestions[0].Text != "email" {
+		t.Errorf("expected the unique key, got %v", suggestions)
+	}
+}
+
+func TestRefreshCompletions_SpecialCommandArgs(t *testing.T) {
+	mock := newRelationsMock()
+	app := NewApp(PostgreSQL, mock, mock, config.DefaultPGConfig())
+	app.special.Favorites["daily"] = "SELECT 1"
+	app.RefreshCompletions()
+
+	texts := make(map[string]bool)
+	for _, s := range app.Complete(`\d act`, 6) {
+		texts[s.Text] = true
+	}
+	if !texts["active_users"] {
+		t.Errorf("\\d should complete relations, got %v", texts)
+	}
+	texts = make(map[string]bool)
+	for _, s := range app.Complete(`\f da`, 5) {
+		texts[s.Text] = true
+	}
+	if !texts["daily"] {
+		t.Errorf("\\f should complete named queries, got %v", texts)
+	}
+}
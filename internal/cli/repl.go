@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	goprompt "github.com/c-bata/go-prompt"
+)
+
+// Run reads and executes input until the user quits or input ends. A
+// terminal gets the full prompt with completion and key bindings; piped
+// input is read line by line. Usage counts are saved when it returns.
+func (a *App) Run() {
+	defer a.saveUsage()
+	if f, ok := a.Stdin.(*os.File); ok && isTerminal(f) {
+		a.runPrompt()
+		return
+	}
+	a.runBasic()
+}
+
+func (a *App) runPrompt() {
+	completer := func(d goprompt.Document) []goprompt.Suggest {
+		text := d.TextBeforeCursor()
+		suggestions := a.Complete(text, len(text))
+		s := make([]goprompt.Suggest, 0, len(suggestions))
+		for _, sg := range suggestions {
+			s = append(s, goprompt.Suggest{
+				Text:        sg.Text,
+				Description: sg.Description,
+			})
+		}
+		return s
+	}
+
+	shouldQuit := false
+	keys := a.Keys()
+	// go-prompt leaves raw mode while a line runs, so answers can be read
+	// as a plain line
+	a.confirmInput = func() string { return readLine(a.Stdin) }
+
+	executor := func(input string) {
+		if keys.Execute(input) {
+			shouldQuit = true
+		}
+	}
+
+	opts := []goprompt.Option{
+		goprompt.OptionPrefix(a.GetPrompt()),
+		goprompt.OptionLivePrefix(func() (string, bool) {
+			return a.LivePrompt(), true
+		}),
+		goprompt.OptionTitle("gocli"),
+		goprompt.OptionPrefixTextColor(goprompt.Cyan),
+		goprompt.OptionSuggestionBGColor(goprompt.DarkGray),
+		goprompt.OptionSuggestionTextColor(goprompt.White),
+		goprompt.OptionSelectedSuggestionBGColor(goprompt.Blue),
+		goprompt.OptionSelectedSuggestionTextColor(goprompt.White),
+		goprompt.OptionDescriptionBGColor(goprompt.DarkGray),
+		goprompt.OptionDescriptionTextColor(goprompt.LightGray),
+		goprompt.OptionSelectedDescriptionBGColor(goprompt.Blue),
+		goprompt.OptionSelectedDescriptionTextColor(goprompt.White),
+		goprompt.OptionMaxSuggestion(10),
+		goprompt.OptionCompletionOnDown(),
+		goprompt.OptionSetExitCheckerOnInput(func(in string, breakline bool) bool {
+			return shouldQuit
+		}),
+	}
+	p := goprompt.New(executor, completer, append(opts, keys.Options()...)...)
+	p.Run()
+}
+
+func (a *App) runBasic() {
+	scanner := bufio.NewScanner(a.Stdin)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for {
+		fmt.Fprint(a.Stdout, a.LivePrompt())
+
+		if !scanner.Scan() {
+			// Run whatever is still buffered, as safe mode needs
+			a.SubmitInput("")
+			return
+		}
+
+		if shouldQuit := a.HandleInput(scanner.Text()); shouldQuit {
+			return
+		}
+	}
+}
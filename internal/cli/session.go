@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tomblomfield/gocli/internal/classify"
+	"github.com/tomblomfield/gocli/internal/format"
+	"github.com/tomblomfield/gocli/internal/statement"
+)
+
+// Connector opens a new connection to database for \c and \r, and to
+// reconnect after the connection is lost. The returned executor usually
+// also implements MetadataProvider.
+type Connector func(ctx context.Context, database string) (Executor, error)
+
+// ConnectionChecker is implemented by executors that can tell errors
+// caused by a broken connection to the server from other errors.
+type ConnectionChecker interface {
+	IsConnectionLost(err error) bool
+}
+
+// SetConnector installs the function used to reconnect or switch
+// databases. Without one, \c and \r report an error.
+func (a *App) SetConnector(c Connector) {
+	a.connector = c
+}
+
+// installHooks lets special commands reach the app.
+func (a *App) installHooks() {
+	a.special.Hooks.RefreshCompletions = a.refreshInBackground
+	a.special.Hooks.Connect = a.connect
+	a.special.Hooks.SetOutput = a.setOutput
+	a.special.Hooks.Tee = a.setTee
+	a.special.Hooks.RunScript = a.runScript
+	a.special.Hooks.RunQuery = a.runConfirmed
+	a.special.Hooks.SetPrompt = func(format string) {
+		// Arguments arrive trimmed; keep the space prompts end with
+		a.config.Prompt = format + " "
+	}
+}
+
+// connect replaces the executor with a new connection to database, or to
+// the current database when it is empty, and reloads completions for it.
+func (a *App) connect(ctx context.Context, database string) (string, error) {
+	if err := a.reopen(ctx, database); err != nil {
+		return "", err
+	}
+	a.LoadCompletionCache()
+	a.refreshInBackground()
+	return a.executor.Database(), nil
+}
+
+// reopen replaces the executor with a new connection to database, or to
+// the current database when it is empty, and restores the session on it.
+func (a *App) reopen(ctx context.Context, database string) error {
+	if a.connector == nil {
+		return errors.New("reconnecting is not supported in this session")
+	}
+	if database == "" {
+		database = a.executor.Database()
+	}
+	executor, err := a.connector(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	// Wait for any refresh still using the old connection
+	a.refreshMu.Lock()
+	old := a.executor
+	a.executor = executor
+	if meta, ok := executor.(MetadataProvider); ok {
+		a.meta = meta
+	}
+	a.refreshMu.Unlock()
+	old.Close()
+	a.inTransaction = false
+	a.detectDialect()
+	a.auditInitCommand()
+	return nil
+}
+
+// auditInitCommand records the init command in the audit log when the
+// session connects. The connector runs it on each new connection itself,
+// so settings it makes hold on every pooled connection and survive \c,
+// \r and reconnects.
+func (a *App) auditInitCommand() {
+	if a.audit != nil && a.config.InitCommand != "" {
+		a.recordAudit(a.config.InitCommand, time.Now(), nil, nil)
+	}
+}
+
+// noteTransaction tracks whether query, which has run, left an explicit
+// transaction open. A COMMIT or ROLLBACK ends it even when it fails.
+func (a *App) noteTransaction(query string, err error) {
+	actions := classify.Actions(query)
+	if len(actions) == 0 {
+		return
+	}
+	lower := strings.ToLower(query)
+	switch actions[0].Verb {
+	case "begin":
+		a.inTransaction = a.inTransaction || err == nil
+	case "start":
+		if strings.Contains(lower, "transaction") {
+			a.inTransaction = a.inTransaction || err == nil
+		}
+	case "commit", "rollback", "end", "abort":
+		// ROLLBACK TO SAVEPOINT and AND CHAIN keep a transaction open
+		if !strings.Contains(lower, " to ") && !strings.Contains(lower, "and chain") {
+			a.inTransaction = false
+		}
+	}
+}
+
+// retrySafe are the verbs of statements that cannot change anything, so
+// one interrupted by a lost connection can run again. SELECT is not one:
+// the functions it calls may write.
+var retrySafe = map[string]bool{"show": true, "describe": true, "desc": true, "explain": true}
+
+// canRetry reports whether query can safely run again after a lost
+// connection. EXPLAIN ANALYZE runs its statement, so it has more than
+// one action.
+func canRetry(query string) bool {
+	actions := classify.Actions(query)
+	return len(actions) == 1 && retrySafe[actions[0].Verb]
+}
+
+// reconnectAfter reconnects when err shows the connection to the server
+// was lost. A transaction open on the old connection is gone, so the user
+// is told it was rolled back. Only a query that cannot change anything is
+// rerun on the new connection; any other may have taken effect.
+func (a *App) reconnectAfter(ctx context.Context, query string, err error) (*format.QueryResult, error) {
+	var timeout *timeoutError
+	checker, ok := a.executor.(ConnectionChecker)
+	if !ok || a.connector == nil || ctx.Err() != nil || errors.As(err, &timeout) || !checker.IsConnectionLost(err) {
+		return nil, err
+	}
+
+	fmt.Fprintf(a.Stderr, "Connection lost: %s\n", err)
+	inTransaction := a.inTransaction
+	if rerr := a.reopen(ctx, ""); rerr != nil {
+		fmt.Fprintf(a.Stderr, "Reconnect failed: %s\n", rerr)
+		return nil, err
+	}
+	fmt.Fprintf(a.Stderr, "Reconnected to %s.\n", a.executor.Database())
+	switch {
+	case inTransaction:
+		return nil, fmt.Errorf("%w; the open transaction was rolled back and the statement was not retried", err)
+	case !canRetry(query):
+		return nil, fmt.Errorf("%w; the statement was not retried and may or may not have run", err)
+	}
+	return a.executeWithTimeout(ctx, query)
+}
+
+// runScript executes the statements and commands in file, split with
+// the session's delimiter. DELIMITER lines in the file apply until its
+// end, as they would if typed. Destructive statements are confirmed
+// together before any of the file runs.
+func (a *App) runScript(_ context.Context, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	stmts := statement.Split(string(data), a.syntax())
+	if !a.confirmDestructive(stmts) {
+		return fmt.Errorf("%s: %w", file, errNotRun)
+	}
+	summary := newRunSummary()
+	failed := a.executeStatements(stmts, summary, a.special.Timing)
+	fmt.Fprintln(a.Stdout, summary)
+	if failed {
+		return fmt.Errorf("%s: not all statements succeeded", file)
+	}
+	return nil
+}
+
+// setOutput sends results to a file or, for "|command", a pipe until it
+// is called again. An empty target restores stdout.
+func (a *App) setOutput(target string) error {
+	var w io.WriteCloser
+	switch {
+	case target == "":
+	case strings.HasPrefix(target, "|"):
+		pw, err := a.startPipe(strings.TrimSpace(target[1:]))
+		if err != nil {
+			return err
+		}
+		w = pw
+	default:
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		w = f
+	}
+	if a.output != nil {
+		a.output.Close()
+	}
+	a.output = w
+	return nil
+}
+
+// setTee copies results to file as well as the normal output, appending
+// unless overwrite is set. An empty file stops copying.
+func (a *App) setTee(file string, overwrite bool) error {
+	var w io.WriteCloser
+	if file != "" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if overwrite {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		}
+		f, err := os.OpenFile(file, flags, 0644)
+		if err != nil {
+			return err
+		}
+		w = f
+	}
+	if a.tee != nil {
+		a.tee.Close()
+	}
+	a.tee = w
+	return nil
+}
@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tomblomfield/gocli/internal/config"
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+func TestHandleInput_OutputToFile(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+	app.config.EnablePager = false
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	app.HandleInput(`\o ` + path)
+	buf.Reset()
+	app.HandleInput("SELECT 1")
+	app.HandleInput(`\o`)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "result") {
+		t.Errorf("results should go to the file, got %q", data)
+	}
+	if strings.Contains(buf.String(), "result") {
+		t.Errorf("results should not be shown while \\o is set, got %q", buf.String())
+	}
+
+	buf.Reset()
+	app.HandleInput("SELECT 1")
+	if !strings.Contains(buf.String(), "result") {
+		t.Errorf("\\o without argument should restore stdout, got %q", buf.String())
+	}
+}
+
+func TestHandleInput_Tee(t *testing.T) {
+	app, buf := newTestApp(MySQL)
+	app.config.EnablePager = false
+	path := filepath.Join(t.TempDir(), "tee.txt")
+	os.WriteFile(path, []byte("earlier\n"), 0644)
+
+	app.HandleInput("tee " + path)
+	buf.Reset()
+	app.HandleInput("SELECT 1")
+	app.HandleInput("notee")
+	app.HandleInput("SELECT 2")
+
+	if !strings.Contains(buf.String(), "result") {
+		t.Errorf("tee should keep showing results, got %q", buf.String())
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "earlier\n") {
+		t.Errorf("tee should append, got %q", data)
+	}
+	if strings.Count(string(data), "(1 row)") != 1 {
+		t.Errorf("only results before notee should be written, got %q", data)
+	}
+}
+
+func TestHandleInput_Connect(t *testing.T) {
+	app, buf := newTestApp(PostgreSQL)
+
+	app.HandleInput(`\c other`)
+	if !strings.Contains(buf.String(), "not supported") {
+		t.Errorf("expected error without a connector, got %q", buf.String())
+	}
+
+	var opened []string
+	app.SetConnector(func(_ context.Context, database string) (Executor, error) {
+		if database == "missing" {
+			return nil, fmt.Errorf(`database "missing" does not exist`)
+		}
+		opened = append(opened, database)
+		return &mockExecutor{database: database}, nil
+	})
+
+	buf.Reset()
+	app.HandleInput(`\c other`)
+	app.refreshWG.Wait()
+	if app.executor.Database() != "other" {
+		t.Errorf("executor should be swapped, database is %q", app.executor.Database())
+	}
+	if !strings.Contains(buf.String(), `connected to database "other"`) {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	app.HandleInput(`\c missing`)
+	if app.executor.Database() != "other" || !strings.Contains(buf.String(), "does not exist") {
+		t.Errorf("failed connections should keep the current one, got %q", buf.String())
+	}
+
+	app.HandleInput(`\c`)
+	app.refreshWG.Wait()
+	if len(opened) != 2 || opened[1] != "other" {
+		t.Errorf("\\c without database should reconnect to the current one, opened %v", opened)
+	}
+}
+
+func TestHandleInput_RefreshCommand(t *testing.T) {
+	mock := newRelationsMock()
+	app, buf := newTestApp(PostgreSQL)
+	app.executor, app.meta = mock, mock
+
+	app.HandleInput(`\#`)
+	app.refreshWG.Wait()
+	if n := mock.refreshes.Load(); n != 1 {
+		t.Errorf("\\# should refresh completions, got %d refreshes", n)
+	}
+	if !strings.Contains(buf.String(), "refresh started") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestHandleInput_PromptCommand(t *testing.T) {
+	app, _ := newTestApp(MySQL)
+	app.HandleInput(`\R \d>`)
+	if got := app.GetPrompt(); got != "testdb> " {
+		t.Errorf("prompt = %q", got)
+	}
+}
+
+func TestHandleInput_SourceScript(t *testing.T) {
+	app, mock := newMultiLineApp(MySQL)
+	path := filepath.Join(t.TempDir(), "procs.sql")
+	script := "DELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND //\nDELIMITER ;\nCALL p();\n"
+	os.WriteFile(path, []byte(script), 0644)
+
+	app.HandleInput(`\. ` + path)
+	if len(mock.queries) != 2 || mock.queries[0] != "CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND" {
+		t.Errorf("unexpected queries %q", mock.queries)
+	}
+	if app.special.Delimiter != "" {
+		t.Errorf("a script should not change the session delimiter, got %q", app.special.Delimiter)
+	}
+}
+
+var errConnLost = errors.New("unexpected EOF")
+
+// droppingExecutor records its statements and fails them all once its
+// connection has dropped.
+type droppingExecutor struct {
+	recordingExecutor
+	dropped bool
+}
+
+func (m *droppingExecutor) Execute(ctx context.Context, query string) (*format.QueryResult, error) {
+	if m.dropped {
+		return nil, errConnLost
+	}
+	return m.recordingExecutor.Execute(ctx, query)
+}
+
+func (m *droppingExecutor) IsConnectionLost(err error) bool { return err == errConnLost }
+
+func newReconnectApp(t *testing.T) (*App, *bytes.Buffer, *[]*droppingExecutor) {
+	t.Helper()
+	first := &droppingExecutor{recordingExecutor: recordingExecutor{mockExecutor: mockExecutor{database: "shop"}}}
+	cfg := config.DefaultPGConfig()
+	cfg.LessChatty = true
+	app := NewApp(PostgreSQL, first, first, cfg)
+	var buf bytes.Buffer
+	app.Stdout = &buf
+	app.Stderr = &buf
+
+	executors := []*droppingExecutor{first}
+	app.SetConnector(func(_ context.Context, database string) (Executor, error) {
+		e := &droppingExecutor{recordingExecutor: recordingExecutor{mockExecutor: mockExecutor{database: database}}}
+		executors = append(executors, e)
+		return e, nil
+	})
+	return app, &buf, &executors
+}
+
+func TestReconnect_RerunsSafeStatements(t *testing.T) {
+	app, buf, executors := newReconnectApp(t)
+	(*executors)[0].dropped = true
+
+	if app.ExecuteNonInteractive("SHOW search_path") {
+		t.Errorf("a statement that cannot write should succeed after reconnecting, got %q", buf.String())
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Connection lost: unexpected EOF") || !strings.Contains(out, "Reconnected to shop.") {
+		t.Errorf("the user should be told about the reconnect, got %q", out)
+	}
+	if len(*executors) != 2 || app.executor != (*executors)[1] {
+		t.Fatalf("the session should move to a new connection")
+	}
+	if got := (*executors)[1].queries; len(got) != 1 || got[0] != "SHOW search_path" {
+		t.Errorf("the statement should be rerun, got %q", got)
+	}
+}
+
+func TestReconnect_DoesNotRerunOthers(t *testing.T) {
+	// A SELECT may call a function that writes
+	for _, query := range []string{"UPDATE users SET active = false WHERE id = 1", "SELECT archive_orders()", "EXPLAIN ANALYZE SELECT 1"} {
+		app, buf, executors := newReconnectApp(t)
+		(*executors)[0].dropped = true
+
+		if !app.ExecuteNonInteractive(query) {
+			t.Errorf("%q interrupted by a lost connection should fail", query)
+		}
+		if !strings.Contains(buf.String(), "Reconnected to shop.") || !strings.Contains(buf.String(), "was not retried") {
+			t.Errorf("the user should be told %q was not retried, got %q", query, buf.String())
+		}
+		if got := (*executors)[1].queries; len(got) != 0 {
+			t.Errorf("nothing should run on the new connection, got %q", got)
+		}
+
+		buf.Reset()
+		if app.ExecuteNonInteractive(query) {
+			t.Errorf("%q should work once rerun by the user, got %q", query, buf.String())
+		}
+	}
+}
+
+func TestReconnect_LostTransaction(t *testing.T) {
+	app, buf, executors := newReconnectApp(t)
+	app.ExecuteNonInteractive("BEGIN")
+	app.ExecuteNonInteractive("UPDATE users SET active = false WHERE id = 1")
+	(*executors)[0].dropped = true
+
+	if !app.ExecuteNonInteractive("SHOW search_path") {
+		t.Error("a statement in a lost transaction should fail")
+	}
+	if !strings.Contains(buf.String(), "the open transaction was rolled back") {
+		t.Errorf("the user should be told the transaction was lost, got %q", buf.String())
+	}
+	if got := (*executors)[1].queries; len(got) != 0 {
+		t.Errorf("nothing should run outside the transaction, got %q", got)
+	}
+
+	// The new connection is in autocommit, and the session knows it
+	buf.Reset()
+	(*executors)[1].dropped = true
+	app.ExecuteNonInteractive("SHOW search_path")
+	if strings.Contains(buf.String(), "rolled back") {
+		t.Errorf("no transaction should be open after the reconnect, got %q", buf.String())
+	}
+}
+
+func TestNoteTransaction(t *testing.T) {
+	app, _ := newTestApp(PostgreSQL)
+	steps := []struct {
+		query string
+		err   error
+		open  bool
+	}{
+		{"START TRANSACTION", nil, true},
+		{"SAVEPOINT a", nil, true},
+		{"ROLLBACK TO SAVEPOINT a", nil, true},
+		{"COMMIT", errors.New("deferred constraint"), false},
+		{"BEGIN", errors.New("failed"), false},
+		{"-- again\nbegin work", nil, true},
+		{"COMMIT AND CHAIN", nil, true},
+		{"rollback", nil, false},
+		{"START REPLICA", nil, false},
+	}
+	for _, s := range steps {
+		app.noteTransaction(s.query, s.err)
+		if app.inTransaction != s.open {
+			t.Errorf("after %q a transaction should be open: %v", s.query, s.open)
+		}
+	}
+}
+
+func TestReconnect_Fails(t *testing.T) {
+	app, buf, executors := newReconnectApp(t)
+	(*executors)[0].dropped = true
+	app.SetConnector(func(context.Context, string) (Executor, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	if !app.ExecuteNonInteractive("SELECT 1") {
+		t.Error("the statement should fail while the server is down")
+	}
+	if out := buf.String(); !strings.Contains(out, "Reconnect failed: connection refused") || !strings.Contains(out, "Error: unexpected EOF") {
+		t.Errorf("the failed reconnect should be reported, got %q", out)
+	}
+	if app.executor != (*executors)[0] {
+		t.Error("the old executor should be kept until a reconnect succeeds")
+	}
+}
+
+func TestReconnect_MySQLCommand(t *testing.T) {
+	first := &droppingExecutor{recordingExecutor: recordingExecutor{mockExecutor: mockExecutor{database: "shop"}}}
+	cfg := config.DefaultMySQLConfig()
+	cfg.LessChatty = true
+	app := NewApp(MySQL, first, first, cfg)
+	var buf bytes.Buffer
+	app.Stdout = &buf
+	app.Stderr = &buf
+	var second *droppingExecutor
+	app.SetConnector(func(_ context.Context, database string) (Executor, error) {
+		second = &droppingExecutor{recordingExecutor: recordingExecutor{mockExecutor: mockExecutor{database: database}}}
+		return second, nil
+	})
+
+	app.HandleInput(`\r`)
+	app.refreshWG.Wait()
+	if !strings.Contains(buf.String(), "Reconnected to: shop") || app.executor != second {
+		t.Fatalf("\\r should reconnect to the current database, got %q", buf.String())
+	}
+}
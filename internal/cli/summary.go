@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tomblomfield/gocli/internal/format"
+	"github.com/tomblomfield/gocli/internal/special"
+)
+
+// statementTiming reports how long a statement took, split between the
+// server, fetching its rows and rendering them.
+func statementTiming(result *format.QueryResult, render time.Duration) string {
+	total := result.ServerTime + result.FetchTime + render
+	return fmt.Sprintf("%s (server %s, fetch %s, render %s)", special.FormatTiming(total),
+		formatDuration(result.ServerTime), formatDuration(result.FetchTime), formatDuration(render))
+}
+
+// formatDuration formats d as FormatTiming does, without its label.
+func formatDuration(d time.Duration) string {
+	return special.FormatTiming(d)[len("Time: "):]
+}
+
+// runSummary tallies the statements run from one input or script.
+type runSummary struct {
+	start time.Time
+
+	Statements   int `json:"statements"`
+	Failed       int `json:"failed"`
+	TimedOut     int `json:"timed_out"`
+	RowsAffected int `json:"rows_affected"`
+	RowsReturned int `json:"rows_returned"`
+}
+
+func newRunSummary() *runSummary {
+	return &runSummary{start: time.Now()}
+}
+
+// add counts a statement and its outcome.
+func (s *runSummary) add(result *format.QueryResult, err error) {
+	s.Statements++
+	switch {
+	case err != nil:
+		s.Failed++
+		var timeout *timeoutError
+		if errors.As(err, &timeout) {
+			s.TimedOut++
+		}
+	case result == nil:
+	case len(result.Columns) > 0:
+		s.RowsReturned += result.RowCount
+	default:
+		s.RowsAffected += result.RowCount
+	}
+}
+
+// String summarizes the run for people, such as "3 statements, 1 failed,
+// 5 rows affected, 2 rows returned. Time: 1.234ms".
+func (s *runSummary) String() string {
+	failed := strconv.Itoa(s.Failed)
+	if s.TimedOut > 0 {
+		failed += fmt.Sprintf(" (%d timed out)", s.TimedOut)
+	}
+	return fmt.Sprintf("%d statement%s, %s failed, %d row%s affected, %d row%s returned. %s",
+		s.Statements, pluralS(s.Statements), failed,
+		s.RowsAffected, pluralS(s.RowsAffected), s.RowsReturned, pluralS(s.RowsReturned),
+		special.FormatTiming(time.Since(s.start)))
+}
+
+// JSON summarizes the run as one line of JSON for scripts.
+func (s *runSummary) JSON() string {
+	data, _ := json.Marshal(struct {
+		*runSummary
+		TotalMS float64 `json:"total_ms"`
+	}{s, float64(time.Since(s.start).Microseconds()) / 1000})
+	return string(data)
+}
+
+func pluralS(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
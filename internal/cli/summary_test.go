@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+func TestStatementTiming(t *testing.T) {
+	result := &format.QueryResult{ServerTime: 2 * time.Millisecond, FetchTime: 500 * time.Microsecond}
+	got := statementTiming(result, 1500*time.Microsecond)
+	want := "Time: 4.000ms (server 2.000ms, fetch 0.500ms, render 1.500ms)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	s := newRunSummary()
+	s.add(&format.QueryResult{StatusText: "UPDATE 3", RowCount: 3}, nil)
+	s.add(&format.QueryResult{Columns: []string{"id"}, RowCount: 1}, nil)
+	s.add(nil, errors.New("syntax error"))
+	s.add(nil, nil) // a special command
+
+	if got := s.String(); !strings.HasPrefix(got, "4 statements, 1 failed, 3 rows affected, 1 row returned. Time: ") {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	var parsed map[string]float64
+	if err := json.Unmarshal([]byte(s.JSON()), &parsed); err != nil {
+		t.Fatalf("the JSON summary should parse: %v", err)
+	}
+	if parsed["statements"] != 4 || parsed["failed"] != 1 || parsed["rows_affected"] != 3 || parsed["rows_returned"] != 1 {
+		t.Errorf("unexpected JSON summary %s", s.JSON())
+	}
+	if _, ok := parsed["total_ms"]; !ok {
+		t.Errorf("the JSON summary should have the total time, got %s", s.JSON())
+	}
+}
+
+func TestExecuteSQL_TimingAndSummary(t *testing.T) {
+	app, mock := newMultiLineApp(PostgreSQL)
+	out := app.Stdout.(*bytes.Buffer)
+	app.special.Timing = true
+	mock.results = []*format.QueryResult{
+		{StatusText: "UPDATE 3", RowCount: 3, ServerTime: time.Millisecond},
+		{Columns: []string{"n"}, Rows: [][]string{{"1"}}, RowCount: 1},
+	}
+
+	app.HandleInput("UPDATE t SET a = 1 WHERE id > 1; SELECT 1;")
+	if n := strings.Count(out.String(), "(server "); n != 2 {
+		t.Errorf("each statement should show its timing, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "2 statements, 0 failed, 3 rows affected, 1 row returned. Time: ") {
+		t.Errorf("multi-statement input should end with a summary, got %q", out.String())
+	}
+
+	out.Reset()
+	app.HandleInput("SELECT 1;")
+	if strings.Contains(out.String(), "statement") {
+		t.Errorf("a single statement needs no summary, got %q", out.String())
+	}
+
+	out.Reset()
+	app.special.Timing = false
+	app.HandleInput("SELECT 1; SELECT 2;")
+	if strings.Contains(out.String(), "(server ") || !strings.Contains(out.String(), "2 statements") {
+		t.Errorf("without timing only the summary should show, got %q", out.String())
+	}
+}
+
+func TestRunScript_Summary(t *testing.T) {
+	app, _ := newMultiLineApp(PostgreSQL)
+	out := app.Stdout.(*bytes.Buffer)
+	path := filepath.Join(t.TempDir(), "seed.sql")
+	os.WriteFile(path, []byte("SELECT 1;\nSELECT 2;\n"), 0644)
+
+	app.HandleInput(`\i ` + path)
+	if !strings.Contains(out.String(), "2 statements, 0 failed, 0 rows affected, 2 rows returned.") {
+		t.Errorf("a script should end with a summary, got %q", out.String())
+	}
+}
+
+func TestExecuteNonInteractive_JSONSummary(t *testing.T) {
+	app, out := newTestApp(PostgreSQL)
+	var summary bytes.Buffer
+	app.SetSummaryOutput(&summary)
+
+	app.ExecuteNonInteractive("SELECT 1; SELECT 2")
+	if strings.Contains(out.String(), "statements") {
+		t.Errorf("-e output should not change, got %q", out.String())
+	}
+	var parsed map[string]float64
+	if err := json.Unmarshal(summary.Bytes(), &parsed); err != nil || parsed["statements"] != 2 || parsed["rows_returned"] != 2 {
+		t.Errorf("unexpected JSON summary %q: %v", summary.String(), err)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+// TimeoutSetter is implemented by executors that can have the server
+// stop statements running longer than statement, or waiting longer than
+// lock for a lock. Zero turns a timeout off.
+type TimeoutSetter interface {
+	SetTimeouts(statement, lock time.Duration)
+}
+
+// TimeoutChecker is implemented by executors that can tell a statement
+// the server stopped for taking too long from other errors.
+type TimeoutChecker interface {
+	IsTimeout(err error) bool
+}
+
+// timeoutGrace is how much longer than the statement timeout the client
+// waits, so the server normally stops the statement and reports it first.
+var timeoutGrace = time.Second
+
+// timeoutError is a statement stopped by the statement or lock timeout.
+type timeoutError struct {
+	err     error
+	timeout time.Duration // set when the client deadline stopped it
+	reopen  string        // what became of the connection the client gave up on
+}
+
+func (e *timeoutError) Error() string {
+	if e.timeout > 0 {
+		return fmt.Sprintf("statement did not finish within %s%s", e.timeout, e.reopen)
+	}
+	return e.err.Error()
+}
+
+func (e *timeoutError) Unwrap() error { return e.err }
+
+// executeWithTimeout runs query with the session's timeouts: the server
+// enforces them where the executor supports it, and the client gives up
+// shortly after the statement timeout in case the server does not.
+func (a *App) executeWithTimeout(ctx context.Context, query string) (*format.QueryResult, error) {
+	timeout, lock := a.special.Timeout, a.special.LockTimeout
+	if s, ok := a.executor.(TimeoutSetter); ok {
+		s.SetTimeouts(timeout, lock)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout+timeoutGrace)
+		defer cancel()
+	}
+
+	result, err := a.executor.Execute(ctx, query)
+	switch {
+	case err == nil:
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil:
+		err = &timeoutError{err: err, timeout: timeout, reopen: a.reopenAfterTimeout()}
+	default:
+		if c, ok := a.executor.(TimeoutChecker); ok && c.IsTimeout(err) {
+			err = &timeoutError{err: err}
+		}
+	}
+	return result, err
+}
+
+// reopenAfterTimeout replaces the connection the client gave up on, which
+// the driver closes in the middle of the statement, so the next statement
+// does not fail on it. It returns how to tell the user.
+func (a *App) reopenAfterTimeout() string {
+	if a.connector == nil {
+		return ""
+	}
+	inTransaction := a.inTransaction
+	if err := a.reopen(context.Background(), ""); err != nil {
+		return fmt.Sprintf("; reconnecting failed: %s", err)
+	}
+	if inTransaction {
+		return "; the connection was reopened and the open transaction rolled back"
+	}
+	return "; the connection was reopened"
+}
+
+// reportError prints err, marking statements stopped by a timeout so they
+// stand out from failures.
+func (a *App) reportError(err error) {
+	var timeout *timeoutError
+	if errors.As(err, &timeout) {
+		fmt.Fprintf(a.Stderr, "Timeout: %s\n", err)
+		return
+	}
+	fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+}
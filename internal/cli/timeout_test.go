@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomblomfield/gocli/internal/config"
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+var errServerTimeout = errors.New("canceling statement due to statement timeout")
+
+// timeoutExecutor records the timeouts it is given, and either fails with
+// a server timeout or blocks until the client gives up.
+type timeoutExecutor struct {
+	mockExecutor
+	statement, lock time.Duration
+	block           bool
+}
+
+func (e *timeoutExecutor) SetTimeouts(statement, lock time.Duration) {
+	e.statement, e.lock = statement, lock
+}
+
+func (e *timeoutExecutor) IsTimeout(err error) bool { return err == errServerTimeout }
+
+func (e *timeoutExecutor) Execute(ctx context.Context, query string) (*format.QueryResult, error) {
+	if e.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return e.mockExecutor.Execute(ctx, query)
+}
+
+func newTimeoutApp(cfg *config.Config) (*App, *timeoutExecutor, *strings.Builder) {
+	cfg.LessChatty = true
+	mock := &timeoutExecutor{mockExecutor: mockExecutor{database: "testdb"}}
+	app := NewApp(PostgreSQL, mock, mock, cfg)
+	var buf strings.Builder
+	app.Stdout = &buf
+	app.Stderr = &buf
+	return app, mock, &buf
+}
+
+func TestTimeout_SetsServerTimeouts(t *testing.T) {
+	cfg := config.DefaultPGConfig()
+	cfg.StatementTimeout = 30 * time.Second
+	cfg.LockTimeout = 5 * time.Second
+	app, mock, _ := newTimeoutApp(cfg)
+
+	app.ExecuteNonInteractive("SELECT 1")
+	if mock.statement != 30*time.Second || mock.lock != 5*time.Second {
+		t.Errorf("the config's timeouts should reach the executor, got %v, %v", mock.statement, mock.lock)
+	}
+
+	app.ExecuteNonInteractive(`\timeout off`)
+	app.ExecuteNonInteractive("SELECT 1")
+	if mock.statement != 0 || mock.lock != 5*time.Second {
+		t.Errorf("\\timeout off should clear the statement timeout, got %v, %v", mock.statement, mock.lock)
+	}
+}
+
+func TestTimeout_ClientDeadline(t *testing.T) {
+	defer func(grace time.Duration) { timeoutGrace = grace }(timeoutGrace)
+	timeoutGrace = 0
+
+	app, mock, buf := newTimeoutApp(config.DefaultPGConfig())
+	mock.block = true
+	app.ExecuteNonInteractive(`\timeout 10ms`)
+	buf.Reset()
+
+	if !app.ExecuteNonInteractive("SELECT pg_sleep(60)") {
+		t.Error("a timed out statement should fail")
+	}
+	if got := buf.String(); got != "Timeout: statement did not finish within 10ms\n" {
+		t.Errorf("the client deadline should be reported as a timeout, got %q", got)
+	}
+}
+
+func TestTimeout_ClientDeadlineReopens(t *testing.T) {
+	defer func(grace time.Duration) { timeoutGrace = grace }(timeoutGrace)
+	timeoutGrace = 0
+
+	app, mock, buf := newTimeoutApp(config.DefaultPGConfig())
+	fresh := &mockExecutor{database: "testdb"}
+	app.SetConnector(func(context.Context, string) (Executor, error) { return fresh, nil })
+	app.ExecuteNonInteractive(`\timeout 10ms`)
+	app.ExecuteNonInteractive("BEGIN")
+	mock.block = true
+	buf.Reset()
+
+	app.ExecuteNonInteractive("UPDATE accounts SET balance = 0")
+	want := "Timeout: statement did not finish within 10ms; the connection was reopened and the open transaction rolled back\n"
+	if got := buf.String(); got != want {
+		t.Errorf("the abandoned connection should be replaced, got %q", got)
+	}
+	if app.executor != fresh {
+		t.Error("the next statement should run on the new connection")
+	}
+}
+
+func TestTimeout_ServerError(t *testing.T) {
+	app, mock, buf := newTimeoutApp(config.DefaultPGConfig())
+	mock.err = errServerTimeout
+	summary := newRunSummary()
+
+	app.executeStatements([]string{"SELECT pg_sleep(60)"}, summary, false)
+	if got := buf.String(); got != "Timeout: canceling statement due to statement timeout\n" {
+		t.Errorf("server timeouts should be reported as timeouts, got %q", got)
+	}
+	if summary.Failed != 1 || summary.TimedOut != 1 {
+		t.Errorf("the summary should count the timeout, got %+v", summary)
+	}
+
+	buf.Reset()
+	mock.err = errors.New(`relation "users" does not exist`)
+	app.executeStatements([]string{"SELECT * FROM users"}, summary, false)
+	if got := buf.String(); !strings.HasPrefix(got, "Error: ") {
+		t.Errorf("other errors should be reported as errors, got %q", got)
+	}
+	if summary.TimedOut != 1 {
+		t.Errorf("other errors should not count as timeouts, got %+v", summary)
+	}
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/tomblomfield/gocli/internal/completion"
+)
+
+// usageFile returns where usage counts are kept: next to the history
+// file.
+func (a *App) usageFile() string {
+	if a.config.HistoryFile == "" {
+		return ""
+	}
+	return a.config.HistoryFile + ".usage"
+}
+
+// LoadUsage loads the usage counts saved by earlier sessions, which rank
+// completions. Run saves the counts back there when the session ends.
+func (a *App) LoadUsage() {
+	path := a.usageFile()
+	if path == "" {
+		return
+	}
+	usage, err := completion.LoadUsage(path)
+	if err != nil {
+		fmt.Fprintf(a.Stderr, "Warning: could not read %s: %s\n", path, err)
+	}
+	a.usage = usage
+	a.usagePath = path
+	a.comp.SetUsage(usage)
+}
+
+// saveUsage persists the usage counts when LoadUsage enabled it.
+func (a *App) saveUsage() {
+	if a.usagePath == "" {
+		return
+	}
+	a.usage.Save(a.usagePath)
+}
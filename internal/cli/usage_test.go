@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tomblomfield/gocli/internal/completion"
+)
+
+func TestLoadUsage_PersistsCounts(t *testing.T) {
+	app, _ := newTestApp(PostgreSQL)
+	app.config.EnablePager = false
+	app.config.HistoryFile = filepath.Join(t.TempDir(), "history")
+
+	app.comp.UpdateMetadata(invoiceMetadata())
+	app.LoadUsage()
+	app.HandleInput("SELECT * FROM invoices")
+
+	path := app.config.HistoryFile + ".usage"
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("usage should not be saved after every statement")
+	}
+	app.Stdin = strings.NewReader("")
+	app.Run()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("usage should be saved next to the history file on exit: %v", err)
+	}
+
+	next, _ := newTestApp(PostgreSQL)
+	next.config.HistoryFile = app.config.HistoryFile
+	next.LoadUsage()
+	if n := next.usage.Count("invoices"); n != 1 {
+		t.Errorf("expected usage from the earlier session, got %d", n)
+	}
+}
+
+func TestExecute_RecordsUsageOnlyOnSuccess(t *testing.T) {
+	app, _ := newTestApp(PostgreSQL)
+	app.config.EnablePager = false
+	app.comp.UpdateMetadata(invoiceMetadata())
+
+	app.HandleInput("SELECT * FROM invoices")
+	app.executor.(*mockExecutor).err = errors.New("relation \"typo_table\" does not exist")
+	app.HandleInput("SELECT * FROM typo_table")
+
+	if app.usage.Count("invoices") != 1 {
+		t.Error("successful statements should be counted")
+	}
+	if app.usage.Count("typo_table") != 0 {
+		t.Error("failed statements should not be counted")
+	}
+}
+
+// invoiceMetadata has the one table the usage tests query.
+func invoiceMetadata() *completion.Metadata {
+	meta := completion.NewMetadata()
+	meta.AddTable("", "invoices", []string{"id", "total"})
+	return meta
+}
@@ -2,9 +2,13 @@
 package completion
 
 import (
+	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"unicode"
+
+	"github.com/tomblomfield/gocli/internal/dialect"
 )
 
 // SuggestionType identifies the type of completion suggestion.
@@ -22,6 +26,10 @@ const (
 	SuggestAlias
 	SuggestSpecial
 	SuggestFavorite
+	SuggestJoin
+	SuggestJoinCondition
+	SuggestHint // the signature of the function being called; not an insertion
+	SuggestFile
 )
 
 // Suggestion represents a single completion suggestion.
@@ -32,41 +40,29 @@ type Suggestion struct {
 	Description string
 }
 
-// SpecialCmd holds a special command name and its description.
+// SpecialCmd holds a special command name and its description, and what
+// its argument names so it can be completed.
 type SpecialCmd struct {
 	Name        string
 	Description string
-}
-
-// Metadata holds database schema information for completions.
-type Metadata struct {
-	Tables    []string
-	Views     []string
-	Columns   map[string][]string // table -> columns
-	Functions []string
-	Schemas   []string
-	Databases []string
-	Datatypes []string
-	Specials  []SpecialCmd
-	Favorites []string
-}
-
-// NewMetadata creates empty metadata.
-func NewMetadata() *Metadata {
-	return &Metadata{
-		Columns: make(map[string][]string),
-	}
+	Aliases     []string
+	Arg         ArgKind
+	Choices     []string // for ArgChoice; "a b" offers b after a
 }
 
 // Completer provides context-aware SQL completions.
 type Completer struct {
+	mu            sync.RWMutex // guards meta, which is swapped by background refreshes
 	meta          *Metadata
 	smart         bool
-	keywordCasing string // "upper", "lower", "auto"
+	keywordCasing string           // "upper", "lower", "auto"
+	usage         *Usage           // ranks frequently used names first; may be nil
+	dialect       *dialect.Dialect // nil offers every backend's keywords
 }
 
 // NewCompleter creates a new SQL completer.
 func NewCompleter(meta *Metadata, smart bool) *Completer {
+	meta.Index()
 	return &Completer{
 		meta:          meta,
 		smart:         smart,
@@ -84,13 +80,48 @@ func (c *Completer) SetSmart(smart bool) {
 	c.smart = smart
 }
 
+// SetUsage sets the usage counts used to rank suggestions.
+func (c *Completer) SetUsage(u *Usage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage = u
+}
+
+// RecordUsage counts the names in sql that completion suggests: known
+// relations, columns and functions, and the dialect's keywords. Aliases
+// and typos are not counted.
+func (c *Completer) RecordUsage(sql string) {
+	c.mu.RLock()
+	meta, d, usage := c.meta, c.dialect, c.usage
+	c.mu.RUnlock()
+	if usage == nil {
+		return
+	}
+	usage.Record(sql, func(name string) bool {
+		return meta.HasName(name) || d.IsKeyword(name) || d.IsFunction(name)
+	})
+}
+
+// SetDialect restricts keyword, function and datatype suggestions to
+// what d's backend and server version understand.
+func (c *Completer) SetDialect(d *dialect.Dialect) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dialect = d
+}
+
 // UpdateMetadata replaces the current metadata.
 func (c *Completer) UpdateMetadata(meta *Metadata) {
+	meta.Index()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.meta = meta
 }
 
 // Complete returns completion suggestions for the given input at the cursor position.
 func (c *Completer) Complete(text string, cursorPos int) []Suggestion {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	if cursorPos > len(text) {
 		cursorPos = len(text)
 	}
@@ -100,11 +131,18 @@ func (c *Completer) Complete(text string, cursorPos int) []Suggestion {
 	word := lastWord(textBefore)
 
 	var suggestions []Suggestion
-	if !c.smart {
+	cmd, args, isSpecial := c.specialCommand(textBefore)
+	switch {
+	case isSpecial:
+		var done []string
+		done, word = splitArgs(args)
+		suggestions = c.argumentSuggestions(cmd, done, word)
+	case !c.smart:
 		suggestions = c.allCompletions(word)
-	} else {
+	default:
 		// Parse context
 		ctx := analyzeContext(textBefore)
+		c.addVirtualTables(&ctx, textBefore)
 
 		// If after a dot, the filter word is just the part after the dot
 		if ctx.AfterDot {
@@ -116,15 +154,24 @@ func (c *Completer) Complete(text string, cursorPos int) []Suggestion {
 		suggestions = c.contextCompletions(ctx, word)
 	}
 
-	// Sort by match quality: prefix > substring > fuzzy
+	// Sort by match quality: prefix > substring > fuzzy, then by usage.
+	// With nothing typed, only usage reorders suggestions of one kind.
 	if word != "" {
-		sortByMatchQuality(suggestions, word)
+		sortByMatchQuality(suggestions, word, c.usage)
+	} else {
+		sortByUsage(suggestions, c.usage)
+	}
+
+	// Inside fn( the signature comes first
+	if hint, ok := c.parameterHint(textBefore); ok && !isSpecial {
+		hint.Text = word
+		suggestions = append([]Suggestion{hint}, suggestions...)
 	}
 
 	return suggestions
 }
 
-func sortByMatchQuality(suggestions []Suggestion, word string) {
+func sortByMatchQuality(suggestions []Suggestion, word string, usage *Usage) {
 	wordLower := strings.ToLower(word)
 	sort.SliceStable(suggestions, func(i, j int) bool {
 		si := matchScore(suggestions[i].Text, wordLower)
@@ -132,13 +179,38 @@ func sortByMatchQuality(suggestions []Suggestion, word string) {
 		if si != sj {
 			return si < sj
 		}
-		// Same score: prefer shorter names (more relevant)
+		// Same score: prefer names used more often
+		ui := usage.Count(suggestions[i].Text)
+		uj := usage.Count(suggestions[j].Text)
+		if ui != uj {
+			return ui > uj
+		}
+		// Then shorter names (more relevant)
 		ni := len(nameAfterDot(suggestions[i].Text))
 		nj := len(nameAfterDot(suggestions[j].Text))
 		return ni < nj
 	})
 }
 
+// sortByUsage moves frequently used names first within each run of
+// suggestions of the same type, keeping the order of the runs.
+func sortByUsage(suggestions []Suggestion, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	for start := 0; start < len(suggestions); {
+		end := start + 1
+		for end < len(suggestions) && suggestions[end].Type == suggestions[start].Type {
+			end++
+		}
+		run := suggestions[start:end]
+		sort.SliceStable(run, func(i, j int) bool {
+			return usage.Count(run[i].Text) > usage.Count(run[j].Text)
+		})
+		start = end
+	}
+}
+
 func matchScore(candidate, wordLower string) int {
 	candidateLower := strings.ToLower(candidate)
 	nameLower := strings.ToLower(nameAfterDot(candidate))
@@ -170,25 +242,30 @@ func nameAfterDot(s string) string {
 
 // SQLContext represents the parsed SQL context at cursor position.
 type SQLContext struct {
-	InSelect     bool
-	InFrom       bool
-	InJoin       bool
-	InWhere      bool
-	InOrderBy    bool
-	InGroupBy    bool
-	InHaving     bool
-	InInsert     bool
-	InUpdate     bool
-	InSet        bool
-	InOn         bool
-	InUsing      bool
-	InCreate     bool
-	InAlter      bool
-	InDrop       bool
-	AfterDot     bool // schema.table or table.column context
-	BeforeDot    string // the identifier before the dot
-	Tables       []tableRef // tables referenced in query
-	IsBackslash  bool
+	InSelect           bool
+	InFrom             bool
+	InJoin             bool
+	InWhere            bool
+	InOrderBy          bool
+	InGroupBy          bool
+	InHaving           bool
+	InInsert           bool
+	InUpdate           bool
+	InSet              bool
+	InOn               bool
+	InUsing            bool
+	InCreate           bool
+	InAlter            bool
+	InDrop             bool
+	AfterDot           bool           // schema.table or table.column context
+	BeforeDot          string         // the identifier before the dot
+	Tables             []tableRef     // tables referenced in query
+	VirtualTables      []VirtualTable // CTEs and derived tables defined in query
+	TargetTable        string         // table written by INSERT or UPDATE, lower-cased
+	InInsertColumns    bool           // inside INSERT INTO t (
+	AfterInsertColumns bool           // after INSERT INTO t [(...)], before VALUES/SELECT
+	InConflictTarget   bool           // inside ON CONFLICT (
+	IsBackslash        bool
 }
 
 type tableRef struct {
@@ -196,28 +273,46 @@ type tableRef struct {
 	Alias string
 }
 
+// qualifier returns how columns of the table are prefixed in the query.
+func (r tableRef) qualifier() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	return r.Name
+}
+
 func analyzeContext(text string) SQLContext {
 	ctx := SQLContext{}
-	text = strings.TrimSpace(text)
 
-	if text == "" {
-		return ctx
+	// A backslash command ends at its newline: on the current line its
+	// name is being typed, and SQL after it is completed on its own
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), `\`) {
+			if i == len(lines)-1 {
+				ctx.IsBackslash = true
+				return ctx
+			}
+			text = strings.Join(lines[i+1:], "\n")
+			break
+		}
 	}
 
-	// Check for backslash command
-	if strings.HasPrefix(text, `\`) {
-		ctx.IsBackslash = true
+	analyzeDML(&ctx, text)
+	text = strings.TrimSpace(text)
+
+	if text == "" {
 		return ctx
 	}
 
 	tokens := tokenize(text)
 
-	// Check for dot context
-	if strings.HasSuffix(text, ".") {
-		parts := strings.Split(lastWord(text[:len(text)-1]), ".")
-		if len(parts) > 0 {
+	// Check for dot context: "t.", "schema.", "schema.t." or a partial
+	// name after any of them
+	if w := lastWord(text); strings.Contains(w, ".") {
+		if before := w[:strings.LastIndex(w, ".")]; before != "" {
 			ctx.AfterDot = true
-			ctx.BeforeDot = strings.ToLower(parts[len(parts)-1])
+			ctx.BeforeDot = strings.ToLower(before)
 		}
 	}
 
@@ -300,9 +395,10 @@ func (c *Completer) contextCompletions(ctx SQLContext, word string) []Suggestion
 	}
 
 	if ctx.AfterDot {
-		// After a dot: suggest columns for the table/alias
+		// After a dot: columns of a table or alias ("t." or "schema.t."),
+		// otherwise the relations of a schema ("schema.")
 		tableName := resolveTableName(ctx.BeforeDot, ctx.Tables)
-		if cols, ok := c.meta.Columns[tableName]; ok {
+		if cols, ok := c.tableColumns(ctx, tableName); ok {
 			for _, col := range cols {
 				if fuzzyMatch(word, col) {
 					suggestions = append(suggestions, Suggestion{
@@ -310,11 +406,31 @@ func (c *Completer) contextCompletions(ctx SQLContext, word string) []Suggestion
 					})
 				}
 			}
+			return suggestions
+		}
+		if !strings.Contains(ctx.BeforeDot, ".") && c.meta.HasSchema(ctx.BeforeDot) {
+			for _, rel := range c.meta.SchemaRelations(ctx.BeforeDot) {
+				if fuzzyMatch(word, rel.Name) {
+					suggestions = append(suggestions, relationSuggestion(rel, rel.Name))
+				}
+			}
 		}
 		return suggestions
 	}
 
 	switch {
+	case ctx.InInsertColumns:
+		suggestions = append(suggestions, c.targetColumnSuggestions(ctx, word)...)
+
+	case ctx.AfterInsertColumns:
+		suggestions = append(suggestions, c.keywordSuggestions(word, insertKeywords)...)
+
+	case ctx.InConflictTarget:
+		suggestions = append(suggestions, c.conflictTargetSuggestions(ctx, word)...)
+
+	case ctx.InSet && ctx.TargetTable != "":
+		suggestions = append(suggestions, c.targetColumnSuggestions(ctx, word)...)
+
 	case ctx.InSelect:
 		suggestions = append(suggestions, c.keywordSuggestions(word, selectKeywords)...)
 		suggestions = append(suggestions, c.functionSuggestions(word)...)
@@ -322,12 +438,25 @@ func (c *Completer) contextCompletions(ctx SQLContext, word string) []Suggestion
 		suggestions = append(suggestions, c.columnSuggestions(ctx, word)...)
 
 	case ctx.InFrom, ctx.InJoin:
-		suggestions = append(suggestions, c.tableSuggestions(word)...)
+		suggestions = append(suggestions, c.cteSuggestions(ctx, word)...)
+		tables := c.tableSuggestions(word)
+		if ctx.InJoin {
+			joins := c.joinSuggestions(ctx, word)
+			suggestions = append(suggestions, joins...)
+			tables = withoutTexts(tables, joins)
+		}
+		suggestions = append(suggestions, tables...)
 		suggestions = append(suggestions, c.viewSuggestions(word)...)
 		suggestions = append(suggestions, c.schemaSuggestions(word)...)
 		suggestions = append(suggestions, c.keywordSuggestions(word, fromKeywords)...)
 
-	case ctx.InWhere, ctx.InHaving, ctx.InOn:
+	case ctx.InOn:
+		suggestions = append(suggestions, c.joinConditionSuggestions(ctx, word)...)
+		suggestions = append(suggestions, c.keywordSuggestions(word, whereKeywords)...)
+		suggestions = append(suggestions, c.columnSuggestions(ctx, word)...)
+		suggestions = append(suggestions, c.functionSuggestions(word)...)
+
+	case ctx.InWhere, ctx.InHaving:
 		suggestions = append(suggestions, c.keywordSuggestions(word, whereKeywords)...)
 		suggestions = append(suggestions, c.columnSuggestions(ctx, word)...)
 		suggestions = append(suggestions, c.functionSuggestions(word)...)
@@ -360,7 +489,7 @@ func (c *Completer) contextCompletions(ctx SQLContext, word string) []Suggestion
 
 func (c *Completer) allCompletions(word string) []Suggestion {
 	var suggestions []Suggestion
-	suggestions = append(suggestions, c.keywordSuggestions(word, allKeywords)...)
+	suggestions = append(suggestions, c.keywordSuggestions(word, c.dialect.Keywords())...)
 	suggestions = append(suggestions, c.tableSuggestions(word)...)
 	suggestions = append(suggestions, c.viewSuggestions(word)...)
 	suggestions = append(suggestions, c.columnSuggestionsAll(word)...)
@@ -382,19 +511,190 @@ func (c *Completer) allCompletions(word string) []Suggestion {
 
 func (c *Completer) tableSuggestions(word string) []Suggestion {
 	var s []Suggestion
-	for _, t := range c.meta.Tables {
-		if fuzzyMatch(word, t) || fuzzyMatch(word, nameAfterDot(t)) {
-			s = append(s, Suggestion{Text: t, Type: SuggestTable, Description: "table"})
+	for _, rel := range c.meta.VisibleRelations() {
+		if !rel.View && fuzzyMatch(word, rel.Name) {
+			s = append(s, relationSuggestion(rel, rel.Name))
+		}
+	}
+	// Relations outside the search path are offered schema-qualified.
+	for _, rel := range c.meta.HiddenRelations() {
+		if !rel.View && (fuzzyMatch(word, rel.QualifiedName()) || fuzzyMatch(word, rel.Name)) {
+			s = append(s, relationSuggestion(rel, rel.QualifiedName()))
+		}
+	}
+	return s
+}
+
+// addVirtualTables records the CTEs and derived tables of the query, and
+// the derived tables as references so their aliases resolve. These go
+// first: tokenize drops parentheses, so "(SELECT ... FROM t) sub" also
+// reads as t aliased sub.
+func (c *Completer) addVirtualTables(ctx *SQLContext, text string) {
+	ctx.VirtualTables = parseVirtualTables(text, func(name string) []string {
+		if rel := c.meta.Lookup(name); rel != nil {
+			return rel.Columns
+		}
+		return nil
+	})
+	var derived []tableRef
+	for _, vt := range ctx.VirtualTables {
+		if !vt.CTE {
+			derived = append(derived, tableRef{Name: vt.Name})
+		}
+	}
+	ctx.Tables = append(derived, ctx.Tables...)
+}
+
+// tableColumns returns the columns of a CTE, derived table or database
+// relation, in that order of precedence.
+func (c *Completer) tableColumns(ctx SQLContext, name string) ([]string, bool) {
+	for i := len(ctx.VirtualTables) - 1; i >= 0; i-- {
+		if ctx.VirtualTables[i].Name == name {
+			return ctx.VirtualTables[i].Columns, true
+		}
+	}
+	if rel := c.meta.Lookup(name); rel != nil {
+		return rel.Columns, true
+	}
+	return nil, false
+}
+
+// cteSuggestions offers the query's CTE names as tables.
+func (c *Completer) cteSuggestions(ctx SQLContext, word string) []Suggestion {
+	var s []Suggestion
+	for _, vt := range ctx.VirtualTables {
+		if vt.CTE && fuzzyMatch(word, vt.Name) {
+			s = append(s, Suggestion{Text: vt.Name, Type: SuggestTable, Description: "cte"})
 		}
 	}
 	return s
 }
 
+// relationSuggestion suggests rel under the given text as a table or view.
+func relationSuggestion(rel *Relation, text string) Suggestion {
+	if rel.View {
+		return Suggestion{Text: text, Type: SuggestView, Description: "view"}
+	}
+	return Suggestion{Text: text, Type: SuggestTable, Description: "table"}
+}
+
+// joinSuggestions returns the tables related by a foreign key to a table
+// already in the query. They come before the plain table list.
+func (c *Completer) joinSuggestions(ctx SQLContext, word string) []Suggestion {
+	var s []Suggestion
+	seen := make(map[string]bool)
+	add := func(table, related string) {
+		if seen[table] || !fuzzyMatch(word, table) {
+			return
+		}
+		seen[table] = true
+		s = append(s, Suggestion{Text: table, Type: SuggestJoin, Description: "join " + related})
+	}
+	for _, ref := range ctx.Tables {
+		for _, fk := range c.meta.ForeignKeys {
+			if refMatches(ref, fk.ChildSchema, fk.ChildTable) {
+				add(fk.ParentTable, ref.Name)
+			}
+			if refMatches(ref, fk.ParentSchema, fk.ParentTable) {
+				add(fk.ChildTable, ref.Name)
+			}
+		}
+	}
+	return s
+}
+
+// joinConditionSuggestions returns "child.col = parent.col" conditions
+// between the table being joined (the last one in the query) and the
+// tables before it, written with their aliases. A composite key is one
+// condition with its columns joined by AND.
+func (c *Completer) joinConditionSuggestions(ctx SQLContext, word string) []Suggestion {
+	if len(ctx.Tables) < 2 {
+		return nil
+	}
+	var s []Suggestion
+	joined := ctx.Tables[len(ctx.Tables)-1]
+	for _, other := range ctx.Tables[:len(ctx.Tables)-1] {
+		for _, key := range foreignKeyGroups(c.meta.ForeignKeys) {
+			fk := key[0]
+			var child, parent tableRef
+			switch {
+			case refMatches(joined, fk.ChildSchema, fk.ChildTable) && refMatches(other, fk.ParentSchema, fk.ParentTable):
+				child, parent = joined, other
+			case refMatches(other, fk.ChildSchema, fk.ChildTable) && refMatches(joined, fk.ParentSchema, fk.ParentTable):
+				child, parent = other, joined
+			default:
+				continue
+			}
+			conds := make([]string, len(key))
+			for i, pair := range key {
+				conds[i] = child.qualifier() + "." + pair.ChildColumn + " = " + parent.qualifier() + "." + pair.ParentColumn
+			}
+			cond := strings.Join(conds, " AND ")
+			if fuzzyMatch(word, cond) {
+				s = append(s, Suggestion{Text: cond, Type: SuggestJoinCondition, Description: "fk join"})
+			}
+		}
+	}
+	return s
+}
+
+// foreignKeyGroups gathers the column pairs of each foreign key, keeping
+// them in key order. Pairs without a constraint name are keys of their own.
+func foreignKeyGroups(fks []ForeignKey) [][]ForeignKey {
+	var groups [][]ForeignKey
+	index := make(map[[3]string]int)
+	for _, fk := range fks {
+		if fk.Constraint != "" {
+			id := [3]string{fk.ChildSchema, fk.ChildTable, fk.Constraint}
+			if i, ok := index[id]; ok {
+				groups[i] = append(groups[i], fk)
+				continue
+			}
+			index[id] = len(groups)
+		}
+		groups = append(groups, []ForeignKey{fk})
+	}
+	return groups
+}
+
+// withoutTexts drops the suggestions whose text already appears in seen.
+func withoutTexts(s, seen []Suggestion) []Suggestion {
+	texts := make(map[string]bool, len(seen))
+	for _, sg := range seen {
+		texts[sg.Text] = true
+	}
+	var out []Suggestion
+	for _, sg := range s {
+		if !texts[sg.Text] {
+			out = append(out, sg)
+		}
+	}
+	return out
+}
+
+// refMatches reports whether ref names the given table. An unqualified
+// reference matches the table in any schema.
+func refMatches(ref tableRef, schema, table string) bool {
+	name := ref.Name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		if !strings.EqualFold(name[:i], schema) {
+			return false
+		}
+		name = name[i+1:]
+	}
+	return strings.EqualFold(name, table)
+}
+
 func (c *Completer) viewSuggestions(word string) []Suggestion {
 	var s []Suggestion
-	for _, v := range c.meta.Views {
-		if fuzzyMatch(word, v) || fuzzyMatch(word, nameAfterDot(v)) {
-			s = append(s, Suggestion{Text: v, Type: SuggestView, Description: "view"})
+	for _, rel := range c.meta.VisibleRelations() {
+		if rel.View && fuzzyMatch(word, rel.Name) {
+			s = append(s, relationSuggestion(rel, rel.Name))
+		}
+	}
+	for _, rel := range c.meta.HiddenRelations() {
+		if rel.View && (fuzzyMatch(word, rel.QualifiedName()) || fuzzyMatch(word, rel.Name)) {
+			s = append(s, relationSuggestion(rel, rel.QualifiedName()))
 		}
 	}
 	return s
@@ -405,7 +705,7 @@ func (c *Completer) columnSuggestions(ctx SQLContext, word string) []Suggestion
 	if len(ctx.Tables) > 0 {
 		for _, tRef := range ctx.Tables {
 			tableName := tRef.Name
-			if cols, ok := c.meta.Columns[tableName]; ok {
+			if cols, ok := c.tableColumns(ctx, tableName); ok {
 				for _, col := range cols {
 					if fuzzyMatch(word, col) {
 						s = append(s, Suggestion{Text: col, Type: SuggestColumn, Description: tableName})
@@ -423,26 +723,85 @@ func (c *Completer) columnSuggestions(ctx SQLContext, word string) []Suggestion
 
 func (c *Completer) columnSuggestionsAll(word string) []Suggestion {
 	var s []Suggestion
-	for table, cols := range c.meta.Columns {
-		for _, col := range cols {
+	for _, rel := range c.meta.VisibleRelations() {
+		for _, col := range rel.Columns {
 			if fuzzyMatch(word, col) {
-				s = append(s, Suggestion{Text: col, Type: SuggestColumn, Description: table})
+				s = append(s, Suggestion{Text: col, Type: SuggestColumn, Description: rel.Name})
 			}
 		}
 	}
 	return s
 }
 
+// targetColumnSuggestions returns the columns of the INSERT or UPDATE
+// target table.
+func (c *Completer) targetColumnSuggestions(ctx SQLContext, word string) []Suggestion {
+	var s []Suggestion
+	cols, _ := c.tableColumns(ctx, ctx.TargetTable)
+	for _, col := range cols {
+		if fuzzyMatch(word, col) {
+			s = append(s, Suggestion{Text: col, Type: SuggestColumn, Description: ctx.TargetTable})
+		}
+	}
+	return s
+}
+
+// conflictTargetSuggestions returns the unique keys of the INSERT target
+// for ON CONFLICT (, or all its columns when none are known.
+func (c *Completer) conflictTargetSuggestions(ctx SQLContext, word string) []Suggestion {
+	var s []Suggestion
+	target := tableRef{Name: ctx.TargetTable}
+	for _, key := range c.meta.UniqueKeys {
+		if !refMatches(target, key.Schema, key.Table) {
+			continue
+		}
+		text := strings.Join(key.Columns, ", ")
+		if fuzzyMatch(word, text) {
+			s = append(s, Suggestion{Text: text, Type: SuggestColumn, Description: "unique key"})
+		}
+	}
+	if len(s) == 0 {
+		return c.targetColumnSuggestions(ctx, word)
+	}
+	return s
+}
+
 func (c *Completer) functionSuggestions(word string) []Suggestion {
 	var s []Suggestion
+	seen := make(map[string]bool)
 	for _, f := range c.meta.Functions {
+		seen[strings.ToLower(f)] = true
 		if fuzzyMatch(word, f) {
-			s = append(s, Suggestion{Text: f + "()", Type: SuggestFunction, Description: "function"})
+			s = append(s, Suggestion{Text: f + "()", Type: SuggestFunction, Description: c.functionDescription(f)})
 		}
 	}
+	// Built-ins the server didn't list, cased like keywords
+	for _, f := range c.dialect.Functions() {
+		if seen[strings.ToLower(f)] || !fuzzyMatch(word, f) {
+			continue
+		}
+		s = append(s, Suggestion{Text: c.caseKeyword(f, word) + "()", Type: SuggestFunction, Description: c.functionDescription(f)})
+	}
 	return s
 }
 
+// functionDescription shows the first signature of a function and how
+// many other overloads it has.
+func (c *Completer) functionDescription(name string) string {
+	fns := c.meta.FunctionSignatures(name)
+	if len(fns) == 0 {
+		return "function"
+	}
+	desc := "(" + fns[0].Arguments + ")"
+	if fns[0].Result != "" {
+		desc += " → " + fns[0].Result
+	}
+	if len(fns) > 1 {
+		desc += fmt.Sprintf(" (+%d more)", len(fns)-1)
+	}
+	return desc
+}
+
 func (c *Completer) schemaSuggestions(word string) []Suggestion {
 	var s []Suggestion
 	for _, sc := range c.meta.Schemas {
@@ -455,7 +814,12 @@ func (c *Completer) schemaSuggestions(word string) []Suggestion {
 
 func (c *Completer) datatypeSuggestions(word string) []Suggestion {
 	var s []Suggestion
-	for _, dt := range c.meta.Datatypes {
+	seen := make(map[string]bool)
+	for _, dt := range append(c.meta.Datatypes, c.dialect.Datatypes()...) {
+		if seen[dt] {
+			continue
+		}
+		seen[dt] = true
 		if fuzzyMatch(word, dt) {
 			s = append(s, Suggestion{Text: dt, Type: SuggestDatatype, Description: "type"})
 		}
@@ -463,9 +827,14 @@ func (c *Completer) datatypeSuggestions(word string) []Suggestion {
 	return s
 }
 
+// keywordSuggestions offers the keywords that fit the current clause,
+// leaving out any the dialect doesn't have.
 func (c *Completer) keywordSuggestions(word string, keywords []string) []Suggestion {
 	var s []Suggestion
 	for _, kw := range keywords {
+		if !c.dialectHas(kw) {
+			continue
+		}
 		if fuzzyMatch(word, kw) {
 			text := c.caseKeyword(kw, word)
 			s = append(s, Suggestion{Text: text, Type: SuggestKeyword, Description: "keyword"})
@@ -474,6 +843,17 @@ func (c *Completer) keywordSuggestions(word string, keywords []string) []Suggest
 	return s
 }
 
+// dialectHas reports whether every word of a keyword phrase such as
+// "DEFAULT VALUES" is a keyword in the completer's dialect.
+func (c *Completer) dialectHas(phrase string) bool {
+	for _, w := range strings.Fields(phrase) {
+		if !c.dialect.IsKeyword(w) {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Completer) caseKeyword(kw, input string) string {
 	switch c.keywordCasing {
 	case "upper":
@@ -654,6 +1034,8 @@ func resolveTableName(alias string, refs []tableRef) string {
 	return alias
 }
 
+// isKeyword reports whether s is a reserved word the parsers should never
+// take for a table name or alias. It doesn't depend on the dialect.
 func isKeyword(s string) bool {
 	upper := strings.ToUpper(s)
 	for _, kw := range allKeywords {
@@ -664,7 +1046,10 @@ func isKeyword(s string) bool {
 	return false
 }
 
-// SQL keyword lists
+// SQL keyword lists for each clause. Words the active dialect lacks are
+// filtered out when suggesting; allKeywords is also what isKeyword checks.
+
+var insertKeywords = []string{"VALUES", "SELECT", "DEFAULT VALUES", "OVERRIDING"}
 
 var selectKeywords = []string{
 	"DISTINCT", "ALL", "AS", "FROM", "WHERE", "GROUP", "BY",
@@ -681,7 +1066,7 @@ var fromKeywords = []string{
 	"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "OUTER", "CROSS",
 	"NATURAL", "ON", "USING", "WHERE", "GROUP", "BY", "HAVING",
 	"ORDER", "LIMIT", "OFFSET", "AS", "UNION", "INTERSECT",
-	"EXCEPT",
+	"EXCEPT", "STRAIGHT_JOIN",
 }
 
 var whereKeywords = []string{
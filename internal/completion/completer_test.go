@@ -3,26 +3,33 @@ package completion
 import (
 	"strings"
 	"testing"
+
+	"github.com/tomblomfield/gocli/internal/dialect"
 )
 
 func testMetadata() *Metadata {
 	meta := NewMetadata()
-	meta.Tables = []string{"users", "orders", "products", "user_settings", "django_migrations"}
-	meta.Views = []string{"active_users", "order_summary"}
-	meta.Columns = map[string][]string{
-		"users":          {"id", "name", "email", "created_at", "is_active"},
-		"orders":         {"id", "user_id", "product_id", "quantity", "total", "created_at"},
-		"products":       {"id", "name", "price", "description", "category"},
-		"user_settings":  {"id", "user_id", "setting_key", "setting_value"},
-	}
+	meta.AddTable("", "users", []string{"id", "name", "email", "created_at", "is_active"})
+	meta.AddTable("", "orders", []string{"id", "user_id", "product_id", "quantity", "total", "created_at"})
+	meta.AddTable("", "products", []string{"id", "name", "price", "description", "category"})
+	meta.AddTable("", "user_settings", []string{"id", "user_id", "setting_key", "setting_value"})
+	meta.AddTable("", "django_migrations", nil)
+	meta.AddView("", "active_users", nil)
+	meta.AddView("", "order_summary", nil)
 	meta.Functions = []string{"count", "sum", "avg", "max", "min", "now", "coalesce"}
 	meta.Schemas = []string{"public", "auth", "billing"}
 	meta.Databases = []string{"mydb", "testdb", "production"}
 	meta.Datatypes = []string{"integer", "text", "boolean", "timestamp", "jsonb", "uuid"}
 	meta.Specials = []SpecialCmd{
-		{`\dt`, "List tables"}, {`\di`, "List indexes"}, {`\dv`, "List views"},
-		{`\df`, "List functions"}, {`\dn`, "List schemas"}, {`\du`, "List roles"},
-		{`\l`, "List databases"}, {`\x`, "Toggle expanded"}, {`\q`, "Quit"},
+		{Name: `\dt`, Description: "List tables", Arg: ArgRelation},
+		{Name: `\di`, Description: "List indexes"},
+		{Name: `\dv`, Description: "List views", Arg: ArgRelation},
+		{Name: `\df`, Description: "List functions", Arg: ArgFunction},
+		{Name: `\dn`, Description: "List schemas", Arg: ArgSchema},
+		{Name: `\du`, Description: "List roles"},
+		{Name: `\l`, Description: "List databases", Arg: ArgDatabase},
+		{Name: `\x`, Description: "Toggle expanded", Arg: ArgChoice, Choices: []string{"on", "off", "auto"}},
+		{Name: `\q`, Description: "Quit", Aliases: []string{"quit", "exit"}},
 	}
 	meta.Favorites = []string{"active_users_query", "daily_report"}
 	return meta
@@ -78,10 +85,10 @@ func TestFuzzyScore(t *testing.T) {
 		input, candidate string
 		expectLower      int // score should be <= this
 	}{
-		{"users", "users", 0},             // exact
-		{"use", "users", 1},               // prefix
-		{"ser", "users", 2},               // substring
-		{"djmi", "django_migrations", 3},  // fuzzy
+		{"users", "users", 0},            // exact
+		{"use", "users", 1},              // prefix
+		{"ser", "users", 2},              // substring
+		{"djmi", "django_migrations", 3}, // fuzzy
 	}
 
 	for _, tt := range tests {
@@ -438,7 +445,7 @@ func TestUpdateMetadata(t *testing.T) {
 
 	// Update with new metadata
 	newMeta := NewMetadata()
-	newMeta.Tables = []string{"new_table"}
+	newMeta.AddTable("", "new_table", nil)
 	comp.UpdateMetadata(newMeta)
 
 	suggestions := comp.Complete("SELECT * FROM ", 14)
@@ -453,6 +460,92 @@ func TestUpdateMetadata(t *testing.T) {
 	}
 }
 
+func TestSetDialect_Keywords(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+
+	c.SetDialect(dialect.New(dialect.MySQL, "8.0.35"))
+	texts := suggestionTexts(c.Complete("SELECT * FROM users WHERE name IL", 33))
+	if _, ok := texts["ILIKE"]; ok {
+		t.Error("MySQL should not be offered ILIKE")
+	}
+	texts = suggestionTexts(c.Complete("SELECT * FROM users STRAIGHT", 28))
+	if _, ok := texts["STRAIGHT_JOIN"]; !ok {
+		t.Error("MySQL should be offered STRAIGHT_JOIN")
+	}
+	texts = suggestionTexts(c.Complete("RETURN", 6))
+	if _, ok := texts["RETURNING"]; ok {
+		t.Error("MySQL should not be offered RETURNING")
+	}
+
+	c.SetDialect(dialect.New(dialect.PostgreSQL, "16.1"))
+	texts = suggestionTexts(c.Complete("SELECT * FROM users WHERE name IL", 33))
+	if _, ok := texts["ILIKE"]; !ok {
+		t.Error("PostgreSQL should be offered ILIKE")
+	}
+	texts = suggestionTexts(c.Complete("SELECT * FROM users STRAIGHT", 28))
+	if _, ok := texts["STRAIGHT_JOIN"]; ok {
+		t.Error("PostgreSQL should not be offered STRAIGHT_JOIN")
+	}
+}
+
+func TestSetDialect_VersionGating(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+
+	c.SetDialect(dialect.New(dialect.PostgreSQL, "14.9"))
+	if _, ok := suggestionTexts(c.Complete("MERG", 4))["MERGE"]; ok {
+		t.Error("MERGE should not be offered before PostgreSQL 15")
+	}
+	c.SetDialect(dialect.New(dialect.PostgreSQL, "15.3"))
+	if _, ok := suggestionTexts(c.Complete("MERG", 4))["MERGE"]; !ok {
+		t.Error("MERGE should be offered from PostgreSQL 15")
+	}
+}
+
+func TestSetDialect_FunctionsAndDatatypes(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+	c.SetKeywordCasing("lower")
+
+	c.SetDialect(dialect.New(dialect.MySQL, "8.0.35"))
+	texts := suggestionTexts(c.Complete("SELECT group_con", 16))
+	if _, ok := texts["group_concat()"]; !ok {
+		t.Errorf("MySQL should be offered group_concat(), got %v", texts)
+	}
+	texts = suggestionTexts(c.Complete("SELECT cou", 10))
+	if _, ok := texts["count()"]; !ok {
+		t.Error("server-listed functions should still be offered")
+	}
+	if _, ok := texts["COUNT()"]; ok {
+		t.Error("built-ins the server already listed should not be offered twice")
+	}
+	texts = suggestionTexts(c.Complete("CREATE TABLE t (a tinyi", 23))
+	if _, ok := texts["tinyint"]; !ok {
+		t.Error("MySQL datatypes should be offered")
+	}
+
+	c.SetDialect(dialect.New(dialect.PostgreSQL, "16.1"))
+	texts = suggestionTexts(c.Complete("SELECT group_con", 16))
+	if _, ok := texts["group_concat()"]; ok {
+		t.Error("PostgreSQL should not be offered group_concat()")
+	}
+	texts = suggestionTexts(c.Complete("CREATE TABLE t (a tinyi", 23))
+	if _, ok := texts["tinyint"]; ok {
+		t.Error("PostgreSQL should not be offered tinyint")
+	}
+}
+
+func TestClauseKeywordsInGenericDialect(t *testing.T) {
+	lists := [][]string{insertKeywords, selectKeywords, fromKeywords, whereKeywords, ddlKeywords}
+	for _, list := range lists {
+		for _, phrase := range list {
+			for _, w := range strings.Fields(phrase) {
+				if !dialect.Generic().IsKeyword(w) {
+					t.Errorf("%q is missing from the dialect catalogs", w)
+				}
+			}
+		}
+	}
+}
+
 func TestLastWord(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -596,3 +689,213 @@ func TestAnalyzeContext_Empty(t *testing.T) {
 
 // Ensure strings import is used
 var _ = strings.ToLower
+
+func fkMetadata() *Metadata {
+	meta := testMetadata()
+	meta.ForeignKeys = []ForeignKey{
+		{ParentSchema: "public", ParentTable: "users", ParentColumn: "id",
+			ChildSchema: "public", ChildTable: "orders", ChildColumn: "user_id"},
+		{ParentSchema: "public", ParentTable: "products", ParentColumn: "id",
+			ChildSchema: "public", ChildTable: "orders", ChildColumn: "product_id"},
+	}
+	return meta
+}
+
+func TestComplete_JoinSuggestsRelatedTables(t *testing.T) {
+	c := NewCompleter(fkMetadata(), true)
+	suggestions := c.Complete("SELECT * FROM orders o JOIN ", 28)
+
+	if len(suggestions) < 2 {
+		t.Fatalf("expected suggestions, got %v", suggestions)
+	}
+	related := map[string]bool{}
+	for _, s := range suggestions[:2] {
+		if s.Type != SuggestJoin {
+			t.Errorf("FK-related tables should come first, got %+v", s)
+		}
+		related[s.Text] = true
+	}
+	if !related["users"] || !related["products"] {
+		t.Errorf("expected users and products first, got %v", suggestions[:2])
+	}
+
+	count := 0
+	for _, s := range suggestions {
+		if s.Text == "users" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("related tables should not be repeated in the table list, got %d", count)
+	}
+}
+
+func TestComplete_JoinConditions(t *testing.T) {
+	c := NewCompleter(fkMetadata(), true)
+	text := "SELECT * FROM orders o JOIN users u ON "
+	suggestions := c.Complete(text, len(text))
+
+	if len(suggestions) == 0 || suggestions[0].Text != "o.user_id = u.id" {
+		t.Fatalf("expected join condition first, got %v", suggestions)
+	}
+	if suggestions[0].Type != SuggestJoinCondition {
+		t.Errorf("unexpected type: %v", suggestions[0].Type)
+	}
+	for _, s := range suggestions {
+		if s.Type == SuggestJoinCondition && strings.Contains(s.Text, "product_id") {
+			t.Errorf("only conditions for the joined tables expected, got %q", s.Text)
+		}
+	}
+
+	// Without aliases the table names qualify the columns
+	text = "SELECT * FROM users JOIN orders ON "
+	suggestions = c.Complete(text, len(text))
+	if len(suggestions) == 0 || suggestions[0].Text != "orders.user_id = users.id" {
+		t.Errorf("expected condition with table names, got %v", suggestions)
+	}
+}
+
+func TestComplete_JoinConditions_CompositeKey(t *testing.T) {
+	meta := fkMetadata()
+	meta.ForeignKeys = append(meta.ForeignKeys,
+		ForeignKey{ParentSchema: "public", ParentTable: "order_lines", ParentColumn: "order_id",
+			ChildSchema: "public", ChildTable: "shipments", ChildColumn: "order_id", Constraint: "shipments_line_fkey"},
+		ForeignKey{ParentSchema: "public", ParentTable: "order_lines", ParentColumn: "line_no",
+			ChildSchema: "public", ChildTable: "shipments", ChildColumn: "line_no", Constraint: "shipments_line_fkey"},
+	)
+	c := NewCompleter(meta, true)
+	text := "SELECT * FROM shipments s JOIN order_lines l ON "
+	suggestions := c.Complete(text, len(text))
+
+	var conds []string
+	for _, s := range suggestions {
+		if s.Type == SuggestJoinCondition {
+			conds = append(conds, s.Text)
+		}
+	}
+	want := "s.order_id = l.order_id AND s.line_no = l.line_no"
+	if len(conds) != 1 || conds[0] != want {
+		t.Errorf("a composite key should be one condition %q, got %q", want, conds)
+	}
+}
+
+func suggestionTexts(suggestions []Suggestion) map[string]Suggestion {
+	texts := make(map[string]Suggestion, len(suggestions))
+	for _, s := range suggestions {
+		texts[s.Text] = s
+	}
+	return texts
+}
+
+func TestComplete_SchemaDot(t *testing.T) {
+	c := NewCompleter(schemaMetadata(), true)
+
+	texts := suggestionTexts(c.Complete("SELECT * FROM auth.", 19))
+	if _, ok := texts["sessions"]; !ok {
+		t.Errorf("expected auth relations, got %v", texts)
+	}
+	if _, ok := texts["orders"]; ok {
+		t.Error("public relations should not be offered after auth.")
+	}
+
+	texts = suggestionTexts(c.Complete("SELECT * FROM billing.inv", 25))
+	if s, ok := texts["invoices"]; !ok || s.Type != SuggestView {
+		t.Errorf("expected invoices view, got %v", texts)
+	}
+}
+
+func TestComplete_SchemaTableDot(t *testing.T) {
+	c := NewCompleter(schemaMetadata(), true)
+
+	texts := suggestionTexts(c.Complete("SELECT auth.users.", 18))
+	if _, ok := texts["password_hash"]; !ok {
+		t.Errorf("expected auth.users columns, got %v", texts)
+	}
+	if _, ok := texts["email"]; ok {
+		t.Error("public.users columns should not be offered for auth.users")
+	}
+
+	texts = suggestionTexts(c.Complete("SELECT users.", 13))
+	if _, ok := texts["email"]; !ok {
+		t.Errorf("unqualified users should resolve through the search path, got %v", texts)
+	}
+
+	text := "SELECT * FROM auth.users u WHERE u.pa"
+	texts = suggestionTexts(c.Complete(text, len(text)))
+	if _, ok := texts["password_hash"]; !ok {
+		t.Errorf("alias of a qualified table should resolve, got %v", texts)
+	}
+}
+
+func TestComplete_SearchPathTables(t *testing.T) {
+	c := NewCompleter(schemaMetadata(), true)
+
+	texts := suggestionTexts(c.Complete("SELECT * FROM ", 14))
+	for _, want := range []string{"users", "orders", "auth.users", "auth.sessions", "billing.invoices"} {
+		if _, ok := texts[want]; !ok {
+			t.Errorf("expected %q in %v", want, texts)
+		}
+	}
+	if _, ok := texts["sessions"]; ok {
+		t.Error("relations outside the search path should be schema-qualified")
+	}
+}
+
+func TestComplete_InsertColumns(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+
+	text := "INSERT INTO orders ("
+	suggestions := c.Complete(text, len(text))
+	if len(suggestions) != 6 {
+		t.Errorf("expected the 6 orders columns, got %v", suggestions)
+	}
+	for _, s := range suggestions {
+		if s.Type != SuggestColumn || s.Description != "orders" {
+			t.Errorf("unexpected suggestion %+v", s)
+		}
+	}
+
+	text = "INSERT INTO orders (user_id, total) "
+	texts := suggestionTexts(c.Complete(text, len(text)))
+	if _, ok := texts["VALUES"]; !ok {
+		t.Errorf("expected VALUES after the column list, got %v", texts)
+	}
+	if _, ok := texts["SELECT"]; !ok {
+		t.Errorf("expected SELECT after the column list, got %v", texts)
+	}
+	if _, ok := texts["users"]; ok {
+		t.Error("tables should not be offered after the column list")
+	}
+}
+
+func TestComplete_OnConflictColumns(t *testing.T) {
+	meta := testMetadata()
+	meta.UniqueKeys = []UniqueKey{
+		{Table: "user_settings", Columns: []string{"id"}},
+		{Table: "user_settings", Columns: []string{"user_id", "setting_key"}},
+		{Table: "users", Columns: []string{"email"}},
+	}
+	c := NewCompleter(meta, true)
+
+	text := "INSERT INTO user_settings (user_id, setting_key) VALUES (1, 'x') ON CONFLICT ("
+	texts := suggestionTexts(c.Complete(text, len(text)))
+	if len(texts) != 2 {
+		t.Errorf("expected the two unique keys, got %v", texts)
+	}
+	if s, ok := texts["user_id, setting_key"]; !ok || s.Description != "unique key" {
+		t.Errorf("expected composite key, got %v", texts)
+	}
+}
+
+func TestComplete_UpdateSetColumns(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+
+	text := "UPDATE products SET "
+	texts := suggestionTexts(c.Complete(text, len(text)))
+	if _, ok := texts["price"]; !ok {
+		t.Errorf("expected products columns, got %v", texts)
+	}
+	if _, ok := texts["email"]; ok {
+		t.Error("only columns of the updated table should be offered")
+	}
+}
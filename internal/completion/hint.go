@@ -0,0 +1,90 @@
+package completion
+
+import "strings"
+
+// parameterHint returns a hint for the function call the cursor is in: the
+// signature with the parameter being typed in brackets.
+func (c *Completer) parameterHint(text string) (Suggestion, bool) {
+	name, arg, ok := openCall(text)
+	if !ok {
+		return Suggestion{}, false
+	}
+	fns := c.meta.FunctionSignatures(name)
+	if len(fns) == 0 {
+		return Suggestion{}, false
+	}
+
+	// Prefer the first overload that takes the current parameter
+	f := fns[0]
+	for _, fn := range fns {
+		if len(splitArguments(fn.Arguments)) > arg {
+			f = fn
+			break
+		}
+	}
+	args := splitArguments(f.Arguments)
+	if arg < len(args) {
+		args[arg] = "[" + args[arg] + "]"
+	}
+	f.Arguments = strings.Join(args, ", ")
+	return Suggestion{Type: SuggestHint, Description: f.Signature()}, true
+}
+
+// openCall finds the innermost parenthesis left open in text and returns
+// the name before it and the index of the argument being typed.
+func openCall(text string) (name string, arg int, ok bool) {
+	var opens, commas []int
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			opens = append(opens, i)
+			commas = append(commas, 0)
+		case ch == ')':
+			if len(opens) > 0 {
+				opens = opens[:len(opens)-1]
+				commas = commas[:len(commas)-1]
+			}
+		case ch == ',':
+			if len(commas) > 0 {
+				commas[len(commas)-1]++
+			}
+		}
+	}
+	if len(opens) == 0 || quote != 0 {
+		return "", 0, false
+	}
+	name = lastWord(strings.TrimRight(text[:opens[len(opens)-1]], " \t\n"))
+	return name, commas[len(commas)-1], name != ""
+}
+
+// splitArguments splits an argument list on the commas outside
+// parentheses, as in "a numeric(10,2), b text".
+func splitArguments(args string) []string {
+	if strings.TrimSpace(args) == "" {
+		return nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(args[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(args[start:]))
+}
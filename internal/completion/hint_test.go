@@ -0,0 +1,97 @@
+package completion
+
+import "testing"
+
+func signatureMetadata() *Metadata {
+	meta := testMetadata()
+	meta.Functions = append(meta.Functions, "substr")
+	meta.AddFunction(Function{Schema: "pg_catalog", Name: "substr", Arguments: "text, integer", Result: "text"})
+	meta.AddFunction(Function{Schema: "pg_catalog", Name: "substr", Arguments: "text, integer, integer", Result: "text"})
+	meta.AddFunction(Function{Schema: "public", Name: "round_to", Arguments: "val numeric(10,2), places integer DEFAULT 2", Result: "numeric"})
+	return meta
+}
+
+func TestOpenCall(t *testing.T) {
+	tests := []struct {
+		text string
+		name string
+		arg  int
+		ok   bool
+	}{
+		{"SELECT substr(", "substr", 0, true},
+		{"SELECT substr(name, 2, ", "substr", 2, true},
+		{"SELECT substr(lower(name), ", "substr", 1, true},
+		{"SELECT substr(lower(name", "lower", 0, true},
+		{"SELECT substr(name, ',', ", "substr", 2, true},
+		{"SELECT public.round_to (", "public.round_to", 0, true},
+		{"SELECT substr(name, 1)", "", 0, false},
+		{"SELECT 'a(", "", 0, false},
+		{"(", "", 0, false},
+	}
+	for _, tt := range tests {
+		name, arg, ok := openCall(tt.text)
+		if name != tt.name || arg != tt.arg || ok != tt.ok {
+			t.Errorf("openCall(%q) = %q, %d, %v; want %q, %d, %v",
+				tt.text, name, arg, ok, tt.name, tt.arg, tt.ok)
+		}
+	}
+}
+
+func TestSplitArguments(t *testing.T) {
+	got := splitArguments("val numeric(10,2), places integer DEFAULT 2")
+	if len(got) != 2 || got[0] != "val numeric(10,2)" || got[1] != "places integer DEFAULT 2" {
+		t.Errorf("splitArguments = %q", got)
+	}
+	if got := splitArguments(""); got != nil {
+		t.Errorf("no arguments should split to nil, got %q", got)
+	}
+}
+
+func TestComplete_ParameterHint(t *testing.T) {
+	c := NewCompleter(signatureMetadata(), true)
+
+	text := "SELECT substr(name, "
+	suggestions := c.Complete(text, len(text))
+	if len(suggestions) == 0 || suggestions[0].Type != SuggestHint {
+		t.Fatalf("expected a hint first, got %v", suggestions)
+	}
+	if got := suggestions[0].Description; got != "substr(text, [integer]) → text" {
+		t.Errorf("hint = %q", got)
+	}
+
+	// The third argument only exists in the longer overload
+	text = "SELECT substr(name, 1, le"
+	suggestions = c.Complete(text, len(text))
+	if suggestions[0].Description != "substr(text, integer, [integer]) → text" {
+		t.Errorf("hint = %q", suggestions[0].Description)
+	}
+	if suggestions[0].Text != "le" {
+		t.Errorf("choosing the hint should keep the typed word, got %q", suggestions[0].Text)
+	}
+
+	text = "SELECT public.round_to(price, "
+	suggestions = c.Complete(text, len(text))
+	if suggestions[0].Description != "round_to(val numeric(10,2), [places integer DEFAULT 2]) → numeric" {
+		t.Errorf("hint = %q", suggestions[0].Description)
+	}
+
+	text = "SELECT count(*), "
+	for _, s := range c.Complete(text, len(text)) {
+		if s.Type == SuggestHint {
+			t.Errorf("no hint expected outside a call, got %q", s.Description)
+		}
+	}
+}
+
+func TestComplete_FunctionDescription(t *testing.T) {
+	c := NewCompleter(signatureMetadata(), true)
+	for _, s := range c.Complete("SELECT subs", 11) {
+		if s.Text == "substr()" {
+			if s.Description != "(text, integer) → text (+1 more)" {
+				t.Errorf("description = %q", s.Description)
+			}
+			return
+		}
+	}
+	t.Error("substr() not suggested")
+}
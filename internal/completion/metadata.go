@@ -0,0 +1,291 @@
+package completion
+
+import (
+	"sort"
+	"strings"
+)
+
+// Metadata holds database schema information for completions.
+type Metadata struct {
+	// Relations maps schema -> relation name -> relation, with both keys
+	// lower-cased. Relations loaded without a schema are kept under "".
+	Relations map[string]map[string]*Relation
+	// SearchPath lists, in lookup order, the schemas whose relations can be
+	// named without a schema. When it is empty every relation is visible.
+	SearchPath []string
+
+	Functions []string
+	// Signatures maps lower-cased function names to their overloads.
+	Signatures  map[string][]Function
+	Schemas     []string
+	Databases   []string
+	Datatypes   []string
+	Specials    []SpecialCmd
+	Favorites   []string
+	ForeignKeys []ForeignKey
+	UniqueKeys  []UniqueKey
+
+	// visible and hidden are the relations sorted for completion, built
+	// by Index so completing does not sort them on every keystroke
+	indexed bool
+	visible []*Relation
+	hidden  []*Relation
+	names   map[string]bool // lower-cased names completion can suggest
+}
+
+// Relation is a table or view and its columns.
+type Relation struct {
+	Schema  string
+	Name    string
+	View    bool
+	Columns []string
+}
+
+// QualifiedName returns "schema.name", or just the name when the relation
+// has no schema.
+func (r *Relation) QualifiedName() string {
+	if r.Schema == "" {
+		return r.Name
+	}
+	return r.Schema + "." + r.Name
+}
+
+// ForeignKey is one column pair of a foreign key, used to suggest JOIN
+// targets and conditions. The pairs of a composite key share its
+// Constraint name and come in key order.
+type ForeignKey struct {
+	ParentSchema string
+	ParentTable  string
+	ParentColumn string
+	ChildSchema  string
+	ChildTable   string
+	ChildColumn  string
+	Constraint   string
+}
+
+// Function is one signature of a function or procedure.
+type Function struct {
+	Schema    string
+	Name      string
+	Arguments string // e.g. "string text, start integer"
+	Result    string // return type; empty for procedures
+}
+
+// Signature returns the function as "name(arguments) → result".
+func (f Function) Signature() string {
+	sig := f.Name + "(" + f.Arguments + ")"
+	if f.Result != "" {
+		sig += " → " + f.Result
+	}
+	return sig
+}
+
+// UniqueKey is a primary key or unique index, usable as an ON CONFLICT
+// target.
+type UniqueKey struct {
+	Schema  string
+	Table   string
+	Columns []string
+}
+
+// NewMetadata creates empty metadata.
+func NewMetadata() *Metadata {
+	return &Metadata{
+		Relations:  make(map[string]map[string]*Relation),
+		Signatures: make(map[string][]Function),
+	}
+}
+
+// AddFunction records a function signature.
+func (m *Metadata) AddFunction(f Function) {
+	if m.Signatures == nil {
+		m.Signatures = make(map[string][]Function)
+	}
+	name := strings.ToLower(f.Name)
+	m.Signatures[name] = append(m.Signatures[name], f)
+}
+
+// FunctionSignatures returns the overloads of a function, which may be
+// schema-qualified.
+func (m *Metadata) FunctionSignatures(name string) []Function {
+	name = strings.ToLower(name)
+	schema := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		schema, name = name[:i], name[i+1:]
+	}
+	if schema == "" {
+		return m.Signatures[name]
+	}
+	var fns []Function
+	for _, f := range m.Signatures[name] {
+		if strings.EqualFold(f.Schema, schema) {
+			fns = append(fns, f)
+		}
+	}
+	return fns
+}
+
+// AddTable records a table and its columns.
+func (m *Metadata) AddTable(schema, name string, columns []string) {
+	m.AddRelation(&Relation{Schema: schema, Name: name, Columns: columns})
+}
+
+// AddView records a view and its columns.
+func (m *Metadata) AddView(schema, name string, columns []string) {
+	m.AddRelation(&Relation{Schema: schema, Name: name, View: true, Columns: columns})
+}
+
+// AddRelation records a table or view, replacing one of the same name.
+func (m *Metadata) AddRelation(rel *Relation) {
+	schema := strings.ToLower(rel.Schema)
+	if m.Relations[schema] == nil {
+		m.Relations[schema] = make(map[string]*Relation)
+	}
+	m.Relations[schema][strings.ToLower(rel.Name)] = rel
+	m.indexed = false
+}
+
+// Lookup resolves a relation name as written in a query: "schema.name", or
+// an unqualified name found through the search path. It returns nil if the
+// relation is unknown.
+func (m *Metadata) Lookup(name string) *Relation {
+	name = strings.ToLower(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return m.Relations[name[:i]][name[i+1:]]
+	}
+	for _, schema := range m.lookupOrder() {
+		if rel := m.Relations[schema][name]; rel != nil {
+			return rel
+		}
+	}
+	return nil
+}
+
+// lookupOrder returns the schemas searched for unqualified names: relations
+// without a schema first, then the search path, or every schema when no
+// search path is known.
+func (m *Metadata) lookupOrder() []string {
+	order := []string{""}
+	if len(m.SearchPath) == 0 {
+		for _, schema := range sortedKeys(m.Relations) {
+			if schema != "" {
+				order = append(order, schema)
+			}
+		}
+		return order
+	}
+	for _, schema := range m.SearchPath {
+		order = append(order, strings.ToLower(schema))
+	}
+	return order
+}
+
+// Index sorts the relations into those visible without a schema and
+// those hidden from it. The completer calls it when metadata is loaded;
+// changing SearchPath afterwards needs another call.
+func (m *Metadata) Index() {
+	m.visible = nil
+	seen := make(map[string]bool)
+	for _, schema := range m.lookupOrder() {
+		for _, name := range sortedKeys(m.Relations[schema]) {
+			if !seen[name] {
+				seen[name] = true
+				m.visible = append(m.visible, m.Relations[schema][name])
+			}
+		}
+	}
+	sort.SliceStable(m.visible, func(i, j int) bool { return m.visible[i].Name < m.visible[j].Name })
+
+	visible := make(map[*Relation]bool, len(m.visible))
+	for _, rel := range m.visible {
+		visible[rel] = true
+	}
+	m.hidden = nil
+	for _, schema := range sortedKeys(m.Relations) {
+		for _, name := range sortedKeys(m.Relations[schema]) {
+			if rel := m.Relations[schema][name]; !visible[rel] {
+				m.hidden = append(m.hidden, rel)
+			}
+		}
+	}
+
+	m.names = make(map[string]bool)
+	for schema, rels := range m.Relations {
+		m.names[schema] = true
+		for name, rel := range rels {
+			m.names[name] = true
+			for _, col := range rel.Columns {
+				m.names[strings.ToLower(col)] = true
+			}
+		}
+	}
+	for _, list := range [][]string{m.Functions, m.Schemas, m.Databases, m.Datatypes} {
+		for _, name := range list {
+			m.names[strings.ToLower(name)] = true
+		}
+	}
+	for name := range m.Signatures {
+		m.names[name] = true
+	}
+	m.indexed = true
+}
+
+// HasName reports whether name, in any case, is a relation, column,
+// schema, database, function or datatype that completion can suggest.
+func (m *Metadata) HasName(name string) bool {
+	if !m.indexed {
+		m.Index()
+	}
+	return m.names[strings.ToLower(name)]
+}
+
+// VisibleRelations returns the relations that can be named without a
+// schema, sorted by name. A relation hidden by a same-named one earlier in
+// the search path is left out.
+func (m *Metadata) VisibleRelations() []*Relation {
+	if !m.indexed {
+		m.Index()
+	}
+	return m.visible
+}
+
+// HiddenRelations returns the relations that need a schema prefix because
+// their schema is not on the search path, sorted by qualified name.
+func (m *Metadata) HiddenRelations() []*Relation {
+	if !m.indexed {
+		m.Index()
+	}
+	return m.hidden
+}
+
+// SchemaRelations returns the relations of one schema, sorted by name.
+func (m *Metadata) SchemaRelations(schema string) []*Relation {
+	rels := m.Relations[strings.ToLower(schema)]
+	var out []*Relation
+	for _, name := range sortedKeys(rels) {
+		out = append(out, rels[name])
+	}
+	return out
+}
+
+// HasSchema reports whether schema is a known schema name.
+func (m *Metadata) HasSchema(schema string) bool {
+	if _, ok := m.Relations[strings.ToLower(schema)]; ok && schema != "" {
+		return true
+	}
+	for _, s := range m.Schemas {
+		if strings.EqualFold(s, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+package completion
+
+import "testing"
+
+func schemaMetadata() *Metadata {
+	meta := NewMetadata()
+	meta.AddTable("public", "users", []string{"id", "email"})
+	meta.AddTable("public", "orders", []string{"id", "user_id"})
+	meta.AddTable("auth", "users", []string{"id", "password_hash"})
+	meta.AddTable("auth", "sessions", []string{"id", "token"})
+	meta.AddView("billing", "invoices", []string{"id", "amount"})
+	meta.Schemas = []string{"public", "auth", "billing"}
+	meta.SearchPath = []string{"public"}
+	return meta
+}
+
+func TestMetadata_Lookup(t *testing.T) {
+	meta := schemaMetadata()
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"users", "public"},
+		{"public.users", "public"},
+		{"auth.users", "auth"},
+		{"AUTH.Users", "auth"},
+	}
+	for _, tt := range tests {
+		rel := meta.Lookup(tt.name)
+		if rel == nil || rel.Schema != tt.schema {
+			t.Errorf("Lookup(%q) = %+v, want schema %q", tt.name, rel, tt.schema)
+		}
+	}
+	if rel := meta.Lookup("sessions"); rel != nil {
+		t.Errorf("auth.sessions is not on the search path, got %+v", rel)
+	}
+
+	meta.SearchPath = []string{"auth", "public"}
+	if rel := meta.Lookup("users"); rel == nil || rel.Schema != "auth" {
+		t.Errorf("first schema on the search path should win, got %+v", rel)
+	}
+}
+
+func TestMetadata_VisibleRelations(t *testing.T) {
+	meta := schemaMetadata()
+
+	var visible []string
+	for _, rel := range meta.VisibleRelations() {
+		visible = append(visible, rel.QualifiedName())
+	}
+	if len(visible) != 2 || visible[0] != "public.orders" || visible[1] != "public.users" {
+		t.Errorf("visible = %v", visible)
+	}
+
+	var hidden []string
+	for _, rel := range meta.HiddenRelations() {
+		hidden = append(hidden, rel.QualifiedName())
+	}
+	want := []string{"auth.sessions", "auth.users", "billing.invoices"}
+	if len(hidden) != len(want) {
+		t.Fatalf("hidden = %v, want %v", hidden, want)
+	}
+	for i := range want {
+		if hidden[i] != want[i] {
+			t.Errorf("hidden = %v, want %v", hidden, want)
+		}
+	}
+
+	// Without a search path every relation is visible; same-named ones
+	// resolve to the first schema alphabetically.
+	meta.SearchPath = nil
+	meta.Index()
+	if n := len(meta.VisibleRelations()); n != 4 {
+		t.Errorf("expected 4 visible relations, got %d", n)
+	}
+	if n := len(meta.HiddenRelations()); n != 1 {
+		t.Errorf("expected the shadowed users table to be hidden, got %d", n)
+	}
+}
+
+func TestMetadata_IndexOnce(t *testing.T) {
+	meta := schemaMetadata()
+	meta.Index()
+	first := meta.VisibleRelations()
+	if second := meta.VisibleRelations(); &first[0] != &second[0] {
+		t.Error("relations should be sorted once, not on every call")
+	}
+
+	meta.AddTable("public", "accounts", []string{"id"})
+	if rels := meta.VisibleRelations(); len(rels) != 3 || rels[0].Name != "accounts" {
+		t.Errorf("adding a relation should rebuild the lists, got %v", rels)
+	}
+}
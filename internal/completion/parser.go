@@ -0,0 +1,446 @@
+package completion
+
+import (
+	"strings"
+	"unicode"
+)
+
+// VirtualTable is a CTE or derived table defined in the query being
+// typed, with the columns it projects.
+type VirtualTable struct {
+	Name    string // CTE name or derived-table alias, lower-cased
+	Columns []string
+	CTE     bool // defined by WITH rather than FROM (...) alias
+}
+
+// sqlToken is a token of the lightweight parser. Unlike tokenize, it keeps
+// parentheses and commas so that nesting can be followed.
+type sqlToken struct {
+	text   string // as written, without identifier quotes
+	upper  string
+	word   bool // identifier or keyword
+	quoted bool // "quoted" or `quoted` identifier
+}
+
+// lexSQL splits sql into tokens, dropping whitespace and comments. Dotted
+// names such as s.t.col stay one token.
+func lexSQL(sql string) []sqlToken {
+	var toks []sqlToken
+	rs := []rune(sql)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(rs) && rs[i+1] == '-':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			i += 2
+			for i+1 < len(rs) && !(rs[i] == '*' && rs[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'':
+			j := i + 1
+			for j < len(rs) {
+				if rs[j] == '\'' {
+					if j+1 < len(rs) && rs[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			end := min(j+1, len(rs))
+			toks = append(toks, sqlToken{text: string(rs[i:end]), upper: string(rs[i:end])})
+			i = end
+		case r == '"' || r == '`':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			name := string(rs[i+1 : min(j, len(rs))])
+			toks = append(toks, sqlToken{text: name, upper: strings.ToUpper(name), word: true, quoted: true})
+			i = min(j+1, len(rs))
+		case isWordRune(r):
+			j := i
+			for j < len(rs) && (isWordRune(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			if j < len(rs) && rs[j] == '*' && rs[j-1] == '.' {
+				j++ // t.*
+			}
+			w := string(rs[i:j])
+			toks = append(toks, sqlToken{text: w, upper: strings.ToUpper(w), word: true})
+			i = j
+		default:
+			toks = append(toks, sqlToken{text: string(r), upper: string(r)})
+			i++
+		}
+	}
+	return toks
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// name returns the token as an identifier: lower-cased unless quoted.
+func (t sqlToken) name() string {
+	if t.quoted {
+		return t.text
+	}
+	return strings.ToLower(t.text)
+}
+
+// parseVirtualTables returns the CTEs and derived tables defined in sql.
+// columnsOf resolves the columns of a table for SELECT * projections.
+func parseVirtualTables(sql string, columnsOf func(string) []string) []VirtualTable {
+	p := &queryParser{columnsOf: columnsOf}
+	p.scan(lexSQL(sql))
+	return p.tables
+}
+
+type queryParser struct {
+	columnsOf func(string) []string
+	tables    []VirtualTable
+}
+
+// columns resolves a table name, looking at the virtual tables found so
+// far before the database.
+func (p *queryParser) columns(name string) []string {
+	for i := len(p.tables) - 1; i >= 0; i-- {
+		if p.tables[i].Name == name {
+			return p.tables[i].Columns
+		}
+	}
+	if p.columnsOf == nil {
+		return nil
+	}
+	return p.columnsOf(name)
+}
+
+// scan walks toks looking for WITH clauses and derived tables, descending
+// into their bodies.
+func (p *queryParser) scan(toks []sqlToken) {
+	inFrom := false // in a FROM list, where "(" after "," starts a table
+	for i := 0; i < len(toks); i++ {
+		t := toks[i].upper
+		switch {
+		case t == "WITH" && toks[i].word:
+			i = p.parseWith(toks, i+1) - 1
+		case t == "(" && i > 0 && (isFromKeyword(toks[i-1].upper) || inFrom && toks[i-1].upper == ","):
+			i = p.parseDerived(toks, i) - 1
+		case t == "FROM":
+			inFrom = true
+		case t == "SELECT" || selectListEnd[t]:
+			inFrom = false
+		}
+	}
+}
+
+func isFromKeyword(s string) bool {
+	return s == "FROM" || s == "JOIN" || s == "LATERAL"
+}
+
+// parseWith parses the CTE list starting at toks[i] and returns the index
+// after it.
+func (p *queryParser) parseWith(toks []sqlToken, i int) int {
+	if i < len(toks) && toks[i].upper == "RECURSIVE" {
+		i++
+	}
+	for i < len(toks) && toks[i].word {
+		cte := VirtualTable{Name: toks[i].name(), CTE: true}
+		i++
+		var explicit []string
+		if i < len(toks) && toks[i].upper == "(" {
+			end := closingParen(toks, i)
+			explicit = columnList(toks[i+1 : end])
+			i = min(end+1, len(toks))
+		}
+		if i >= len(toks) || toks[i].upper != "AS" {
+			return i
+		}
+		i++
+		for i < len(toks) && (toks[i].upper == "NOT" || toks[i].upper == "MATERIALIZED") {
+			i++
+		}
+		if i >= len(toks) || toks[i].upper != "(" {
+			return i
+		}
+		end := closingParen(toks, i)
+		body := toks[i+1 : end]
+		// A recursive CTE can refer to itself
+		p.tables = append(p.tables, cte)
+		p.scan(body)
+		cte.Columns = explicit
+		if cte.Columns == nil {
+			cte.Columns = p.projection(body)
+		}
+		p.replace(cte)
+		i = min(end+1, len(toks))
+		if i >= len(toks) || toks[i].upper != "," {
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// replace updates the last virtual table named like vt.
+func (p *queryParser) replace(vt VirtualTable) {
+	for i := len(p.tables) - 1; i >= 0; i-- {
+		if p.tables[i].Name == vt.Name {
+			p.tables[i] = vt
+			return
+		}
+	}
+	p.tables = append(p.tables, vt)
+}
+
+// parseDerived parses "(SELECT ...) [AS] alias [(columns)]" starting at
+// the opening parenthesis toks[i] and returns the index after it.
+func (p *queryParser) parseDerived(toks []sqlToken, i int) int {
+	end := closingParen(toks, i)
+	body := toks[i+1 : end]
+	p.scan(body)
+	i = end + 1
+	if len(body) == 0 || !(body[0].upper == "SELECT" || body[0].upper == "WITH" || body[0].upper == "VALUES") {
+		return i
+	}
+	if i < len(toks) && toks[i].upper == "AS" {
+		i++
+	}
+	if i >= len(toks) || !toks[i].word || isKeyword(toks[i].upper) {
+		return i
+	}
+	vt := VirtualTable{Name: toks[i].name()}
+	i++
+	if i < len(toks) && toks[i].upper == "(" {
+		end := closingParen(toks, i)
+		vt.Columns = columnList(toks[i+1 : end])
+		i = min(end+1, len(toks))
+	} else if body[0].upper != "VALUES" {
+		vt.Columns = p.projection(body)
+	}
+	p.tables = append(p.tables, vt)
+	return i
+}
+
+// closingParen returns the index of the parenthesis closing toks[open],
+// or len(toks) if the query ends first.
+func closingParen(toks []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(toks); i++ {
+		switch toks[i].upper {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(toks)
+}
+
+// columnList returns the names in an "(a, b, c)" column list.
+func columnList(toks []sqlToken) []string {
+	var cols []string
+	for _, t := range toks {
+		if t.word {
+			cols = append(cols, t.name())
+		}
+	}
+	return cols
+}
+
+// selectListEnd lists the keywords that end a select list.
+var selectListEnd = map[string]bool{
+	"FROM": true, "INTO": true, "WHERE": true, "GROUP": true, "HAVING": true,
+	"ORDER": true, "LIMIT": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "WINDOW": true, "OFFSET": true, "FETCH": true, ";": true,
+}
+
+// projection returns the output column names of a SELECT: aliases, plain
+// column names and the expansion of * and t.*.
+func (p *queryParser) projection(toks []sqlToken) []string {
+	// Skip a leading WITH list: the main SELECT is the first at depth 0
+	start := -1
+	depth := 0
+	for i, t := range toks {
+		switch t.upper {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case "SELECT":
+			if depth == 0 && start < 0 {
+				start = i + 1
+			}
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	if start < len(toks) && (toks[start].upper == "DISTINCT" || toks[start].upper == "ALL") {
+		start++
+		if start < len(toks) && toks[start].upper == "ON" && start+1 < len(toks) {
+			start = closingParen(toks, start+1) + 1
+		}
+	}
+
+	end := start
+	depth = 0
+	for end < len(toks) {
+		t := toks[end].upper
+		if t == "(" {
+			depth++
+		} else if t == ")" {
+			depth--
+		} else if depth == 0 && selectListEnd[t] {
+			break
+		}
+		end++
+	}
+
+	var refs []tableRef
+	if end < len(toks) && toks[end].upper == "FROM" {
+		var sb strings.Builder
+		for _, t := range toks[end:] {
+			sb.WriteString(t.text)
+			sb.WriteByte(' ')
+		}
+		refs = extractTableRefs(tokenize(sb.String()))
+	}
+
+	var cols []string
+	for _, item := range splitTopLevel(toks[start:min(end, len(toks))]) {
+		cols = append(cols, p.itemColumns(item, refs)...)
+	}
+	return cols
+}
+
+// itemColumns returns the column names one select-list item produces.
+func (p *queryParser) itemColumns(item []sqlToken, refs []tableRef) []string {
+	n := len(item)
+	if n == 0 {
+		return nil
+	}
+	last := item[n-1]
+	switch {
+	case last.text == "*":
+		if n > 1 {
+			return nil
+		}
+		var cols []string
+		for _, ref := range refs {
+			cols = append(cols, p.columns(ref.Name)...)
+		}
+		return cols
+	case strings.HasSuffix(last.text, ".*"):
+		qualifier := strings.ToLower(strings.TrimSuffix(last.text, ".*"))
+		return p.columns(resolveTableName(qualifier, refs))
+	case n >= 2 && item[n-2].upper == "AS":
+		return []string{last.name()}
+	case n == 1 && last.word:
+		return []string{nameAfterDot(last.name())}
+	case n >= 2 && last.word && !isKeyword(last.upper):
+		// "expr alias" without AS
+		prev := item[n-2]
+		if prev.word || prev.text == ")" || strings.HasPrefix(prev.text, "'") {
+			return []string{last.name()}
+		}
+	}
+	return nil
+}
+
+// splitTopLevel splits toks on the commas outside parentheses.
+func splitTopLevel(toks []sqlToken) [][]sqlToken {
+	var items [][]sqlToken
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch t.upper {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				items = append(items, toks[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, toks[start:])
+}
+
+// analyzeDML records the target table of an INSERT or UPDATE and where
+// the cursor is in an INSERT: in its column list, right after it, or in
+// an ON CONFLICT column list.
+func analyzeDML(ctx *SQLContext, text string) {
+	toks := lexSQL(text)
+	start := -1
+	for i, t := range toks {
+		// ON CONFLICT ... DO UPDATE keeps the INSERT target
+		doUpdate := i > 0 && toks[i-1].upper == "DO"
+		if t.word && (t.upper == "INSERT" || t.upper == "UPDATE" && !doUpdate) {
+			start = i
+		}
+	}
+	if start < 0 {
+		return
+	}
+	i := start + 1
+	if toks[start].upper == "INSERT" {
+		if i >= len(toks) || toks[i].upper != "INTO" {
+			return
+		}
+		i++
+	} else if i < len(toks) && toks[i].upper == "ONLY" {
+		i++
+	}
+	if i >= len(toks) || !toks[i].word || isKeyword(toks[i].upper) {
+		return
+	}
+	ctx.TargetTable = toks[i].name()
+	i++
+	if toks[start].upper == "UPDATE" {
+		return
+	}
+
+	// Still typing the table name
+	typing := text != "" && !unicode.IsSpace(rune(text[len(text)-1]))
+	if i == len(toks) && typing {
+		ctx.TargetTable = ""
+		return
+	}
+	if i+1 < len(toks) && toks[i].upper == "AS" {
+		i += 2
+	}
+	if i < len(toks) && toks[i].upper == "(" {
+		end := closingParen(toks, i)
+		if end == len(toks) {
+			ctx.InInsertColumns = true
+			return
+		}
+		i = end + 1
+	}
+	if i == len(toks) || (i == len(toks)-1 && typing && toks[i].word) {
+		ctx.AfterInsertColumns = true
+		return
+	}
+
+	for j := i; j+2 < len(toks); j++ {
+		if toks[j].upper == "ON" && toks[j+1].upper == "CONFLICT" && toks[j+2].upper == "(" {
+			if closingParen(toks, j+2) == len(toks) {
+				ctx.InConflictTarget = true
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package completion
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testColumnsOf(name string) []string {
+	switch name {
+	case "users":
+		return []string{"id", "name", "email"}
+	case "orders":
+		return []string{"id", "user_id", "total"}
+	}
+	return nil
+}
+
+func TestParseVirtualTables(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []VirtualTable
+	}{
+		{
+			"cte projection",
+			"WITH x AS (SELECT a, b AS bee, u.email, count(*) n FROM users u) SELECT ",
+			[]VirtualTable{{Name: "x", Columns: []string{"a", "bee", "email", "n"}, CTE: true}},
+		},
+		{
+			"cte column list",
+			"WITH RECURSIVE t(n, m) AS (SELECT 1, 2 UNION ALL SELECT n+1, m FROM t) SELECT ",
+			[]VirtualTable{{Name: "t", Columns: []string{"n", "m"}, CTE: true}},
+		},
+		{
+			"several ctes and star",
+			"WITH a AS MATERIALIZED (SELECT * FROM users), b AS (SELECT a.* FROM a) SELECT ",
+			[]VirtualTable{
+				{Name: "a", Columns: []string{"id", "name", "email"}, CTE: true},
+				{Name: "b", Columns: []string{"id", "name", "email"}, CTE: true},
+			},
+		},
+		{
+			"derived table",
+			"SELECT * FROM (SELECT id, total * 2 AS doubled FROM orders) AS sub JOIN users ON ",
+			[]VirtualTable{{Name: "sub", Columns: []string{"id", "doubled"}}},
+		},
+		{
+			"derived table column list and values",
+			"SELECT * FROM (VALUES (1, 'a')) v(num, letter), (SELECT DISTINCT ON (id) id FROM users) d",
+			[]VirtualTable{
+				{Name: "v", Columns: []string{"num", "letter"}},
+				{Name: "d", Columns: []string{"id"}},
+			},
+		},
+		{
+			"nested derived table",
+			"SELECT * FROM (SELECT s.id FROM (SELECT id FROM users) s) outer_q",
+			[]VirtualTable{
+				{Name: "s", Columns: []string{"id"}},
+				{Name: "outer_q", Columns: []string{"id"}},
+			},
+		},
+		{
+			"quoted names and comments",
+			`WITH "Totals" AS (SELECT /* sum */ sum(total) "Sum" -- per user
+			FROM orders) SELECT `,
+			[]VirtualTable{{Name: "Totals", Columns: []string{"Sum"}, CTE: true}},
+		},
+		{
+			"subquery in where is not a table",
+			"SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseVirtualTables(tt.sql, testColumnsOf)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplete_CTEColumns(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+
+	text := "WITH recent AS (SELECT id, total AS amount FROM orders) SELECT recent."
+	texts := suggestionTexts(c.Complete(text, len(text)))
+	if _, ok := texts["amount"]; !ok || len(texts) != 2 {
+		t.Errorf("expected CTE columns, got %v", texts)
+	}
+
+	text = "WITH recent AS (SELECT id FROM orders) SELECT * FROM rec"
+	suggestions := c.Complete(text, len(text))
+	if len(suggestions) == 0 || suggestions[0].Text != "recent" || suggestions[0].Description != "cte" {
+		t.Errorf("expected CTE name as a table, got %v", suggestions)
+	}
+
+	text = "WITH recent AS (SELECT id, quantity AS qty FROM orders) SELECT * FROM recent r WHERE r."
+	texts = suggestionTexts(c.Complete(text, len(text)))
+	if _, ok := texts["qty"]; !ok {
+		t.Errorf("alias of a CTE should resolve, got %v", texts)
+	}
+}
+
+func TestComplete_DerivedTableColumns(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+
+	text := "SELECT * FROM (SELECT id, email AS mail FROM users) sub WHERE sub."
+	texts := suggestionTexts(c.Complete(text, len(text)))
+	if _, ok := texts["mail"]; !ok {
+		t.Errorf("expected derived table columns, got %v", texts)
+	}
+	if _, ok := texts["name"]; ok {
+		t.Error("columns of the inner table should not leak into sub.")
+	}
+
+	text = "SELECT * FROM (SELECT id, email AS mail FROM users) sub WHERE ma"
+	found := false
+	for _, s := range c.Complete(text, len(text)) {
+		if s.Text == "mail" && strings.Contains(s.Description, "sub") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("derived table columns should be suggested unqualified")
+	}
+}
+
+func TestAnalyzeDML(t *testing.T) {
+	tests := []struct {
+		text                            string
+		target                          string
+		columns, afterColumns, conflict bool
+	}{
+		{"INSERT INTO us", "", false, false, false},
+		{"INSERT INTO users ", "users", false, true, false},
+		{"INSERT INTO users (", "users", true, false, false},
+		{"INSERT INTO users (id, na", "users", true, false, false},
+		{"INSERT INTO public.users AS u (id) ", "public.users", false, true, false},
+		{"INSERT INTO users (id) VAL", "users", false, true, false},
+		{"INSERT INTO users (id) VALUES (1) ON CONFLICT (", "users", false, false, true},
+		{"INSERT INTO users (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET ", "users", false, false, false},
+		{"UPDATE ONLY orders SET ", "orders", false, false, false},
+		{"SELECT * FROM users", "", false, false, false},
+	}
+	for _, tt := range tests {
+		var ctx SQLContext
+		analyzeDML(&ctx, tt.text)
+		if ctx.TargetTable != tt.target || ctx.InInsertColumns != tt.columns ||
+			ctx.AfterInsertColumns != tt.afterColumns || ctx.InConflictTarget != tt.conflict {
+			t.Errorf("analyzeDML(%q) = target %q columns %v after %v conflict %v",
+				tt.text, ctx.TargetTable, ctx.InInsertColumns, ctx.AfterInsertColumns, ctx.InConflictTarget)
+		}
+	}
+}
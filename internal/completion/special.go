@@ -0,0 +1,197 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArgKind says what a special command's argument names, so it can be
+// completed.
+type ArgKind int
+
+const (
+	ArgNone     ArgKind = iota
+	ArgRelation         // a table or view, optionally schema-qualified
+	ArgFunction
+	ArgSchema
+	ArgDatabase
+	ArgFavorite // a named query
+	ArgFile     // a path on the local filesystem
+	ArgChoice   // one of the command's Choices
+)
+
+// specialCommand finds the special command at the start of the line or
+// statement being typed once its name is complete, returning it and
+// whatever follows the name. A command ends at a newline or semicolon, so
+// SQL typed after "use db;" is not taken as its argument.
+func (c *Completer) specialCommand(text string) (SpecialCmd, string, bool) {
+	text = text[strings.LastIndexAny(text, "\n;")+1:]
+	text = strings.TrimLeft(text, " \t")
+	end := strings.IndexAny(text, " \t")
+	if end <= 0 {
+		return SpecialCmd{}, "", false
+	}
+	name, args := text[:end], text[end+1:]
+	if strings.HasPrefix(name, `\`) {
+		// \d+ and \dt+ take the same arguments as \d and \dt
+		if len(name) > 2 {
+			name = strings.TrimSuffix(name, "+")
+		}
+	} else {
+		name = strings.ToLower(name)
+	}
+	for _, s := range c.meta.Specials {
+		if s.Name == name {
+			return s, args, true
+		}
+		for _, alias := range s.Aliases {
+			if alias == name {
+				return s, args, true
+			}
+		}
+	}
+	return SpecialCmd{}, "", false
+}
+
+// splitArgs splits the arguments typed so far into the finished ones and
+// the one being typed, which is empty after trailing whitespace.
+func splitArgs(args string) (done []string, word string) {
+	done = strings.Fields(args)
+	if len(done) == 0 || strings.TrimRight(args, " \t\n") != args {
+		return done, ""
+	}
+	return done[:len(done)-1], done[len(done)-1]
+}
+
+// argumentSuggestions completes the argument being typed after cmd.
+func (c *Completer) argumentSuggestions(cmd SpecialCmd, done []string, word string) []Suggestion {
+	if cmd.Arg == ArgChoice {
+		return choiceSuggestions(cmd.Choices, done, word)
+	}
+	if cmd.Arg == ArgFile {
+		// Files may follow flags, as in tee -o file; \o |command is not one
+		if strings.HasPrefix(word, "|") {
+			return nil
+		}
+		return fileSuggestions(word)
+	}
+	// Everything else names one object, possibly after flags
+	for _, d := range done {
+		if !strings.HasPrefix(d, "-") {
+			return nil
+		}
+	}
+
+	var s []Suggestion
+	switch cmd.Arg {
+	case ArgRelation:
+		s = c.relationArgSuggestions(word)
+	case ArgFunction:
+		for _, f := range c.meta.Functions {
+			if fuzzyMatch(word, f) {
+				s = append(s, Suggestion{Text: f, Type: SuggestFunction, Description: c.functionDescription(f)})
+			}
+		}
+	case ArgSchema:
+		s = c.schemaSuggestions(word)
+	case ArgDatabase:
+		for _, db := range c.meta.Databases {
+			if fuzzyMatch(word, db) {
+				s = append(s, Suggestion{Text: db, Type: SuggestDatabase, Description: "database"})
+			}
+		}
+	case ArgFavorite:
+		for _, f := range c.meta.Favorites {
+			if fuzzyMatch(word, f) {
+				s = append(s, Suggestion{Text: f, Type: SuggestFavorite, Description: "named query"})
+			}
+		}
+	}
+	return s
+}
+
+// relationArgSuggestions offers tables and views the way \d patterns name
+// them: bare when on the search path, otherwise schema-qualified, and
+// always qualified once the pattern names a schema.
+func (c *Completer) relationArgSuggestions(word string) []Suggestion {
+	qualified := strings.Contains(word, ".")
+	var s []Suggestion
+	add := func(rels []*Relation, visible bool) {
+		for _, rel := range rels {
+			text := rel.Name
+			if qualified || !visible {
+				text = rel.QualifiedName()
+			}
+			if fuzzyMatch(word, text) || (!qualified && fuzzyMatch(word, rel.Name)) {
+				s = append(s, relationSuggestion(rel, text))
+			}
+		}
+	}
+	add(c.meta.VisibleRelations(), true)
+	add(c.meta.HiddenRelations(), false)
+	return s
+}
+
+// choiceSuggestions offers the next word of each choice whose earlier
+// words match those already typed, so "format" then "unicode" can be
+// completed in turn from the choice "format unicode".
+func choiceSuggestions(choices []string, done []string, word string) []Suggestion {
+	seen := make(map[string]bool)
+	var s []Suggestion
+	for _, choice := range choices {
+		words := strings.Fields(choice)
+		if len(words) <= len(done) {
+			continue
+		}
+		matches := true
+		for i, d := range done {
+			if !strings.EqualFold(words[i], d) {
+				matches = false
+				break
+			}
+		}
+		next := words[len(done)]
+		if matches && !seen[next] && fuzzyMatch(word, next) {
+			seen[next] = true
+			s = append(s, Suggestion{Text: next, Type: SuggestKeyword, Description: "option"})
+		}
+	}
+	return s
+}
+
+// fileSuggestions lists the entries of the directory word is in whose
+// names start with what follows its last slash. Hidden entries are only
+// offered once a dot is typed.
+func fileSuggestions(word string) []Suggestion {
+	dir, prefix := filepath.Split(word)
+	path := dir
+	if path == "" {
+		path = "."
+	} else if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+
+	var s []Suggestion
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		desc := "file"
+		if e.IsDir() {
+			name += string(filepath.Separator)
+			desc = "directory"
+		}
+		s = append(s, Suggestion{Text: dir + name, Type: SuggestFile, Description: desc})
+	}
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Text < s[j].Text })
+	return s
+}
@@ -0,0 +1,182 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		done int
+		word string
+	}{
+		{"", 0, ""},
+		{"us", 0, "us"},
+		{"format ", 1, ""},
+		{"format uni", 1, "uni"},
+		{"-o  out", 1, "out"},
+	}
+	for _, tt := range tests {
+		done, word := splitArgs(tt.args)
+		if len(done) != tt.done || word != tt.word {
+			t.Errorf("splitArgs(%q) = %v, %q; want %d done, %q", tt.args, done, word, tt.done, tt.word)
+		}
+	}
+}
+
+func TestComplete_SpecialRelationArgs(t *testing.T) {
+	meta := schemaMetadata()
+	meta.Specials = []SpecialCmd{{Name: `\d`, Arg: ArgRelation}}
+	c := NewCompleter(meta, true)
+
+	texts := suggestionTexts(c.Complete(`\d us`, 5))
+	if _, ok := texts["users"]; !ok {
+		t.Errorf("\\d should complete tables on the search path, got %v", texts)
+	}
+	texts = suggestionTexts(c.Complete(`\d+ `, 4))
+	if _, ok := texts["auth.sessions"]; !ok {
+		t.Errorf("\\d+ should complete relations outside the search path qualified, got %v", texts)
+	}
+	texts = suggestionTexts(c.Complete(`\d public.us`, 12))
+	if _, ok := texts["public.users"]; !ok {
+		t.Errorf("a schema-qualified pattern should complete qualified names, got %v", texts)
+	}
+	if len(c.Complete(`\d users `, 9)) != 0 {
+		t.Error("\\d takes a single pattern")
+	}
+}
+
+func TestComplete_SpecialCommandEndsStatement(t *testing.T) {
+	meta := testMetadata()
+	meta.Specials = append(meta.Specials,
+		SpecialCmd{Name: `\u`, Aliases: []string{"use"}, Arg: ArgDatabase},
+	)
+	c := NewCompleter(meta, true)
+
+	for _, text := range []string{"use mydb; SELECT * FROM ", "\\u mydb\nSELECT * FROM "} {
+		texts := suggestionTexts(c.Complete(text, len(text)))
+		if _, ok := texts["users"]; !ok {
+			t.Errorf("SQL after %q should complete tables, got %v", text, texts)
+		}
+	}
+	texts := suggestionTexts(c.Complete("SELECT 1;\nuse te", 16))
+	if _, ok := texts["testdb"]; !ok {
+		t.Errorf("a command after earlier SQL should complete its argument, got %v", texts)
+	}
+}
+
+func TestComplete_SpecialArgKinds(t *testing.T) {
+	meta := testMetadata()
+	meta.Specials = append(meta.Specials,
+		SpecialCmd{Name: `\c`, Arg: ArgDatabase},
+		SpecialCmd{Name: `\sf`, Arg: ArgFunction},
+		SpecialCmd{Name: `\f`, Arg: ArgFavorite},
+		SpecialCmd{Name: `\u`, Aliases: []string{"use"}, Arg: ArgDatabase},
+	)
+	c := NewCompleter(meta, true)
+
+	tests := []struct {
+		input string
+		want  string
+		typ   SuggestionType
+	}{
+		{`\c prod`, "production", SuggestDatabase},
+		{`\sf coa`, "coalesce", SuggestFunction},
+		{`\f dai`, "daily_report", SuggestFavorite},
+		{`\dn au`, "auth", SuggestSchema},
+		{`\df cou`, "count", SuggestFunction},
+		{`\x a`, "auto", SuggestKeyword},
+		{`USE test`, "testdb", SuggestDatabase},
+	}
+	for _, tt := range tests {
+		got, ok := suggestionTexts(c.Complete(tt.input, len(tt.input)))[tt.want]
+		if !ok {
+			t.Errorf("%q should suggest %q", tt.input, tt.want)
+			continue
+		}
+		if got.Type != tt.typ {
+			t.Errorf("%q: %q has type %v, want %v", tt.input, tt.want, got.Type, tt.typ)
+		}
+	}
+
+	if s := c.Complete(`\du `, 4); len(s) != 0 {
+		t.Errorf("commands without an argument kind should not complete, got %v", s)
+	}
+	if _, ok := suggestionTexts(c.Complete(`\d`, 2))[`\dt`]; !ok {
+		t.Error("an unfinished command name should still complete commands")
+	}
+}
+
+func TestComplete_SpecialChoices(t *testing.T) {
+	meta := NewMetadata()
+	meta.Specials = []SpecialCmd{{
+		Name:    `\pset`,
+		Arg:     ArgChoice,
+		Choices: []string{"null", "format ascii", "format unicode", "expanded on"},
+	}}
+	c := NewCompleter(meta, true)
+
+	texts := suggestionTexts(c.Complete(`\pset `, 6))
+	for _, want := range []string{"null", "format", "expanded"} {
+		if _, ok := texts[want]; !ok {
+			t.Errorf("\\pset should offer %q, got %v", want, texts)
+		}
+	}
+	if len(texts) != 3 {
+		t.Errorf("each option should be offered once, got %v", texts)
+	}
+	texts = suggestionTexts(c.Complete(`\pset format uni`, 16))
+	if _, ok := texts["unicode"]; !ok || len(texts) != 1 {
+		t.Errorf("\\pset format should offer the formats, got %v", texts)
+	}
+	if len(c.Complete(`\pset null `, 11)) != 0 {
+		t.Error("free-form values should not be completed")
+	}
+}
+
+func TestComplete_SpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"setup.sql", "seed.sql", ".hidden.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := NewMetadata()
+	meta.Specials = []SpecialCmd{
+		{Name: `\i`, Arg: ArgFile},
+		{Name: "tee", Arg: ArgFile},
+		{Name: `\o`, Arg: ArgFile},
+	}
+	c := NewCompleter(meta, true)
+
+	input := `\i ` + dir + "/se"
+	texts := suggestionTexts(c.Complete(input, len(input)))
+	for _, want := range []string{"setup.sql", "seed.sql"} {
+		if s, ok := texts[filepath.Join(dir, want)]; !ok || s.Type != SuggestFile {
+			t.Errorf("%q should suggest %s, got %v", input, want, texts)
+		}
+	}
+
+	input = `\i ` + dir + "/"
+	texts = suggestionTexts(c.Complete(input, len(input)))
+	if s, ok := texts[filepath.Join(dir, "scripts")+"/"]; !ok || s.Description != "directory" {
+		t.Errorf("directories should be offered with a trailing slash, got %v", texts)
+	}
+	if _, ok := texts[filepath.Join(dir, ".hidden.sql")]; ok {
+		t.Error("hidden files should not be offered until a dot is typed")
+	}
+
+	input = "tee -o " + dir + "/set"
+	if _, ok := suggestionTexts(c.Complete(input, len(input)))[filepath.Join(dir, "setup.sql")]; !ok {
+		t.Error("file arguments should complete after flags")
+	}
+	if len(c.Complete(`\o |le`, 6)) != 0 {
+		t.Error("\\o |command should not complete files")
+	}
+}
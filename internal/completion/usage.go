@@ -0,0 +1,114 @@
+package completion
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxUsageNames caps how many names Save keeps, dropping the least used,
+// so the file does not grow with every name ever typed.
+const maxUsageNames = 2000
+
+// Usage counts how often names (tables, columns, keywords, functions)
+// appear in executed statements. The counts rank completions.
+type Usage struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewUsage creates empty usage counts.
+func NewUsage() *Usage {
+	return &Usage{counts: make(map[string]int)}
+}
+
+// Record counts the names used in sql for which known returns true; a nil
+// known counts every name. Each part of a dotted name counts, so
+// "public.users" counts both public and users.
+func (u *Usage) Record(sql string, known func(name string) bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, t := range lexSQL(sql) {
+		if !t.word {
+			continue
+		}
+		for _, part := range strings.Split(t.name(), ".") {
+			if part != "" && part != "*" && (known == nil || known(part)) {
+				u.counts[strings.ToLower(part)]++
+			}
+		}
+	}
+}
+
+// Count returns how often name has been used. Completion texts such as
+// "count()" and "schema.table" are reduced to the name.
+func (u *Usage) Count(name string) int {
+	if u == nil {
+		return 0
+	}
+	name = strings.ToLower(strings.TrimSuffix(nameAfterDot(name), "()"))
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.counts[name]
+}
+
+// LoadUsage reads counts saved by Save. A missing file gives empty counts.
+func LoadUsage(path string) (*Usage, error) {
+	u := NewUsage()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	if err := json.Unmarshal(data, &u.counts); err != nil {
+		return NewUsage(), err
+	}
+	if u.counts == nil {
+		u.counts = make(map[string]int)
+	}
+	return u, nil
+}
+
+// Save writes the counts of the most used names to path as JSON.
+func (u *Usage) Save(path string) error {
+	u.mu.Lock()
+	u.prune(maxUsageNames)
+	data, err := json.Marshal(u.counts)
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// prune drops all but the max most used names. Callers hold u.mu.
+func (u *Usage) prune(max int) {
+	if len(u.counts) <= max {
+		return
+	}
+	names := make([]string, 0, len(u.counts))
+	for name := range u.counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if u.counts[names[i]] != u.counts[names[j]] {
+			return u.counts[names[i]] > u.counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names[max:] {
+		delete(u.counts, name)
+	}
+}
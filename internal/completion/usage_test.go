@@ -0,0 +1,119 @@
+package completion
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestUsage_Record(t *testing.T) {
+	u := NewUsage()
+	u.Record("SELECT o.total FROM public.orders o WHERE o.status = 'users'", nil)
+	u.Record("select count(*) from orders", nil)
+
+	tests := map[string]int{
+		"orders":   2,
+		"ORDERS":   2,
+		"total":    1,
+		"public":   1,
+		"count()":  1,
+		"SELECT":   2,
+		"users":    0, // strings do not count
+		"products": 0,
+	}
+	for name, want := range tests {
+		if got := u.Count(name); got != want {
+			t.Errorf("Count(%q) = %d, want %d", name, got, want)
+		}
+	}
+	if got := u.Count("public.orders"); got != 2 {
+		t.Errorf("qualified names count by name, got %d", got)
+	}
+
+	var nilUsage *Usage
+	if nilUsage.Count("orders") != 0 {
+		t.Error("nil usage should count nothing")
+	}
+}
+
+func TestCompleter_RecordUsage(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+	u := NewUsage()
+	c.SetUsage(u)
+	c.RecordUsage("SELECT o.total, count(*) FROM public.orders o JOIN ordres x ON true")
+
+	for _, name := range []string{"SELECT", "total", "count()", "public", "orders"} {
+		if u.Count(name) != 1 {
+			t.Errorf("expected %q to be counted", name)
+		}
+	}
+	for _, name := range []string{"o", "x", "ordres"} {
+		if u.Count(name) != 0 {
+			t.Errorf("aliases and typos should not be counted, got %q", name)
+		}
+	}
+}
+
+func TestUsage_SaveKeepsMostUsed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.usage")
+	u := NewUsage()
+	for i := 0; i <= maxUsageNames; i++ {
+		u.Record(fmt.Sprintf("t%d", i), nil)
+	}
+	u.Record("orders orders", nil)
+	if err := u.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage: %v", err)
+	}
+	if len(loaded.counts) != maxUsageNames {
+		t.Errorf("expected %d names kept, got %d", maxUsageNames, len(loaded.counts))
+	}
+	if loaded.Count("orders") != 2 {
+		t.Error("the most used name should be kept")
+	}
+}
+
+func TestUsage_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.usage")
+
+	u, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("missing file should not error: %v", err)
+	}
+	u.Record("SELECT * FROM orders", nil)
+	if err := u.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage: %v", err)
+	}
+	if loaded.Count("orders") != 1 {
+		t.Errorf("expected saved count, got %d", loaded.Count("orders"))
+	}
+}
+
+func TestComplete_RanksByUsage(t *testing.T) {
+	c := NewCompleter(testMetadata(), true)
+	u := NewUsage()
+	u.Record("SELECT * FROM user_settings", nil)
+	u.Record("SELECT * FROM user_settings", nil)
+	c.SetUsage(u)
+
+	// users is a shorter prefix match, but user_settings is used more
+	suggestions := c.Complete("SELECT * FROM user", 18)
+	if len(suggestions) == 0 || suggestions[0].Text != "user_settings" {
+		t.Errorf("expected the frequently used table first, got %v", suggestions)
+	}
+
+	// With nothing typed, usage orders the tables
+	suggestions = c.Complete("SELECT * FROM ", 14)
+	if len(suggestions) == 0 || suggestions[0].Text != "user_settings" {
+		t.Errorf("expected the frequently used table first, got %v", suggestions)
+	}
+}
@@ -10,46 +10,68 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tomblomfield/gocli/internal/classify"
 )
 
 // Config holds all configuration settings.
 type Config struct {
 	// Main settings
-	SmartCompletion  bool
-	MultiLine        bool
-	MultiLineMode    string // "psql" or "safe"
-	ViMode           bool
-	KeyBindings      string // "emacs" or "vi"
-	Timing           bool
-	Pager            string
-	EnablePager      bool
-	TableFormat      string
-	SyntaxStyle      string
-	ExpandedOutput   bool
-	AutoExpand       bool
-	OnError          string // "STOP" or "RESUME"
-	RowLimit         int
-	MaxFieldWidth    int
-	LessChatty       bool
-	KeywordCasing    string // "auto", "upper", "lower"
-	NullString       string
-	Prompt           string
+	SmartCompletion    bool
+	MultiLine          bool
+	MultiLineMode      string // "psql" or "safe"
+	ViMode             bool
+	KeyBindings        string // "emacs" or "vi"
+	Timing             bool
+	Pager              string
+	EnablePager        bool
+	TableFormat        string
+	SyntaxStyle        string
+	Color              string // "auto", "always" or "never"
+	ExpandedOutput     bool
+	AutoExpand         bool
+	OnError            string // "STOP" or "RESUME"
+	RowLimit           int
+	MaxFieldWidth      int
+	LessChatty         bool
+	ReadOnly           bool          // sessions are read-only and writes are refused
+	InitCommand        string        // SQL each new connection runs before any other
+	StatementTimeout   time.Duration // 0 lets statements run as long as they take
+	LockTimeout        time.Duration // 0 waits for locks as the server does
+	KeywordCasing      string        // "auto", "upper", "lower"
+	NullString         string
+	NullStringCSV      string
+	NullStringJSON     string
+	Prompt             string
 	PromptContinuation string
-	WiderCompletion  bool
-	ShowToolbar      bool
-	LogFile          string
-	LogLevel         string
-	HistoryFile      string
-
-	// Destructive warnings
-	DestructiveWarning     bool
-	DestructiveKeywords    []string
+	WiderCompletion    bool
+	ShowToolbar        bool
+	LogFile            string // audit log of every statement run; empty for none
+	LogLevel           string // "ERROR" logs only failures, "NONE" nothing
+	LogFormat          string // "text" or "json"
+	HistoryFile        string
+	CompletionCacheDir string // per-DSN completion metadata cache; "" disables
+
+	// Value display
+	FloatPrecision     int // digits after the decimal mark; -1 shows values as returned
+	ThousandsSeparator string
+	DecimalMark        string
+	DateFormat         string // Go time layout, e.g. "02/01/2006"
+	TimestampFormat    string // Go time layout
+	TimeZone           string // IANA zone for timestamptz display
+
+	// Destructive warnings: statements starting with a keyword are
+	// confirmed first, UPDATE and DELETE only without a WHERE clause
+	DestructiveWarning  bool
+	DestructiveKeywords []string
 
 	// Named queries / favorites
 	NamedQueries map[string]string
 
-	// DSN aliases
-	DSNAliases map[string]string
+	// DSN aliases, and the [alias_dsn.NAME] options that override [main]
+	// when connecting through an alias
+	DSNAliases   map[string]string
+	AliasOptions map[string]map[string]string
 
 	// Color settings
 	Colors map[string]string
@@ -64,32 +86,37 @@ func DefaultPGConfig() *Config {
 	configDir := filepath.Join(home, ".config", "pgcli")
 
 	return &Config{
-		SmartCompletion:  true,
-		MultiLine:        false,
-		MultiLineMode:    "psql",
-		ViMode:           false,
-		KeyBindings:      "emacs",
-		Timing:           true,
-		EnablePager:      true,
-		Pager:            "less -SRXF",
-		TableFormat:      "psql",
-		SyntaxStyle:      "default",
-		OnError:          "STOP",
-		RowLimit:         1000,
-		MaxFieldWidth:    500,
-		KeywordCasing:    "auto",
-		NullString:       "NULL",
-		Prompt:           `\u@\h:\d> `,
-		ShowToolbar:      true,
-		LogLevel:         "INFO",
-		HistoryFile:      filepath.Join(configDir, "history"),
-		LogFile:          filepath.Join(configDir, "log"),
-		DestructiveWarning: true,
+		SmartCompletion:     true,
+		MultiLine:           false,
+		MultiLineMode:       "psql",
+		ViMode:              false,
+		KeyBindings:         "emacs",
+		Timing:              true,
+		EnablePager:         true,
+		Pager:               "less -SRXF",
+		TableFormat:         "psql",
+		SyntaxStyle:         "default",
+		Color:               "auto",
+		OnError:             "STOP",
+		RowLimit:            1000,
+		MaxFieldWidth:       500,
+		KeywordCasing:       "auto",
+		NullString:          "NULL",
+		NullStringJSON:      "null",
+		FloatPrecision:      -1,
+		Prompt:              `\u@\h:\d> `,
+		ShowToolbar:         true,
+		LogLevel:            "INFO",
+		LogFormat:           "text",
+		HistoryFile:         filepath.Join(configDir, "history"),
+		CompletionCacheDir:  filepath.Join(configDir, "completion_cache"),
+		DestructiveWarning:  true,
 		DestructiveKeywords: []string{"drop", "shutdown", "delete", "truncate", "alter", "update"},
-		NamedQueries:     make(map[string]string),
-		DSNAliases:       make(map[string]string),
-		Colors:           make(map[string]string),
-		filePath:         filepath.Join(configDir, "config"),
+		NamedQueries:        make(map[string]string),
+		DSNAliases:          make(map[string]string),
+		AliasOptions:        make(map[string]map[string]string),
+		Colors:              make(map[string]string),
+		filePath:            filepath.Join(configDir, "config"),
 	}
 }
 
@@ -98,33 +125,38 @@ func DefaultMySQLConfig() *Config {
 	home, _ := os.UserHomeDir()
 
 	return &Config{
-		SmartCompletion:  true,
-		MultiLine:        false,
-		MultiLineMode:    "psql",
-		ViMode:           false,
-		KeyBindings:      "emacs",
-		Timing:           true,
-		EnablePager:      true,
-		Pager:            "less -SRXF",
-		TableFormat:      "ascii",
-		SyntaxStyle:      "default",
-		OnError:          "STOP",
-		RowLimit:         1000,
-		MaxFieldWidth:    500,
-		KeywordCasing:    "auto",
-		NullString:       "NULL",
-		Prompt:           `\t \u@\h:\d> `,
-		PromptContinuation: "-> ",
-		ShowToolbar:      true,
-		LogLevel:         "INFO",
-		HistoryFile:      filepath.Join(home, ".mycli-history"),
-		LogFile:          filepath.Join(home, ".mycli.log"),
-		DestructiveWarning: true,
+		SmartCompletion:     true,
+		MultiLine:           false,
+		MultiLineMode:       "psql",
+		ViMode:              false,
+		KeyBindings:         "emacs",
+		Timing:              true,
+		EnablePager:         true,
+		Pager:               "less -SRXF",
+		TableFormat:         "ascii",
+		SyntaxStyle:         "default",
+		Color:               "auto",
+		OnError:             "STOP",
+		RowLimit:            1000,
+		MaxFieldWidth:       500,
+		KeywordCasing:       "auto",
+		NullString:          "NULL",
+		NullStringJSON:      "null",
+		FloatPrecision:      -1,
+		Prompt:              `\t \u@\h:\d> `,
+		PromptContinuation:  "-> ",
+		ShowToolbar:         true,
+		LogLevel:            "INFO",
+		LogFormat:           "text",
+		HistoryFile:         filepath.Join(home, ".mycli-history"),
+		CompletionCacheDir:  filepath.Join(home, ".mycli-completion-cache"),
+		DestructiveWarning:  true,
 		DestructiveKeywords: []string{"drop", "shutdown", "delete", "truncate", "alter", "update"},
-		NamedQueries:     make(map[string]string),
-		DSNAliases:       make(map[string]string),
-		Colors:           make(map[string]string),
-		filePath:         filepath.Join(home, ".myclirc"),
+		NamedQueries:        make(map[string]string),
+		DSNAliases:          make(map[string]string),
+		AliasOptions:        make(map[string]map[string]string),
+		Colors:              make(map[string]string),
+		filePath:            filepath.Join(home, ".myclirc"),
 	}
 }
 
@@ -212,6 +244,13 @@ func (c *Config) Save() error {
 		fmt.Fprintf(w, "pager = %s\n", c.Pager)
 	}
 	fmt.Fprintf(w, "less_chatty = %s\n", boolStr(c.LessChatty))
+	fmt.Fprintf(w, "read_only = %s\n", boolStr(c.ReadOnly))
+	if c.StatementTimeout > 0 {
+		fmt.Fprintf(w, "statement_timeout = %s\n", c.StatementTimeout)
+	}
+	if c.LockTimeout > 0 {
+		fmt.Fprintf(w, "lock_timeout = %s\n", c.LockTimeout)
+	}
 	fmt.Fprintf(w, "prompt = %s\n", c.Prompt)
 	if c.PromptContinuation != "" {
 		fmt.Fprintf(w, "prompt_continuation = %s\n", c.PromptContinuation)
@@ -219,6 +258,7 @@ func (c *Config) Save() error {
 	fmt.Fprintf(w, "null_string = %s\n", c.NullString)
 	fmt.Fprintf(w, "destructive_warning = %s\n", boolStr(c.DestructiveWarning))
 	fmt.Fprintf(w, "log_level = %s\n", c.LogLevel)
+	fmt.Fprintf(w, "log_format = %s\n", c.LogFormat)
 	fmt.Fprintln(w)
 
 	// Named queries
@@ -238,6 +278,13 @@ func (c *Config) Save() error {
 		}
 		fmt.Fprintln(w)
 	}
+	for name, options := range c.AliasOptions {
+		fmt.Fprintf(w, "[alias_dsn.%s]\n", name)
+		for key, value := range options {
+			fmt.Fprintf(w, "%s = %s\n", key, value)
+		}
+		fmt.Fprintln(w)
+	}
 
 	return w.Flush()
 }
@@ -277,6 +324,13 @@ func (c *Config) parse(f *os.File) error {
 			c.DSNAliases[key] = value
 		case "colors":
 			c.Colors[key] = value
+		default:
+			if name, ok := strings.CutPrefix(section, "alias_dsn."); ok {
+				if c.AliasOptions[name] == nil {
+					c.AliasOptions[name] = make(map[string]string)
+				}
+				c.AliasOptions[name][key] = value
+			}
 		}
 	}
 
@@ -306,6 +360,8 @@ func (c *Config) parseMainOption(key, value string) {
 		c.TableFormat = value
 	case "syntax_style":
 		c.SyntaxStyle = value
+	case "color":
+		c.Color = strings.ToLower(value)
 	case "expand", "expanded_output":
 		c.ExpandedOutput = parseBool(value)
 	case "auto_expand", "auto_vertical_output":
@@ -322,10 +378,38 @@ func (c *Config) parseMainOption(key, value string) {
 		}
 	case "less_chatty":
 		c.LessChatty = parseBool(value)
+	case "read_only", "readonly":
+		c.ReadOnly = parseBool(value)
+	case "statement_timeout":
+		if d, err := ParseTimeout(value); err == nil {
+			c.StatementTimeout = d
+		}
+	case "lock_timeout":
+		if d, err := ParseTimeout(value); err == nil {
+			c.LockTimeout = d
+		}
 	case "keyword_casing":
 		c.KeywordCasing = value
 	case "null_string":
 		c.NullString = value
+	case "null_string_csv":
+		c.NullStringCSV = value
+	case "null_string_json":
+		c.NullStringJSON = value
+	case "float_precision":
+		if n, err := strconv.Atoi(value); err == nil {
+			c.FloatPrecision = n
+		}
+	case "thousands_separator":
+		c.ThousandsSeparator = value
+	case "decimal_mark":
+		c.DecimalMark = value
+	case "date_format":
+		c.DateFormat = value
+	case "timestamp_format":
+		c.TimestampFormat = value
+	case "timezone", "time_zone":
+		c.TimeZone = value
 	case "prompt":
 		c.Prompt = value
 	case "prompt_continuation":
@@ -338,8 +422,12 @@ func (c *Config) parseMainOption(key, value string) {
 		c.LogFile = value
 	case "log_level":
 		c.LogLevel = value
+	case "log_format":
+		c.LogFormat = value
 	case "history_file":
 		c.HistoryFile = value
+	case "completion_cache_dir":
+		c.CompletionCacheDir = value
 	case "destructive_warning":
 		c.DestructiveWarning = parseBool(value)
 	case "destructive_keywords":
@@ -347,6 +435,35 @@ func (c *Config) parseMainOption(key, value string) {
 	}
 }
 
+// ApplyAlias applies the options of the [alias_dsn.NAME] section, so a
+// connection through the alias can, for example, be read-only.
+func (c *Config) ApplyAlias(name string) {
+	for key, value := range c.AliasOptions[name] {
+		c.parseMainOption(key, value)
+	}
+}
+
+// ParseTimeout parses a timeout such as "30s" or "1m30s". A bare number
+// is seconds, and "0", "off" or "none" turn the timeout off.
+func ParseTimeout(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "0", "off", "none":
+		return 0, nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		s = strconv.FormatFloat(n, 'f', -1, 64) + "s"
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: use a duration such as 30s or 1m", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must not be negative", s)
+	}
+	return d, nil
+}
+
 func stripQuotes(s string) string {
 	if len(s) >= 2 {
 		if (s[0] == '\'' && s[len(s)-1] == '\'') || (s[0] == '"' && s[len(s)-1] == '"') {
@@ -410,21 +527,18 @@ func FormatPrompt(promptFmt string, user, host, database, port string, isSuperus
 	return result
 }
 
-// IsDestructive checks if a query matches destructive keywords.
+// IsDestructive reports whether query does something destructive_keywords
+// asks to confirm.
 func (c *Config) IsDestructive(query string) bool {
+	return len(c.DestructiveWarnings(query)) > 0
+}
+
+// DestructiveWarnings returns what query does that destructive_keywords
+// asks to confirm, naming the objects affected. UPDATE and DELETE only
+// warn without a WHERE clause.
+func (c *Config) DestructiveWarnings(query string) []classify.Warning {
 	if !c.DestructiveWarning {
-		return false
-	}
-	upper := strings.ToUpper(strings.TrimSpace(query))
-	words := strings.Fields(upper)
-	if len(words) == 0 {
-		return false
-	}
-	firstWord := strings.ToLower(words[0])
-	for _, kw := range c.DestructiveKeywords {
-		if firstWord == strings.ToLower(kw) {
-			return true
-		}
+		return nil
 	}
-	return false
+	return classify.Warnings(query, c.DestructiveKeywords)
 }
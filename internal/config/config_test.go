@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultPGConfig(t *testing.T) {
@@ -77,7 +78,14 @@ destructive_warning = False
 enable_pager = False
 pager = less -SRXF
 log_level = DEBUG
+log_format = json
+read_only = True
 max_field_width = 200
+null_string_csv = \N
+float_precision = 3
+thousands_separator = ,
+timezone = UTC
+color = Never
 
 [named queries]
 active = SELECT * FROM users WHERE active = true
@@ -144,9 +152,30 @@ keyword = ansibrightblue
 	if cfg.LogLevel != "DEBUG" {
 		t.Errorf("log_level should be 'DEBUG', got %q", cfg.LogLevel)
 	}
+	if cfg.LogFormat != "json" {
+		t.Errorf("log_format should be 'json', got %q", cfg.LogFormat)
+	}
+	if !cfg.ReadOnly {
+		t.Error("read_only should be true")
+	}
 	if cfg.MaxFieldWidth != 200 {
 		t.Errorf("max_field_width should be 200, got %d", cfg.MaxFieldWidth)
 	}
+	if cfg.NullStringCSV != `\N` {
+		t.Errorf("null_string_csv should be '\\N', got %q", cfg.NullStringCSV)
+	}
+	if cfg.FloatPrecision != 3 {
+		t.Errorf("float_precision should be 3, got %d", cfg.FloatPrecision)
+	}
+	if cfg.ThousandsSeparator != "," {
+		t.Errorf("thousands_separator should be ',', got %q", cfg.ThousandsSeparator)
+	}
+	if cfg.Color != "never" {
+		t.Errorf("color should be 'never', got %q", cfg.Color)
+	}
+	if cfg.TimeZone != "UTC" {
+		t.Errorf("timezone should be 'UTC', got %q", cfg.TimeZone)
+	}
 
 	// Named queries
 	if len(cfg.NamedQueries) != 2 {
@@ -257,6 +286,114 @@ func TestConfigSave(t *testing.T) {
 	}
 }
 
+func TestConfigLoad_AliasOptions(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config")
+	content := `[main]
+row_limit = 500
+
+[alias_dsn]
+prod = postgres://prod/app
+staging = postgres://staging/app
+
+[alias_dsn.prod]
+read_only = True
+row_limit = 100
+`
+	os.WriteFile(cfgPath, []byte(content), 0644)
+
+	cfg := DefaultPGConfig()
+	if err := cfg.Load(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReadOnly || cfg.DSNAliases["prod"] != "postgres://prod/app" {
+		t.Fatalf("alias options should not apply until the alias is used: %v, %v", cfg.ReadOnly, cfg.DSNAliases)
+	}
+
+	cfg.ApplyAlias("staging")
+	if cfg.ReadOnly || cfg.RowLimit != 500 {
+		t.Errorf("an alias without options should keep [main], got %v, %d", cfg.ReadOnly, cfg.RowLimit)
+	}
+	cfg.ApplyAlias("prod")
+	if !cfg.ReadOnly || cfg.RowLimit != 100 {
+		t.Errorf("[alias_dsn.prod] should override [main], got %v, %d", cfg.ReadOnly, cfg.RowLimit)
+	}
+
+	cfg.filePath = filepath.Join(dir, "saved")
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved := DefaultPGConfig()
+	saved.Load(filepath.Join(dir, "saved"))
+	if saved.AliasOptions["prod"]["read_only"] != "True" {
+		t.Errorf("alias options should survive a save, got %v", saved.AliasOptions)
+	}
+}
+
+func TestConfigLoad_Timeouts(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config")
+	content := `[main]
+statement_timeout = 5m
+lock_timeout = 2
+
+[alias_dsn]
+prod = postgres://prod/app
+
+[alias_dsn.prod]
+statement_timeout = 30s
+`
+	os.WriteFile(cfgPath, []byte(content), 0644)
+
+	cfg := DefaultPGConfig()
+	if err := cfg.Load(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StatementTimeout != 5*time.Minute || cfg.LockTimeout != 2*time.Second {
+		t.Errorf("got statement_timeout %v, lock_timeout %v", cfg.StatementTimeout, cfg.LockTimeout)
+	}
+	cfg.ApplyAlias("prod")
+	if cfg.StatementTimeout != 30*time.Second {
+		t.Errorf("[alias_dsn.prod] should set statement_timeout, got %v", cfg.StatementTimeout)
+	}
+
+	cfg.filePath = filepath.Join(dir, "saved")
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved := DefaultPGConfig()
+	saved.Load(filepath.Join(dir, "saved"))
+	if saved.StatementTimeout != 30*time.Second || saved.LockTimeout != 2*time.Second {
+		t.Errorf("timeouts should survive a save, got %v, %v", saved.StatementTimeout, saved.LockTimeout)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"30s", 30 * time.Second, false},
+		{"1m30s", 90 * time.Second, false},
+		{"500ms", 500 * time.Millisecond, false},
+		{"10", 10 * time.Second, false},
+		{"1.5", 1500 * time.Millisecond, false},
+		{"0", 0, false},
+		{"off", 0, false},
+		{"OFF", 0, false},
+		{"", 0, false},
+		{"soon", 0, true},
+		{"-5s", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeout(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseTimeout(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
 func TestConfigLoad_BoolParsing(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, "config")
@@ -333,11 +470,11 @@ table_format = grid
 
 func TestFormatPrompt(t *testing.T) {
 	tests := []struct {
-		format      string
-		user, host  string
-		db, port    string
-		superuser   bool
-		expected    string
+		format     string
+		user, host string
+		db, port   string
+		superuser  bool
+		expected   string
 	}{
 		{`\u@\h:\d> `, "alice", "localhost", "mydb", "5432", false, "alice@localhost:mydb> "},
 		{`\u@\h:\d# `, "postgres", "server", "production", "5432", false, "postgres@server:production# "},
@@ -370,6 +507,8 @@ func TestIsDestructive(t *testing.T) {
 		{"TRUNCATE users", true},
 		{"ALTER TABLE users ADD COLUMN", true},
 		{"UPDATE users SET name = 'test'", true},
+		{"UPDATE users SET name = 'test' WHERE id = 5", false},
+		{"WITH old AS (SELECT 1) DELETE FROM users", true},
 		{"SELECT * FROM users", false},
 		{"INSERT INTO users VALUES (1)", false},
 		{"CREATE TABLE test (id int)", false},
@@ -386,6 +525,13 @@ func TestIsDestructive(t *testing.T) {
 	}
 }
 
+func TestIsDestructive_LeadingComment(t *testing.T) {
+	cfg := DefaultPGConfig()
+	if !cfg.IsDestructive("-- clean up\n/* old rows */ DELETE FROM users") {
+		t.Error("comments before the statement should be skipped")
+	}
+}
+
 func TestIsDestructive_Disabled(t *testing.T) {
 	cfg := DefaultPGConfig()
 	cfg.DestructiveWarning = false
@@ -476,6 +622,7 @@ wider_completion_menu = True
 show_bottom_toolbar = False
 on_error = RESUME
 history_file = /tmp/test-history
+completion_cache_dir = /tmp/test-cache
 `
 	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
 		t.Fatalf("failed to write config: %v", err)
@@ -504,4 +651,7 @@ history_file = /tmp/test-history
 	if cfg.HistoryFile != "/tmp/test-history" {
 		t.Errorf("history_file should be '/tmp/test-history', got %q", cfg.HistoryFile)
 	}
+	if cfg.CompletionCacheDir != "/tmp/test-cache" {
+		t.Errorf("completion_cache_dir should be '/tmp/test-cache', got %q", cfg.CompletionCacheDir)
+	}
 }
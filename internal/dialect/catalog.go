@@ -0,0 +1,165 @@
+package dialect
+
+// Keywords and functions are upper case; data types are lower case, the
+// way they're usually written.
+
+var commonKeywords = []string{
+	"ADD", "ALL", "ALTER", "AND", "ANY", "AS", "ASC", "BEGIN", "BETWEEN",
+	"BY", "CASCADE", "CASE", "CHECK", "CLOSE", "COLLATE", "COLUMN",
+	"COMMENT", "COMMIT", "CONSTRAINT", "CREATE", "CROSS", "CURRENT",
+	"CURSOR", "DATABASE", "DECLARE", "DEFAULT", "DELETE", "DELIMITER",
+	"DESC", "DISTINCT", "DROP", "ELSE", "END", "ESCAPE", "EXCEPT",
+	"EXISTS", "EXPLAIN", "FALSE", "FETCH", "FIRST", "FOLLOWING", "FOR",
+	"FOREIGN", "FORMAT", "FROM", "FULL", "FUNCTION", "GRANT", "GROUP",
+	"HAVING", "IF", "IN", "INDEX", "INNER", "INSERT", "INTERSECT",
+	"INTERVAL", "INTO", "IS", "JOIN", "KEY", "LAST", "LATERAL", "LEFT",
+	"LIKE", "LIMIT", "LOCAL", "NATURAL", "NEXT", "NOT", "NULL", "OFFSET",
+	"ON", "OR", "ORDER", "OUTER", "OVER", "PARTITION", "PRECEDING",
+	"PRIMARY", "PROCEDURE", "RANGE", "RECURSIVE", "REFERENCES", "RENAME",
+	"REPLACE", "RESTRICT", "REVOKE", "RIGHT", "ROLLBACK", "ROW", "ROWS",
+	"SAVEPOINT", "SCHEMA", "SELECT", "SET", "SOME", "TABLE", "TEMPORARY",
+	"THEN", "TO", "TRIGGER", "TRUE", "TRUNCATE", "UNBOUNDED", "UNION",
+	"UNIQUE", "UPDATE", "USING", "VALUES", "VIEW", "WHEN", "WHERE",
+	"WINDOW", "WITH",
+}
+
+var commonFunctions = []string{
+	"ABS", "ASCII", "AVG", "CAST", "CEIL", "CEILING", "CHAR_LENGTH",
+	"CHARACTER_LENGTH", "COALESCE", "CONCAT", "CONCAT_WS", "COUNT",
+	"CUME_DIST", "CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP",
+	"CURRENT_USER", "DENSE_RANK", "EXP", "EXTRACT", "FIRST_VALUE",
+	"FLOOR", "GREATEST", "LAG", "LAST_VALUE", "LEAD", "LEAST", "LENGTH",
+	"LN", "LOG", "LOWER", "LPAD", "MAX", "MD5", "MIN", "MOD", "NOW",
+	"NTH_VALUE", "NTILE", "NULLIF", "PERCENT_RANK", "PI", "POSITION",
+	"POWER", "RANK", "REGEXP_REPLACE", "REPEAT", "REVERSE", "ROUND",
+	"ROW_NUMBER", "RPAD", "SESSION_USER", "SIGN", "SQRT", "SUBSTRING",
+	"SUM", "TRIM", "UPPER", "VERSION",
+}
+
+var pgKeywords = []string{
+	"ABSOLUTE", "ANALYZE", "BACKWARD", "BUFFERS", "CLUSTER",
+	"CONCURRENTLY", "CONFLICT", "COPY", "COSTS", "CSV", "DO", "DOMAIN",
+	"EXTENSION", "FILTER", "FORCE", "FORWARD", "HEADER", "ILIKE", "LISTEN",
+	"MATCHED", "MATERIALIZED", "MERGE", "MOVE", "NOTHING", "NOTIFY",
+	"NULLS", "ONLY", "OVERRIDING", "OWNER", "PRIOR", "QUOTE", "REINDEX",
+	"RELATIVE", "RETURNING", "SEQUENCE", "SIMILAR", "STDIN", "STDOUT",
+	"TABLESAMPLE", "TEMP", "TYPE", "UNLOGGED", "VACUUM", "VERBOSE",
+	"WITHIN",
+}
+
+var pgFunctions = []string{
+	"AGE", "ANY_VALUE", "ARRAY_AGG", "ARRAY_LENGTH", "BOOL_AND",
+	"BOOL_OR", "CHR", "CLOCK_TIMESTAMP", "CURRENT_DATABASE",
+	"CURRENT_SCHEMA", "DATE_PART", "DATE_TRUNC", "DECODE", "ENCODE",
+	"EVERY", "GEN_RANDOM_UUID", "GENERATE_SERIES", "INITCAP",
+	"JSON_AGG", "JSON_ARRAY_LENGTH", "JSON_BUILD_ARRAY",
+	"JSON_BUILD_OBJECT", "JSON_EXTRACT_PATH", "JSON_EXTRACT_PATH_TEXT",
+	"JSONB_AGG", "JSONB_BUILD_ARRAY", "JSONB_BUILD_OBJECT", "JSONB_INSERT",
+	"JSONB_OBJECT", "JSONB_SET", "MAKE_DATE", "MAKE_TIME",
+	"MAKE_TIMESTAMP", "MERGE_ACTION", "PG_SIZE_PRETTY", "PG_TYPEOF",
+	"RANDOM", "RANGE_AGG", "REGEXP_MATCHES", "ROW_TO_JSON", "SPLIT_PART",
+	"STATEMENT_TIMESTAMP", "STRING_AGG", "STRPOS", "TIMEOFDAY", "TO_CHAR",
+	"TO_DATE", "TO_JSON", "TO_JSONB", "TO_NUMBER", "TO_TIMESTAMP",
+	"TRANSLATE", "TRUNC", "UNNEST",
+}
+
+var pgDatatypes = []string{
+	"bigint", "bigserial", "bit", "bit varying", "boolean", "box",
+	"bytea", "character", "character varying", "cidr", "circle",
+	"date", "datemultirange", "daterange", "double precision", "inet",
+	"int4multirange", "int4range", "int8multirange", "int8range",
+	"integer", "interval", "json", "jsonb", "line", "lseg", "macaddr",
+	"macaddr8", "money", "nummultirange", "numeric", "numrange", "path",
+	"pg_lsn", "pg_snapshot", "point", "polygon", "real", "smallint",
+	"smallserial", "serial", "text", "time", "time with time zone",
+	"timestamp", "timestamp with time zone", "tsmultirange", "tsquery",
+	"tsrange", "tstzmultirange", "tstzrange", "tsvector", "txid_snapshot",
+	"uuid", "xml",
+}
+
+var pgSince = map[string]Version{
+	"GEN_RANDOM_UUID": {13, 0, 0},
+	"pg_snapshot":     {13, 0, 0},
+	"RANGE_AGG":       {14, 0, 0},
+	"datemultirange":  {14, 0, 0},
+	"int4multirange":  {14, 0, 0},
+	"int8multirange":  {14, 0, 0},
+	"nummultirange":   {14, 0, 0},
+	"tsmultirange":    {14, 0, 0},
+	"tstzmultirange":  {14, 0, 0},
+	"MERGE":           {15, 0, 0},
+	"MATCHED":         {15, 0, 0},
+	"ANY_VALUE":       {16, 0, 0},
+	"MERGE_ACTION":    {17, 0, 0},
+}
+
+var mysqlKeywords = []string{
+	"AFTER", "AGAINST", "AUTO_INCREMENT", "CHANGE", "CHARSET", "COLUMNS",
+	"DATA", "DATABASES", "DELAYED", "DESCRIBE", "DIV", "DUPLICATE",
+	"ENGINE", "ENUM", "FULLTEXT", "GLOBAL", "HIGH_PRIORITY", "IGNORE",
+	"INFILE", "LOAD", "LOCK", "LOW_PRIORITY", "MATCH", "MODIFY",
+	"OPTIMIZE", "PROCESSLIST", "REGEXP", "RLIKE", "SESSION", "SHOW",
+	"SPATIAL", "SQL_CALC_FOUND_ROWS", "STATUS", "STRAIGHT_JOIN", "TABLES",
+	"UNLOCK", "UNSIGNED", "USE", "VARIABLES", "XOR", "ZEROFILL",
+}
+
+var mysqlFunctions = []string{
+	"ADDDATE", "ANY_VALUE", "BENCHMARK", "BIN", "CONNECTION_ID", "CONV",
+	"CRC32", "CURDATE", "CURTIME", "DATE_ADD", "DATE_FORMAT", "DATE_SUB",
+	"DATEDIFF", "ELT", "FIELD", "FOUND_ROWS", "FROM_UNIXTIME",
+	"GROUP_CONCAT", "HEX", "IFNULL", "INET_ATON", "INET_NTOA", "INSTR",
+	"JSON_ARRAY", "JSON_ARRAYAGG", "JSON_CONTAINS", "JSON_EXTRACT",
+	"JSON_OBJECT", "JSON_OBJECTAGG", "JSON_SET", "JSON_TABLE",
+	"JSON_UNQUOTE", "LAST_INSERT_ID", "LOCATE", "MAKE_SET", "OCT", "RAND",
+	"REGEXP_INSTR", "REGEXP_LIKE", "REGEXP_SUBSTR", "SHA1", "SHA2",
+	"SLEEP", "STR_TO_DATE", "SUBDATE", "SYSDATE", "TIMESTAMPDIFF", "UNHEX",
+	"UNIX_TIMESTAMP", "USER", "UUID",
+}
+
+var mysqlDatatypes = []string{
+	"bigint", "binary", "bit", "blob", "boolean", "char",
+	"date", "datetime", "decimal", "double", "enum",
+	"float", "geometry", "int", "integer", "json",
+	"linestring", "longblob", "longtext", "mediumblob",
+	"mediumint", "mediumtext", "multilinestring", "multipoint",
+	"multipolygon", "numeric", "point", "polygon",
+	"real", "set", "smallint", "text", "time",
+	"timestamp", "tinyblob", "tinyint", "tinytext",
+	"varbinary", "varchar", "year",
+}
+
+var mysqlSince = map[string]Version{
+	"ANY_VALUE":      {5, 7, 5},
+	"JSON_ARRAY":     {5, 7, 8},
+	"JSON_CONTAINS":  {5, 7, 8},
+	"JSON_EXTRACT":   {5, 7, 8},
+	"JSON_OBJECT":    {5, 7, 8},
+	"JSON_SET":       {5, 7, 8},
+	"JSON_UNQUOTE":   {5, 7, 8},
+	"json":           {5, 7, 8},
+	"JSON_ARRAYAGG":  {5, 7, 22},
+	"JSON_OBJECTAGG": {5, 7, 22},
+	"WITH":           {8, 0, 1},
+	"RECURSIVE":      {8, 0, 1},
+	"OVER":           {8, 0, 2},
+	"WINDOW":         {8, 0, 2},
+	"CUME_DIST":      {8, 0, 2},
+	"DENSE_RANK":     {8, 0, 2},
+	"FIRST_VALUE":    {8, 0, 2},
+	"LAG":            {8, 0, 2},
+	"LAST_VALUE":     {8, 0, 2},
+	"LEAD":           {8, 0, 2},
+	"NTH_VALUE":      {8, 0, 2},
+	"NTILE":          {8, 0, 2},
+	"PERCENT_RANK":   {8, 0, 2},
+	"RANK":           {8, 0, 2},
+	"ROW_NUMBER":     {8, 0, 2},
+	"JSON_TABLE":     {8, 0, 4},
+	"REGEXP_INSTR":   {8, 0, 4},
+	"REGEXP_LIKE":    {8, 0, 4},
+	"REGEXP_REPLACE": {8, 0, 4},
+	"REGEXP_SUBSTR":  {8, 0, 4},
+	"LATERAL":        {8, 0, 14},
+	"EXCEPT":         {8, 0, 31},
+	"INTERSECT":      {8, 0, 31},
+}
@@ -0,0 +1,180 @@
+// Package dialect describes the SQL each backend understands: its
+// keywords, built-in functions and data types, gated by server version so
+// completion and highlighting only offer what the server will accept.
+package dialect
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Name identifies a backend.
+type Name string
+
+const (
+	PostgreSQL Name = "postgresql"
+	MySQL      Name = "mysql"
+)
+
+// Version is a server version. The zero Version means unknown, in which
+// case nothing is gated.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion reads the leading numbers of a version string such as
+// "15.4 (Debian 15.4-1.pgdg120+1)", "8.0.35-0ubuntu0.22.04.1" or
+// "16beta2". Anything it can't read is left as zero.
+func ParseVersion(s string) Version {
+	var parts [3]int
+	s = strings.TrimSpace(s)
+	for i := range parts {
+		end := 0
+		for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		parts[i], _ = strconv.Atoi(s[:end])
+		if end == len(s) || s[end] != '.' {
+			break
+		}
+		s = s[end+1:]
+	}
+	return Version{parts[0], parts[1], parts[2]}
+}
+
+// Less reports whether v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+// IsZero reports whether the version is unknown.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Dialect is the keyword, function and data type catalog for one backend
+// at one server version. A nil *Dialect behaves like Generic.
+type Dialect struct {
+	Name    Name
+	Version Version
+
+	keywords  map[string]bool
+	functions map[string]bool
+	datatypes []string
+}
+
+// catalog lists the words one backend adds to the common set, and the
+// server version each gated word first appeared in.
+type catalog struct {
+	keywords  []string
+	functions []string
+	datatypes []string
+	since     map[string]Version
+}
+
+var catalogs = map[Name]catalog{
+	PostgreSQL: {pgKeywords, pgFunctions, pgDatatypes, pgSince},
+	MySQL:      {mysqlKeywords, mysqlFunctions, mysqlDatatypes, mysqlSince},
+}
+
+var generic = New("", "")
+
+// Generic returns the union of every backend's catalog, for callers that
+// don't know which server they talk to.
+func Generic() *Dialect {
+	return generic
+}
+
+// New builds the catalog for a backend at the given server version
+// string. An unknown name yields the union of all backends; an empty or
+// unparseable version enables every gated word.
+func New(name Name, version string) *Dialect {
+	d := &Dialect{
+		Name:      name,
+		Version:   ParseVersion(version),
+		keywords:  make(map[string]bool),
+		functions: make(map[string]bool),
+	}
+	backends := []Name{name}
+	if _, ok := catalogs[name]; !ok {
+		backends = []Name{PostgreSQL, MySQL}
+	}
+
+	seenType := make(map[string]bool)
+	add := func(words []string, since map[string]Version, set func(string)) {
+		for _, w := range words {
+			if min, gated := since[w]; gated && !d.Version.IsZero() && d.Version.Less(min) {
+				continue
+			}
+			set(w)
+		}
+	}
+	for _, b := range backends {
+		cat := catalogs[b]
+		add(commonKeywords, cat.since, func(w string) { d.keywords[w] = true })
+		add(cat.keywords, cat.since, func(w string) { d.keywords[w] = true })
+		add(commonFunctions, cat.since, func(w string) { d.functions[w] = true })
+		add(cat.functions, cat.since, func(w string) { d.functions[w] = true })
+		add(cat.datatypes, cat.since, func(w string) {
+			if !seenType[w] {
+				seenType[w] = true
+				d.datatypes = append(d.datatypes, w)
+			}
+		})
+	}
+	sort.Strings(d.datatypes)
+	return d
+}
+
+func (d *Dialect) orGeneric() *Dialect {
+	if d == nil {
+		return generic
+	}
+	return d
+}
+
+// IsKeyword reports whether word is a keyword in this dialect.
+func (d *Dialect) IsKeyword(word string) bool {
+	return d.orGeneric().keywords[strings.ToUpper(word)]
+}
+
+// IsFunction reports whether word names a built-in function in this
+// dialect.
+func (d *Dialect) IsFunction(word string) bool {
+	return d.orGeneric().functions[strings.ToUpper(word)]
+}
+
+// Keywords returns the dialect's keywords in upper case, sorted.
+func (d *Dialect) Keywords() []string {
+	return sortedWords(d.orGeneric().keywords)
+}
+
+// Functions returns the dialect's built-in function names in upper case,
+// sorted.
+func (d *Dialect) Functions() []string {
+	return sortedWords(d.orGeneric().functions)
+}
+
+// Datatypes returns the dialect's data type names in lower case, sorted.
+func (d *Dialect) Datatypes() []string {
+	return append([]string(nil), d.orGeneric().datatypes...)
+}
+
+func sortedWords(set map[string]bool) []string {
+	words := make([]string, 0, len(set))
+	for w := range set {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
@@ -0,0 +1,137 @@
+package dialect
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Version
+	}{
+		{"15.4 (Debian 15.4-1.pgdg120+1)", Version{15, 4, 0}},
+		{"9.6.24", Version{9, 6, 24}},
+		{"16beta2", Version{16, 0, 0}},
+		{"8.0.35-0ubuntu0.22.04.1", Version{8, 0, 35}},
+		{"10.11.2-MariaDB", Version{10, 11, 2}},
+		{"", Version{}},
+		{"unknown", Version{}},
+	}
+	for _, tt := range tests {
+		if got := ParseVersion(tt.input); got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	if !(Version{8, 0, 13}).Less(Version{8, 0, 14}) {
+		t.Error("8.0.13 should be older than 8.0.14")
+	}
+	if (Version{15, 0, 0}).Less(Version{14, 9, 0}) {
+		t.Error("15.0 should not be older than 14.9")
+	}
+	if (Version{15, 0, 0}).Less(Version{15, 0, 0}) {
+		t.Error("a version should not be older than itself")
+	}
+}
+
+func TestNew_BackendKeywords(t *testing.T) {
+	pg := New(PostgreSQL, "16.1")
+	my := New(MySQL, "8.0.35")
+
+	for _, kw := range []string{"ILIKE", "RETURNING", "NULLS"} {
+		if !pg.IsKeyword(kw) {
+			t.Errorf("%s should be a PostgreSQL keyword", kw)
+		}
+		if my.IsKeyword(kw) {
+			t.Errorf("%s should not be a MySQL keyword", kw)
+		}
+	}
+	for _, kw := range []string{"STRAIGHT_JOIN", "SHOW", "AUTO_INCREMENT"} {
+		if !my.IsKeyword(kw) {
+			t.Errorf("%s should be a MySQL keyword", kw)
+		}
+		if pg.IsKeyword(kw) {
+			t.Errorf("%s should not be a PostgreSQL keyword", kw)
+		}
+	}
+	if !pg.IsKeyword("select") || !my.IsKeyword("select") {
+		t.Error("keywords should match case-insensitively in both dialects")
+	}
+	if !my.IsFunction("group_concat") || pg.IsFunction("group_concat") {
+		t.Error("GROUP_CONCAT should only be a MySQL function")
+	}
+	if !pg.IsFunction("string_agg") || my.IsFunction("string_agg") {
+		t.Error("STRING_AGG should only be a PostgreSQL function")
+	}
+}
+
+func TestNew_VersionGating(t *testing.T) {
+	if New(PostgreSQL, "14.10").IsKeyword("MERGE") {
+		t.Error("MERGE should not be offered before PostgreSQL 15")
+	}
+	if !New(PostgreSQL, "15.0").IsKeyword("MERGE") {
+		t.Error("MERGE should be offered from PostgreSQL 15")
+	}
+	if !New(PostgreSQL, "").IsKeyword("MERGE") {
+		t.Error("an unknown version should not gate anything")
+	}
+
+	old := New(MySQL, "5.7.44")
+	for _, kw := range []string{"WITH", "OVER", "LATERAL"} {
+		if old.IsKeyword(kw) {
+			t.Errorf("%s should not be offered on MySQL 5.7", kw)
+		}
+	}
+	if old.IsFunction("ROW_NUMBER") {
+		t.Error("window functions should not be offered on MySQL 5.7")
+	}
+	if !old.IsFunction("JSON_EXTRACT") {
+		t.Error("JSON functions should be offered on MySQL 5.7")
+	}
+	if New(MySQL, "8.0.13").IsKeyword("LATERAL") || !New(MySQL, "8.0.14").IsKeyword("LATERAL") {
+		t.Error("LATERAL should be gated at MySQL 8.0.14")
+	}
+}
+
+func TestNew_Datatypes(t *testing.T) {
+	pg := New(PostgreSQL, "13.0").Datatypes()
+	if !slices.Contains(pg, "jsonb") || slices.Contains(pg, "tinyint") {
+		t.Errorf("unexpected PostgreSQL datatypes: %v", pg)
+	}
+	if slices.Contains(pg, "int4multirange") {
+		t.Error("multirange types should not be offered before PostgreSQL 14")
+	}
+	my := New(MySQL, "").Datatypes()
+	if !slices.Contains(my, "tinyint") || slices.Contains(my, "jsonb") {
+		t.Errorf("unexpected MySQL datatypes: %v", my)
+	}
+	if !slices.IsSorted(my) {
+		t.Error("datatypes should be sorted")
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	var d *Dialect
+	for _, kw := range []string{"ILIKE", "STRAIGHT_JOIN", "MERGE"} {
+		if !Generic().IsKeyword(kw) || !d.IsKeyword(kw) {
+			t.Errorf("%s should be a keyword in the generic dialect", kw)
+		}
+	}
+	if len(d.Keywords()) != len(Generic().Keywords()) {
+		t.Error("a nil dialect should behave like the generic one")
+	}
+	types := Generic().Datatypes()
+	if !slices.Contains(types, "jsonb") || !slices.Contains(types, "tinyint") {
+		t.Error("generic datatypes should cover every backend")
+	}
+	seen := make(map[string]bool)
+	for _, dt := range types {
+		if seen[dt] {
+			t.Errorf("duplicate datatype %q", dt)
+		}
+		seen[dt] = true
+	}
+}
@@ -8,14 +8,15 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
-// ANSI color codes for table output
+// ANSI color codes for the default theme
 const (
-	colorReset  = "\033[0m"
-	colorGreen  = "\033[32m"
-	colorBold   = "\033[1m"
+	colorReset = "\033[0m"
+	colorGreen = "\033[32m"
+	colorBold  = "\033[1m"
 )
 
 // OutputFormat specifies the output format type.
@@ -47,7 +48,22 @@ type Options struct {
 	Expanded  bool // \x expanded output
 	MaxWidth  int  // terminal width for wrapping
 	NullValue string
-	FloatFmt  string
+	// NullValues overrides NullValue for individual output formats.
+	NullValues map[OutputFormat]string
+	TuplesOnly bool // omit column headers (\pset tuples_only)
+	// Color enables ANSI escape codes in table and vertical output.
+	// Callers decide this from the destination; see Theme for the colors.
+	Color bool
+	Theme Theme
+
+	// Value display settings, applied to table and vertical output only.
+	// CSV, TSV and JSON keep values exactly as returned by the server.
+	FloatFmt        string // printf verb for float columns, e.g. "%.2f"; NUMERIC only takes a "%.Nf" precision; empty leaves values as is
+	ThousandsSep    string
+	DecimalMark     string
+	DateFormat      string // Go time layout for date columns
+	TimestampFormat string // Go time layout for timestamp columns
+	TimeZone        string // IANA zone that timestamptz values are shown in
 }
 
 // DefaultOptions returns sensible defaults.
@@ -57,16 +73,35 @@ func DefaultOptions() Options {
 		Style:     UnicodeStyle,
 		MaxWidth:  0, // auto-detect
 		NullValue: "NULL",
-		FloatFmt:  "%.2f",
+		NullValues: map[OutputFormat]string{
+			CSVFormat:  "",
+			TSVFormat:  "",
+			JSONFormat: "null",
+		},
+		Theme: DefaultTheme(),
 	}
 }
 
 // QueryResult holds the result of a query execution.
 type QueryResult struct {
-	Columns    []string
-	Rows       [][]string
-	StatusText string // e.g. "SELECT 5", "INSERT 0 1"
-	RowCount   int
+	Columns     []string
+	ColumnTypes []string // database type names, parallel to Columns (may be nil)
+	Rows        [][]string
+	Nulls       [][]bool // NULL markers, parallel to Rows (may be nil)
+	StatusText  string   // e.g. "SELECT 5", "INSERT 0 1"
+	RowCount    int
+
+	// Timing, as measured by the executor
+	ServerTime time.Duration // until the server answered
+	FetchTime  time.Duration // reading the rows after that
+}
+
+// IsNull reports whether the cell at (row, col) is SQL NULL.
+func (r *QueryResult) IsNull(row, col int) bool {
+	if row >= len(r.Nulls) || col >= len(r.Nulls[row]) {
+		return false
+	}
+	return r.Nulls[row][col]
 }
 
 // Format writes the query result to w using the specified options.
@@ -81,11 +116,11 @@ func Format(w io.Writer, result *QueryResult, opts Options) error {
 	case TableFormat, AlignedFormat:
 		return formatTable(w, result, opts)
 	case CSVFormat:
-		return formatCSV(w, result, ',')
+		return formatCSV(w, result, ',', opts.nullText(CSVFormat), !opts.TuplesOnly)
 	case TSVFormat:
-		return formatCSV(w, result, '\t')
+		return formatCSV(w, result, '\t', opts.nullText(TSVFormat), !opts.TuplesOnly)
 	case JSONFormat:
-		return formatJSON(w, result)
+		return formatJSON(w, result, opts.nullText(JSONFormat))
 	default:
 		return formatTable(w, result, opts)
 	}
@@ -93,11 +128,11 @@ func Format(w io.Writer, result *QueryResult, opts Options) error {
 
 // borders holds the characters used for table borders.
 type borders struct {
-	TopLeft, TopMid, TopRight       string
-	MidLeft, MidMid, MidRight       string
-	BotLeft, BotMid, BotRight       string
-	Horizontal, Vertical            string
-	HeaderHorizontal                string
+	TopLeft, TopMid, TopRight string
+	MidLeft, MidMid, MidRight string
+	BotLeft, BotMid, BotRight string
+	Horizontal, Vertical      string
+	HeaderHorizontal          string
 }
 
 func asciiBorders() borders {
@@ -181,38 +216,73 @@ func padRight(s string, width int) string {
 	return s + strings.Repeat(" ", width-dw)
 }
 
-func formatTable(w io.Writer, result *QueryResult, opts Options) error {
-	if len(result.Columns) == 0 {
-		return nil
+// padLeft right-aligns a string to the given display width.
+func padLeft(s string, width int) string {
+	dw := displayWidth(s)
+	if dw >= width {
+		return s
 	}
-	b := getBorders(opts.Style)
+	return strings.Repeat(" ", width-dw) + s
+}
 
-	// Calculate column widths
+// renderCells formats every cell for display and returns the rendered
+// values, their NULL markers and the width of each column.
+func renderCells(result *QueryResult, kinds []ColumnKind, opts Options) ([][]string, [][]bool, []int) {
+	cells := make([][]string, len(result.Rows))
+	nulls := make([][]bool, len(result.Rows))
 	widths := make([]int, len(result.Columns))
 	for i, col := range result.Columns {
 		widths[i] = displayWidth(col)
 	}
-	for _, row := range result.Rows {
-		for i, cell := range row {
-			if i < len(widths) {
-				cw := displayWidth(cell)
-				if cw > widths[i] {
-					widths[i] = cw
-				}
+	for r := range result.Rows {
+		cells[r] = make([]string, len(result.Columns))
+		nulls[r] = make([]bool, len(result.Columns))
+		for i := range result.Columns {
+			cells[r][i], nulls[r][i] = displayCell(result, r, i, kinds[i], opts)
+			if cw := displayWidth(cells[r][i]); cw > widths[i] {
+				widths[i] = cw
 			}
 		}
 	}
+	return cells, nulls, widths
+}
+
+// TableWidth returns the width in terminal columns of the table that
+// Format would draw for result, used to decide on \x auto expansion.
+func TableWidth(result *QueryResult, opts Options) int {
+	if len(result.Columns) == 0 {
+		return 0
+	}
+	_, _, widths := renderCells(result, columnKinds(result), opts)
+	// Every column has a space either side and a border character after it,
+	// plus the leading border
+	total := 1
+	for _, width := range widths {
+		total += width + 3
+	}
+	return total
+}
+
+func formatTable(w io.Writer, result *QueryResult, opts Options) error {
+	if len(result.Columns) == 0 {
+		return nil
+	}
+	b := getBorders(opts.Style)
+	kinds := columnKinds(result)
+	cells, nulls, widths := renderCells(result, kinds, opts)
 
 	// Helper to write a border line
 	writeBorderLine := func(left, mid, right, horiz string) {
-		fmt.Fprint(w, colorGreen, left)
+		var line strings.Builder
+		line.WriteString(left)
 		for i, width := range widths {
-			fmt.Fprint(w, strings.Repeat(horiz, width+2))
+			line.WriteString(strings.Repeat(horiz, width+2))
 			if i < len(widths)-1 {
-				fmt.Fprint(w, mid)
+				line.WriteString(mid)
 			}
 		}
-		fmt.Fprintln(w, right, colorReset)
+		line.WriteString(right)
+		fmt.Fprintln(w, opts.paint(opts.Theme.Border, line.String()))
 	}
 
 	// Top border (skip if empty, e.g. psql style)
@@ -220,31 +290,34 @@ func formatTable(w io.Writer, result *QueryResult, opts Options) error {
 		writeBorderLine(b.TopLeft, b.TopMid, b.TopRight, b.Horizontal)
 	}
 
-	// Header (green + bold)
-	fmt.Fprint(w, colorGreen, colorBold, b.Vertical)
-	for i, col := range result.Columns {
-		fmt.Fprintf(w, " %s ", padRight(col, widths[i]))
-		fmt.Fprint(w, b.Vertical)
-	}
-	fmt.Fprintln(w, colorReset)
+	// Header
+	if !opts.TuplesOnly {
+		var header strings.Builder
+		header.WriteString(b.Vertical)
+		for i, col := range result.Columns {
+			fmt.Fprintf(&header, " %s ", padRight(col, widths[i]))
+			header.WriteString(b.Vertical)
+		}
+		fmt.Fprintln(w, opts.paint(opts.Theme.Header, header.String()))
 
-	// Header separator
-	writeBorderLine(b.MidLeft, b.MidMid, b.MidRight, b.HeaderHorizontal)
+		// Header separator
+		writeBorderLine(b.MidLeft, b.MidMid, b.MidRight, b.HeaderHorizontal)
+	}
 
 	// Data rows
-	for _, row := range result.Rows {
-		fmt.Fprint(w, colorGreen, b.Vertical, colorReset)
-		for i := range result.Columns {
-			cell := ""
-			if i < len(row) {
-				cell = row[i]
+	vertical := opts.paint(opts.Theme.Border, b.Vertical)
+	for r := range cells {
+		fmt.Fprint(w, vertical)
+		for i, cell := range cells[r] {
+			pad := padRight
+			if kinds[i].IsNumeric() {
+				pad = padLeft
 			}
-			if cell == opts.NullValue {
-				fmt.Fprintf(w, " %s%s%s ", colorGreen, padRight(cell, widths[i]), colorReset)
-			} else {
-				fmt.Fprintf(w, " %s ", padRight(cell, widths[i]))
+			cell = pad(cell, widths[i])
+			if nulls[r][i] {
+				cell = opts.paint(opts.Theme.Null, cell)
 			}
-			fmt.Fprint(w, colorGreen, b.Vertical, colorReset)
+			fmt.Fprintf(w, " %s %s", cell, vertical)
 		}
 		fmt.Fprintln(w)
 	}
@@ -257,42 +330,159 @@ func formatTable(w io.Writer, result *QueryResult, opts Options) error {
 	return nil
 }
 
+// minWrapWidth is the narrowest value column that expanded output wraps
+// into; below this values are printed unwrapped.
+const minWrapWidth = 10
+
+// formatVertical prints one block per row in the style of psql's expanded
+// mode. Boxed table styles draw a frame around the records, and without a
+// style the plain psql layout is used. Values longer than opts.MaxWidth
+// allows are wrapped under the value column.
 func formatVertical(w io.Writer, result *QueryResult, opts Options) error {
 	if len(result.Columns) == 0 {
 		return nil
 	}
+	b := psqlBorders()
+	if opts.Style != "" {
+		b = getBorders(opts.Style)
+	}
+	boxed := b.TopLeft != ""
+	kinds := columnKinds(result)
 
-	// Find max column name width
-	maxWidth := 0
+	nameWidth := 0
 	for _, col := range result.Columns {
-		if cw := utf8.RuneCountInString(col); cw > maxWidth {
-			maxWidth = cw
+		if cw := displayWidth(col); cw > nameWidth {
+			nameWidth = cw
 		}
 	}
 
-	for i, row := range result.Rows {
-		fmt.Fprintf(w, "-[ RECORD %d ]%s\n", i+1, strings.Repeat("-", 40))
+	cells := make([][]string, len(result.Rows))
+	nulls := make([][]bool, len(result.Rows))
+	valueWidth := 0
+	for i := range result.Rows {
+		cells[i] = make([]string, len(result.Columns))
+		nulls[i] = make([]bool, len(result.Columns))
+		for j := range result.Columns {
+			cells[i][j], nulls[i][j] = displayCell(result, i, j, kinds[j], opts)
+			for _, line := range strings.Split(cells[i][j], "\n") {
+				if lw := displayWidth(line); lw > valueWidth {
+					valueWidth = lw
+				}
+			}
+		}
+	}
+
+	// "name | value", or "| name | value |" when boxed
+	overhead := nameWidth + 3
+	if boxed {
+		overhead += 4
+	}
+	wrapWidth := 0
+	if avail := opts.MaxWidth - overhead; opts.MaxWidth > 0 && valueWidth > avail && avail >= minWrapWidth {
+		valueWidth = avail
+		wrapWidth = avail
+	}
+	// The record header must fit even when every value is short
+	titleWidth := len(fmt.Sprintf("[ RECORD %d ]", len(result.Rows))) + 2
+	if boxed {
+		titleWidth += 2
+	}
+	if overhead+valueWidth < titleWidth {
+		valueWidth = titleWidth - overhead
+	}
+	lineWidth := overhead + valueWidth
+
+	vertical := opts.paint(opts.Theme.Border, b.Vertical)
+	for i := range result.Rows {
+		left, right := "", ""
+		if boxed {
+			left, right = b.MidLeft, b.MidRight
+			if i == 0 {
+				left, right = b.TopLeft, b.TopRight
+			}
+		}
+		title := fmt.Sprintf("[ RECORD %d ]", i+1)
+		fill := lineWidth - displayWidth(left) - displayWidth(right) - 1 - len(title)
+		header := left + b.Horizontal + title + strings.Repeat(b.Horizontal, fill) + right
+		fmt.Fprintln(w, opts.paint(opts.Theme.Border, header))
+
 		for j, col := range result.Columns {
-			cell := ""
-			if j < len(row) {
-				cell = row[j]
+			for k, line := range wrapText(cells[i][j], wrapWidth) {
+				name := ""
+				if k == 0 {
+					name = col
+				}
+				name = opts.paint(opts.Theme.Header, padRight(name, nameWidth))
+				if boxed {
+					line = padRight(line, valueWidth)
+				}
+				if nulls[i][j] {
+					line = opts.paint(opts.Theme.Null, line)
+				}
+				if boxed {
+					fmt.Fprintf(w, "%s %s %s %s %s\n", vertical, name, vertical, line, vertical)
+				} else {
+					fmt.Fprintf(w, "%s %s %s\n", name, vertical, line)
+				}
 			}
-			fmt.Fprintf(w, "%-*s | %s\n", maxWidth, col, cell)
 		}
 	}
 
+	if boxed && len(result.Rows) > 0 {
+		bottom := b.BotLeft + strings.Repeat(b.Horizontal, nameWidth+2) + b.BotMid +
+			strings.Repeat(b.Horizontal, valueWidth+2) + b.BotRight
+		fmt.Fprintln(w, opts.paint(opts.Theme.Border, bottom))
+	}
+
 	return nil
 }
 
-func formatCSV(w io.Writer, result *QueryResult, delimiter rune) error {
+// wrapText splits s into lines at embedded newlines and then at width
+// display columns. A width of 0 only splits at newlines.
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		for width > 0 && displayWidth(line) > width {
+			cut, w := 0, 0
+			for i, r := range line {
+				rw := displayWidth(string(r))
+				if w+rw > width {
+					break
+				}
+				w += rw
+				cut = i + utf8.RuneLen(r)
+			}
+			if cut == 0 {
+				// A single wide rune is wider than the column
+				_, cut = utf8.DecodeRuneInString(line)
+			}
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func formatCSV(w io.Writer, result *QueryResult, delimiter rune, null string, header bool) error {
 	cw := csv.NewWriter(w)
 	cw.Comma = delimiter
 
-	if err := cw.Write(result.Columns); err != nil {
-		return err
+	if header {
+		if err := cw.Write(result.Columns); err != nil {
+			return err
+		}
 	}
-	for _, row := range result.Rows {
-		if err := cw.Write(row); err != nil {
+	record := make([]string, len(result.Columns))
+	for r := range result.Rows {
+		for c := range record {
+			cell, isNull := rawCell(result, r, c)
+			if isNull {
+				cell = null
+			}
+			record[c] = cell
+		}
+		if err := cw.Write(record); err != nil {
 			return err
 		}
 	}
@@ -300,13 +490,23 @@ func formatCSV(w io.Writer, result *QueryResult, delimiter rune) error {
 	return cw.Error()
 }
 
-func formatJSON(w io.Writer, result *QueryResult) error {
+// formatJSON writes rows as an array of objects. NULL cells become JSON null
+// when the configured null string is "null", and that string otherwise.
+func formatJSON(w io.Writer, result *QueryResult, null string) error {
 	rows := make([]map[string]interface{}, len(result.Rows))
 	for i, row := range result.Rows {
 		m := make(map[string]interface{})
 		for j, col := range result.Columns {
-			if j < len(row) {
+			if j >= len(row) {
+				continue
+			}
+			switch {
+			case !result.IsNull(i, j):
 				m[col] = row[j]
+			case null == "null":
+				m[col] = nil
+			default:
+				m[col] = null
 			}
 		}
 		rows[i] = m
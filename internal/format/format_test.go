@@ -330,8 +330,8 @@ func TestDisplayWidth(t *testing.T) {
 		{"hello", 5},
 		{"", 0},
 		{"abc", 3},
-		{"日本語", 6},  // CJK characters are double-width
-		{"a日b", 4},   // mixed
+		{"日本語", 6}, // CJK characters are double-width
+		{"a日b", 4}, // mixed
 	}
 
 	for _, tt := range tests {
@@ -746,3 +746,67 @@ func BenchmarkFormatJSON(b *testing.B) {
 		Format(&buf, result, opts)
 	}
 }
+
+func TestFormatVertical_WrapsToMaxWidth(t *testing.T) {
+	result := &QueryResult{
+		Columns: []string{"id", "note"},
+		Rows:    [][]string{{"1", strings.Repeat("x", 30)}},
+	}
+
+	var buf bytes.Buffer
+	opts := Options{Format: VerticalFormat, MaxWidth: 20}
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for _, line := range lines {
+		if displayWidth(line) > 20 {
+			t.Errorf("line wider than MaxWidth: %q", line)
+		}
+	}
+	// "note | " leaves 13 columns: 30 x's take three lines
+	if len(lines) != 5 {
+		t.Errorf("expected header, id and three note lines, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[3], "     | x") {
+		t.Errorf("continuation lines should align under the value column, got %q", lines[3])
+	}
+}
+
+func TestFormatVertical_BorderStyle(t *testing.T) {
+	result := &QueryResult{
+		Columns: []string{"id"},
+		Rows:    [][]string{{"1"}, {"2"}},
+	}
+
+	var buf bytes.Buffer
+	opts := Options{Format: VerticalFormat, Style: UnicodeStyle}
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"┌─[ RECORD 1 ]", "├─[ RECORD 2 ]", "│ id │ 1", "└────┴"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("unicode expanded output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestTableWidth(t *testing.T) {
+	result := &QueryResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]string{{"1", "Alice"}},
+	}
+
+	var buf bytes.Buffer
+	opts := Options{Format: TableFormat, Style: ASCIIStyle}
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	firstLine := strings.SplitN(buf.String(), "\n", 2)[0]
+	if got := TableWidth(result, opts); got != displayWidth(firstLine) {
+		t.Errorf("TableWidth = %d, drawn table is %d wide", got, displayWidth(firstLine))
+	}
+}
@@ -0,0 +1,103 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Theme holds the ANSI escape sequences used for table output when
+// Options.Color is set. An empty field leaves that element uncolored.
+type Theme struct {
+	Header string
+	Border string
+	Null   string
+}
+
+// DefaultTheme returns the built-in output colors.
+func DefaultTheme() Theme {
+	return Theme{
+		Header: colorGreen + colorBold,
+		Border: colorGreen,
+		Null:   colorGreen,
+	}
+}
+
+// ThemeFromColors overrides the default theme with entries from the [colors]
+// config section. The keys follow pgcli: output.header, output.border and
+// output.null.
+func ThemeFromColors(colors map[string]string) Theme {
+	theme := DefaultTheme()
+	if spec, ok := colors["output.header"]; ok {
+		theme.Header = ParseColor(spec)
+	}
+	if spec, ok := colors["output.border"]; ok {
+		theme.Border = ParseColor(spec)
+	}
+	if spec, ok := colors["output.null"]; ok {
+		theme.Null = ParseColor(spec)
+	}
+	return theme
+}
+
+var ansiColorCodes = map[string]int{
+	"black": 30, "red": 31, "green": 32, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37, "gray": 90,
+	"brightblack": 90, "brightred": 91, "brightgreen": 92, "brightyellow": 93,
+	"brightblue": 94, "brightmagenta": 95, "brightcyan": 96, "brightwhite": 97,
+}
+
+var ansiAttrCodes = map[string]int{
+	"bold": 1, "dim": 2, "italic": 3, "underline": 4, "reverse": 7,
+}
+
+// ParseColor converts a prompt_toolkit style color spec such as
+// "ansibrightblue bold", "bg:ansired" or "#ff8700" into an ANSI escape
+// sequence. Unknown words are ignored, so an empty or unrecognized spec
+// yields "" (no color).
+func ParseColor(spec string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(strings.ToLower(spec)) {
+		bg := strings.HasPrefix(word, "bg:")
+		word = strings.TrimPrefix(strings.TrimPrefix(word, "bg:"), "fg:")
+
+		if code, ok := ansiAttrCodes[word]; ok && !bg {
+			fmt.Fprintf(&b, "\033[%dm", code)
+			continue
+		}
+		if code, ok := ansiColorCodes[strings.TrimPrefix(word, "ansi")]; ok {
+			if bg {
+				code += 10
+			}
+			fmt.Fprintf(&b, "\033[%dm", code)
+			continue
+		}
+		if r, g, bl, ok := parseHexColor(word); ok {
+			layer := 38
+			if bg {
+				layer = 48
+			}
+			fmt.Fprintf(&b, "\033[%d;2;%d;%d;%dm", layer, r, g, bl)
+		}
+	}
+	return b.String()
+}
+
+func parseHexColor(s string) (r, g, b int64, ok bool) {
+	if len(s) != 7 || s[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int64(v >> 16), int64(v >> 8 & 0xff), int64(v & 0xff), true
+}
+
+// paint wraps s in the given escape sequence when color output is enabled.
+func (o Options) paint(code, s string) string {
+	if !o.Color || code == "" {
+		return s
+	}
+	return code + s + colorReset
+}
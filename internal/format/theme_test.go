@@ -0,0 +1,72 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		spec, expected string
+	}{
+		{"ansigreen", "\033[32m"},
+		{"ansibrightblue bold", "\033[94m\033[1m"},
+		{"bg:ansired", "\033[41m"},
+		{"#ff8700", "\033[38;2;255;135;0m"},
+		{"notacolor", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseColor(tt.spec); got != tt.expected {
+			t.Errorf("ParseColor(%q) = %q, want %q", tt.spec, got, tt.expected)
+		}
+	}
+}
+
+func TestThemeFromColors(t *testing.T) {
+	theme := ThemeFromColors(map[string]string{
+		"output.header": "ansiyellow",
+		"output.null":   "",
+	})
+	if theme.Header != "\033[33m" {
+		t.Errorf("header color not applied, got %q", theme.Header)
+	}
+	if theme.Null != "" {
+		t.Errorf("empty spec should disable NULL color, got %q", theme.Null)
+	}
+	if theme.Border != DefaultTheme().Border {
+		t.Errorf("unset border should keep default, got %q", theme.Border)
+	}
+}
+
+func TestFormatTable_Color(t *testing.T) {
+	result := &QueryResult{
+		Columns: []string{"id", "email"},
+		Rows:    [][]string{{"1", "NULL"}},
+		Nulls:   [][]bool{{false, true}},
+	}
+
+	var buf bytes.Buffer
+	opts := DefaultOptions()
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("output without Color should have no escape codes, got %q", buf.String())
+	}
+
+	buf.Reset()
+	opts.Color = true
+	opts.Theme.Null = "\033[35m"
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "\033[35mNULL") {
+		t.Errorf("NULL should use the themed color, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), colorGreen+colorBold) {
+		t.Errorf("header should use the default color, got %q", buf.String())
+	}
+}
@@ -0,0 +1,271 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnKind classifies a result column for value formatting and alignment.
+type ColumnKind int
+
+const (
+	KindText ColumnKind = iota
+	KindInteger
+	KindFloat
+	KindDecimal // exact NUMERIC and DECIMAL values
+	KindDate
+	KindTime
+	KindTimestamp
+	KindTimestampTZ
+)
+
+// Canonical layouts executors use for temporal values. The formatter parses
+// cells back with these layouts when a display format is configured.
+const (
+	DateLayout        = "2006-01-02"
+	TimeLayout        = "15:04:05.999999"
+	TimestampLayout   = "2006-01-02 15:04:05.999999"
+	TimestampTZLayout = "2006-01-02 15:04:05.999999-07:00"
+)
+
+// KindOf maps a database type name (as reported by database/sql) to a ColumnKind.
+// It understands both PostgreSQL and MySQL type names.
+func KindOf(typeName string) ColumnKind {
+	name := strings.ToUpper(strings.TrimSpace(typeName))
+	name = strings.TrimPrefix(name, "UNSIGNED ")
+	switch name {
+	case "INT2", "INT4", "INT8", "SMALLINT", "INTEGER", "INT", "BIGINT",
+		"TINYINT", "MEDIUMINT", "SERIAL", "BIGSERIAL", "OID":
+		return KindInteger
+	case "FLOAT4", "FLOAT8", "FLOAT", "DOUBLE", "REAL":
+		return KindFloat
+	case "NUMERIC", "DECIMAL":
+		return KindDecimal
+	case "DATE":
+		return KindDate
+	case "TIME", "TIMETZ":
+		return KindTime
+	case "TIMESTAMP", "DATETIME":
+		return KindTimestamp
+	case "TIMESTAMPTZ":
+		return KindTimestampTZ
+	}
+	return KindText
+}
+
+// IsNumeric reports whether values of this kind are right-aligned in tables.
+func (k ColumnKind) IsNumeric() bool {
+	return k == KindInteger || k == KindFloat || k == KindDecimal
+}
+
+// TimeValue renders a temporal value in the canonical layout for its column type.
+func TimeValue(t time.Time, typeName string) string {
+	switch KindOf(typeName) {
+	case KindDate:
+		return t.Format(DateLayout)
+	case KindTime:
+		return t.Format(TimeLayout)
+	case KindTimestampTZ:
+		return t.Format(TimestampTZLayout)
+	default:
+		return t.Format(TimestampLayout)
+	}
+}
+
+// columnKinds returns the kind of every column in the result.
+func columnKinds(result *QueryResult) []ColumnKind {
+	kinds := make([]ColumnKind, len(result.Columns))
+	for i := range kinds {
+		if i < len(result.ColumnTypes) {
+			kinds[i] = KindOf(result.ColumnTypes[i])
+		}
+	}
+	return kinds
+}
+
+// nullText returns the string used to render NULL in the given output format.
+func (o Options) nullText(f OutputFormat) string {
+	if s, ok := o.NullValues[f]; ok {
+		return s
+	}
+	return o.NullValue
+}
+
+// rawCell returns the cell text and whether it is NULL, without any display formatting.
+func rawCell(result *QueryResult, row, col int) (string, bool) {
+	cells := result.Rows[row]
+	if col >= len(cells) {
+		return "", false
+	}
+	return cells[col], result.IsNull(row, col)
+}
+
+// displayCell returns the cell as it should appear in human-oriented output
+// (table and vertical), applying NULL, number and date settings.
+func displayCell(result *QueryResult, row, col int, kind ColumnKind, opts Options) (string, bool) {
+	cell, isNull := rawCell(result, row, col)
+	if isNull {
+		return opts.NullValue, true
+	}
+	return formatValue(cell, kind, opts), false
+}
+
+// formatValue applies number and date display settings to a single value.
+// Values that cannot be parsed are returned unchanged.
+func formatValue(cell string, kind ColumnKind, opts Options) string {
+	switch kind {
+	case KindInteger:
+		return formatNumber(cell, opts.ThousandsSep, opts.DecimalMark)
+	case KindFloat:
+		if opts.FloatFmt != "" {
+			if f, err := strconv.ParseFloat(cell, 64); err == nil {
+				cell = fmt.Sprintf(opts.FloatFmt, f)
+			}
+		}
+		return formatNumber(cell, opts.ThousandsSep, opts.DecimalMark)
+	case KindDecimal:
+		// Going through float64 would lose digits, so only a precision
+		// applies, by rounding the digits themselves
+		if places, ok := fixedPlaces(opts.FloatFmt); ok {
+			cell = roundDecimal(cell, places)
+		}
+		return formatNumber(cell, opts.ThousandsSep, opts.DecimalMark)
+	case KindDate:
+		return formatTime(cell, []string{DateLayout}, opts.DateFormat, "")
+	case KindTimestamp:
+		return formatTime(cell, []string{TimestampLayout}, opts.TimestampFormat, "")
+	case KindTimestampTZ:
+		return formatTime(cell, []string{TimestampTZLayout, "2006-01-02 15:04:05-07"}, opts.TimestampFormat, opts.TimeZone)
+	}
+	return cell
+}
+
+// fixedPlaces returns the number of decimal places a "%.Nf" format
+// rounds to.
+func fixedPlaces(format string) (int, bool) {
+	digits, ok := strings.CutPrefix(format, "%.")
+	if !ok {
+		return 0, false
+	}
+	digits, ok = strings.CutSuffix(digits, "f")
+	if !ok || !isDigits(digits) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// roundDecimal rounds a plain decimal number to places decimal places,
+// halves away from zero, padding with zeros as %.Nf would. Anything that
+// is not a plain number (NaN, Infinity) is returned as is.
+func roundDecimal(s string, places int) string {
+	sign := ""
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if !isDigits(intPart) || (fracPart != "" && !isDigits(fracPart)) {
+		return s
+	}
+	roundUp := len(fracPart) > places && fracPart[places] >= '5'
+	if len(fracPart) > places {
+		fracPart = fracPart[:places]
+	} else {
+		fracPart += strings.Repeat("0", places-len(fracPart))
+	}
+
+	kept := []byte(intPart + fracPart)
+	if roundUp {
+		i := len(kept) - 1
+		for ; i >= 0 && kept[i] == '9'; i-- {
+			kept[i] = '0'
+		}
+		if i >= 0 {
+			kept[i]++
+		} else {
+			kept = append([]byte{'1'}, kept...)
+		}
+	}
+	intPart, fracPart = string(kept[:len(kept)-places]), string(kept[len(kept)-places:])
+	if strings.Trim(intPart+fracPart, "0") == "" {
+		sign = "" // -0.001 rounds to 0.00, not -0.00
+	}
+	if places == 0 {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// formatNumber regroups the integer digits of a plain decimal number and
+// swaps the decimal mark. Anything that is not a plain number (NaN, 1e10,
+// Infinity) is returned as is.
+func formatNumber(s, thousandsSep, decimalMark string) string {
+	if thousandsSep == "" && (decimalMark == "" || decimalMark == ".") {
+		return s
+	}
+	sign := ""
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	intPart, fracPart, hasFrac := strings.Cut(digits, ".")
+	if intPart == "" || !isDigits(intPart) || (hasFrac && !isDigits(fracPart)) {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, d := range intPart {
+		if i > 0 && thousandsSep != "" && (len(intPart)-i)%3 == 0 {
+			b.WriteString(thousandsSep)
+		}
+		b.WriteRune(d)
+	}
+	if hasFrac {
+		if decimalMark == "" {
+			decimalMark = "."
+		}
+		b.WriteString(decimalMark)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatTime re-renders a canonical temporal value using a Go layout and,
+// optionally, converts it to the named time zone first.
+func formatTime(cell string, layouts []string, layout, zone string) string {
+	if layout == "" && zone == "" {
+		return cell
+	}
+	for _, l := range layouts {
+		t, err := time.Parse(l, cell)
+		if err != nil {
+			continue
+		}
+		if zone != "" {
+			if loc, err := time.LoadLocation(zone); err == nil {
+				t = t.In(loc)
+			}
+		}
+		if layout == "" {
+			layout = l
+		}
+		return t.Format(layout)
+	}
+	return cell
+}
@@ -0,0 +1,195 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		typeName string
+		expected ColumnKind
+	}{
+		{"INT4", KindInteger},
+		{"int8", KindInteger},
+		{"UNSIGNED BIGINT", KindInteger},
+		{"NUMERIC", KindDecimal},
+		{"decimal", KindDecimal},
+		{"DOUBLE", KindFloat},
+		{"DATE", KindDate},
+		{"TIMESTAMPTZ", KindTimestampTZ},
+		{"DATETIME", KindTimestamp},
+		{"TEXT", KindText},
+		{"", KindText},
+	}
+
+	for _, tt := range tests {
+		if got := KindOf(tt.typeName); got != tt.expected {
+			t.Errorf("KindOf(%q) = %v, want %v", tt.typeName, got, tt.expected)
+		}
+	}
+}
+
+func TestTimeValue(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 5, 6, 500000000, time.UTC)
+
+	if got := TimeValue(ts, "DATE"); got != "2024-03-09" {
+		t.Errorf("date: got %q", got)
+	}
+	if got := TimeValue(ts, "TIMESTAMP"); got != "2024-03-09 14:05:06.5" {
+		t.Errorf("timestamp: got %q", got)
+	}
+	if got := TimeValue(ts, "TIMESTAMPTZ"); got != "2024-03-09 14:05:06.5+00:00" {
+		t.Errorf("timestamptz: got %q", got)
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		input, sep, mark, expected string
+	}{
+		{"1234567", ",", "", "1,234,567"},
+		{"-1234567.891", ",", "", "-1,234,567.891"},
+		{"1234.5", ".", ",", "1.234,5"},
+		{"123", ",", "", "123"},
+		{"1e10", ",", "", "1e10"},
+		{"NaN", ",", "", "NaN"},
+		{"1234", "", "", "1234"},
+	}
+
+	for _, tt := range tests {
+		if got := formatNumber(tt.input, tt.sep, tt.mark); got != tt.expected {
+			t.Errorf("formatNumber(%q, %q, %q) = %q, want %q", tt.input, tt.sep, tt.mark, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatValue_FloatPrecision(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FloatFmt = "%.2f"
+	opts.ThousandsSep = ","
+
+	if got := formatValue("12345.6789", KindFloat, opts); got != "12,345.68" {
+		t.Errorf("got %q, want %q", got, "12,345.68")
+	}
+	// Precision does not apply to integer columns
+	if got := formatValue("12345", KindInteger, opts); got != "12,345" {
+		t.Errorf("got %q, want %q", got, "12,345")
+	}
+	// Text columns are never touched
+	if got := formatValue("12345", KindText, opts); got != "12345" {
+		t.Errorf("got %q, want %q", got, "12345")
+	}
+}
+
+func TestFormatValue_DecimalPrecision(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FloatFmt = "%.2f"
+	opts.ThousandsSep = ","
+
+	tests := []struct {
+		input, expected string
+	}{
+		// NUMERIC(38,10) is more precise than a float64
+		{"12345678901234567890.1234567890", "12,345,678,901,234,567,890.12"},
+		{"0.005", "0.01"},
+		{"-0.005", "-0.01"},
+		{"-0.001", "0.00"},
+		{"99.995", "100.00"},
+		{"7", "7.00"},
+		{"NaN", "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.input, KindDecimal, opts); got != tt.expected {
+			t.Errorf("formatValue(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+
+	// Other float formats would go through float64, so decimals keep their digits
+	opts.FloatFmt = "%.3e"
+	if got := formatValue("1234.5", KindDecimal, opts); got != "1,234.5" {
+		t.Errorf("got %q, want %q", got, "1,234.5")
+	}
+	opts.FloatFmt = "%.0f"
+	if got := formatValue("1234.5", KindDecimal, opts); got != "1,235" {
+		t.Errorf("got %q, want %q", got, "1,235")
+	}
+}
+
+func TestFormatValue_Dates(t *testing.T) {
+	opts := DefaultOptions()
+	opts.DateFormat = "02/01/2006"
+	opts.TimestampFormat = "2006-01-02 15:04 MST"
+	opts.TimeZone = "UTC"
+
+	if got := formatValue("2024-03-09", KindDate, opts); got != "09/03/2024" {
+		t.Errorf("date: got %q", got)
+	}
+	if got := formatValue("2024-03-09 16:05:06+02:00", KindTimestampTZ, opts); got != "2024-03-09 14:05 UTC" {
+		t.Errorf("timestamptz: got %q", got)
+	}
+	if got := formatValue("not a date", KindDate, opts); got != "not a date" {
+		t.Errorf("unparseable values should pass through, got %q", got)
+	}
+}
+
+func TestFormatTable_RightAlignsNumbers(t *testing.T) {
+	result := &QueryResult{
+		Columns:     []string{"name", "amount"},
+		ColumnTypes: []string{"TEXT", "INT4"},
+		Rows:        [][]string{{"a", "1"}, {"b", "1000"}},
+	}
+
+	var buf bytes.Buffer
+	opts := DefaultOptions()
+	opts.Style = ASCIIStyle
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "      1 ") || strings.Contains(buf.String(), " 1      ") {
+		t.Errorf("numeric column should be right-aligned, got:\n%s", buf.String())
+	}
+}
+
+func TestFormat_NullPerFormat(t *testing.T) {
+	result := &QueryResult{
+		Columns: []string{"id", "email"},
+		Rows:    [][]string{{"1", "NULL"}},
+		Nulls:   [][]bool{{false, true}},
+	}
+	opts := DefaultOptions()
+	opts.NullValue = "<null>"
+
+	var buf bytes.Buffer
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<null>") {
+		t.Errorf("table should render NULL as <null>, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	opts.Format = CSVFormat
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); lines[1] != "1," {
+		t.Errorf("CSV should render NULL as empty, got %q", lines[1])
+	}
+
+	buf.Reset()
+	opts.Format = JSONFormat
+	if err := Format(&buf, result, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var data []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if v, ok := data[0]["email"]; !ok || v != nil {
+		t.Errorf("JSON should render NULL as null, got %v", v)
+	}
+}
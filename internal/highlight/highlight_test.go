@@ -3,6 +3,8 @@ package highlight
 import (
 	"strings"
 	"testing"
+
+	"github.com/tomblomfield/gocli/internal/dialect"
 )
 
 func TestTokenize_SimpleSelect(t *testing.T) {
@@ -321,14 +323,14 @@ func TestDefaultStyle_HasAllColors(t *testing.T) {
 func TestIsKeyword(t *testing.T) {
 	keywords := []string{"SELECT", "FROM", "WHERE", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "DROP"}
 	for _, kw := range keywords {
-		if !isKeyword(kw) {
+		if !dialect.Generic().IsKeyword(kw) {
 			t.Errorf("%q should be a keyword", kw)
 		}
 	}
 
 	nonKeywords := []string{"users", "id", "name", "foobar"}
 	for _, w := range nonKeywords {
-		if isKeyword(strings.ToUpper(w)) {
+		if dialect.Generic().IsKeyword(w) {
 			t.Errorf("%q should not be a keyword", w)
 		}
 	}
@@ -337,7 +339,7 @@ func TestIsKeyword(t *testing.T) {
 func TestIsFunction(t *testing.T) {
 	functions := []string{"COUNT", "SUM", "AVG", "MAX", "MIN", "NOW", "COALESCE"}
 	for _, fn := range functions {
-		if !isFunction(fn) {
+		if !dialect.Generic().IsFunction(fn) {
 			t.Errorf("%q should be a function", fn)
 		}
 	}
@@ -376,6 +378,37 @@ func TestTokenize_PGSpecific(t *testing.T) {
 	}
 }
 
+func TestTokenizeDialect(t *testing.T) {
+	typeOf := func(sql, word string, d *dialect.Dialect) TokenType {
+		for _, tok := range TokenizeDialect(sql, d) {
+			if tok.Value == word {
+				return tok.Type
+			}
+		}
+		t.Fatalf("%q not found in %q", word, sql)
+		return 0
+	}
+
+	pg := dialect.New(dialect.PostgreSQL, "16.2")
+	my := dialect.New(dialect.MySQL, "8.0.35")
+
+	if got := typeOf("SELECT * FROM t WHERE a ILIKE 'x'", "ILIKE", pg); got != TokenKeyword {
+		t.Errorf("ILIKE should be a PostgreSQL keyword, got %v", got)
+	}
+	if got := typeOf("SELECT * FROM t WHERE a ILIKE 'x'", "ILIKE", my); got != TokenName {
+		t.Errorf("ILIKE should not be a MySQL keyword, got %v", got)
+	}
+	if got := typeOf("SELECT STRAIGHT_JOIN * FROM t", "STRAIGHT_JOIN", pg); got != TokenName {
+		t.Errorf("STRAIGHT_JOIN should not be a PostgreSQL keyword, got %v", got)
+	}
+	if got := typeOf("SELECT group_concat(a) FROM t", "group_concat", my); got != TokenFunction {
+		t.Errorf("group_concat should be a MySQL function, got %v", got)
+	}
+	if got := typeOf("SELECT group_concat(a) FROM t", "group_concat", pg); got != TokenName {
+		t.Errorf("group_concat should not be a PostgreSQL function, got %v", got)
+	}
+}
+
 func TestHighlight_DifferentStyles(t *testing.T) {
 	input := "SELECT * FROM users"
 	styles := []string{"default", "monokai", "native", "vim", "fruity"}
@@ -3,34 +3,47 @@ package mysql
 
 import (
 	"context"
+	"crypto/aes"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/tomblomfield/gocli/internal/completion"
+	"github.com/tomblomfield/gocli/internal/dialect"
 	"github.com/tomblomfield/gocli/internal/format"
 
-	_ "github.com/go-sql-driver/mysql"
+	gomysql "github.com/go-sql-driver/mysql"
 )
 
 // ConnectionConfig holds MySQL connection parameters.
 type ConnectionConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	Database string
-	Socket   string
-	SSL      bool
-	SSLCa    string
-	SSLCert  string
-	SSLKey   string
-	Charset  string
-	Options  map[string]string
+	Host        string
+	Port        int
+	User        string
+	Password    string
+	Database    string
+	Socket      string
+	SSL         bool
+	SSLCa       string
+	SSLCert     string
+	SSLKey      string
+	Charset     string
+	ReadOnly    bool   // every session is read-only
+	InitCommand string // run by each new connection, after the session settings
+	Options     map[string]string
 }
 
 // DefaultConfig returns default connection parameters.
@@ -117,15 +130,27 @@ func ParseDSN(dsn string) (ConnectionConfig, error) {
 
 // ParseMyCnf reads MySQL credentials from my.cnf/my.ini.
 func ParseMyCnf() ConnectionConfig {
+	return ParseLoginPath("")
+}
+
+// ParseLoginPath reads MySQL credentials from the [client] and [mysql]
+// groups of the option files, and then from the group named loginPath,
+// as mysql --login-path does. Login paths usually live in the encrypted
+// ~/.mylogin.cnf written by mysql_config_editor.
+func ParseLoginPath(loginPath string) ConnectionConfig {
 	cfg := DefaultConfig()
+	groups := []string{"client", "mysql"}
+	if loginPath != "" {
+		groups = append(groups, loginPath)
+	}
 
 	paths := myCnfPaths()
 	for _, path := range paths {
-		data, err := os.ReadFile(path)
+		data, err := readOptionFile(path)
 		if err != nil {
 			continue
 		}
-		parsed := parseMyCnfData(string(data))
+		parsed := parseMyCnfGroups(data, groups...)
 		if v, ok := parsed["host"]; ok && v != "" {
 			cfg.Host = v
 		}
@@ -168,9 +193,69 @@ func myCnfPaths() []string {
 	return paths
 }
 
+// readOptionFile returns the text of an option file, decrypting
+// .mylogin.cnf.
+func readOptionFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(path) == ".mylogin.cnf" {
+		return decryptMyLoginCnf(data)
+	}
+	return string(data), nil
+}
+
+// decryptMyLoginCnf decodes the format mysql_config_editor writes: four
+// unused bytes, a 20-byte key, then lines each stored as a little-endian
+// length and an AES-128-ECB block sequence with PKCS#7 padding.
+func decryptMyLoginCnf(data []byte) (string, error) {
+	const keyLen = 20
+	if len(data) < 4+keyLen {
+		return "", fmt.Errorf("login file is too short")
+	}
+	key := make([]byte, aes.BlockSize)
+	for i, b := range data[4 : 4+keyLen] {
+		key[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for rest := data[4+keyLen:]; len(rest) >= 4; {
+		n := int(binary.LittleEndian.Uint32(rest))
+		rest = rest[4:]
+		if n > len(rest) || n%aes.BlockSize != 0 {
+			return "", fmt.Errorf("login file is corrupt")
+		}
+		line := make([]byte, n)
+		for i := 0; i < n; i += aes.BlockSize {
+			block.Decrypt(line[i:i+aes.BlockSize], rest[i:i+aes.BlockSize])
+		}
+		rest = rest[n:]
+		if n > 0 {
+			pad := int(line[n-1])
+			if pad == 0 || pad > n {
+				return "", fmt.Errorf("login file is corrupt")
+			}
+			line = line[:n-pad]
+		}
+		text.Write(line)
+	}
+	return text.String(), nil
+}
+
 func parseMyCnfData(data string) map[string]string {
+	return parseMyCnfGroups(data, "client", "mysql")
+}
+
+// parseMyCnfGroups returns the options set in any of groups, later
+// settings overriding earlier ones.
+func parseMyCnfGroups(data string, groups ...string) map[string]string {
 	result := make(map[string]string)
-	inClientSection := false
+	inGroup := false
 
 	for _, line := range strings.Split(data, "\n") {
 		line = strings.TrimSpace(line)
@@ -179,10 +264,10 @@ func parseMyCnfData(data string) map[string]string {
 		}
 		if strings.HasPrefix(line, "[") {
 			section := strings.Trim(line, "[]")
-			inClientSection = section == "client" || section == "mysql"
+			inGroup = slices.Contains(groups, section)
 			continue
 		}
-		if !inClientSection {
+		if !inGroup {
 			continue
 		}
 		parts := strings.SplitN(line, "=", 2)
@@ -197,32 +282,120 @@ func parseMyCnfData(data string) map[string]string {
 	return result
 }
 
-// Executor handles MySQL query execution.
+// Executor handles MySQL query execution. User statements run on one
+// connection for the whole session, so USE, session variables, temporary
+// tables and transactions carry over between them; metadata queries use
+// the other connections of the pool.
 type Executor struct {
-	db       *sql.DB
-	config   ConnectionConfig
-	database string
+	db        *sql.DB
+	session   *sql.Conn
+	sessionID int64 // CONNECTION_ID() of the session connection
+	mariaDB   bool  // the server has max_statement_time instead of max_execution_time
+	config    ConnectionConfig
+
+	// The current database and the statement and lock timeouts. Once a
+	// timeout has been set, new connections get them when they open and
+	// the session connection gets them again before its next statement
+	// after a change.
+	mu               sync.Mutex
+	database         string
+	statementTimeout time.Duration
+	lockTimeout      time.Duration
+	timeoutGen       int // counts changes to the timeouts
+	sessionGen       int // the timeoutGen the session connection has
+}
+
+// SessionStatements returns the statements each new connection runs
+// before it is used, so every connection in the pool has the same
+// session settings.
+func (c ConnectionConfig) SessionStatements() []string {
+	var stmts []string
+	if c.ReadOnly {
+		stmts = append(stmts, "SET SESSION TRANSACTION READ ONLY")
+	}
+	if c.InitCommand != "" {
+		stmts = append(stmts, c.InitCommand)
+	}
+	return stmts
+}
+
+// sessionConnector runs the session statements on each new connection,
+// then sets the executor's timeouts on it.
+type sessionConnector struct {
+	driver.Connector
+	stmts []string
+	e     *Executor
+}
+
+func (c sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exec := func(stmt string) error {
+		_, err := conn.(driver.ExecerContext).ExecContext(ctx, stmt, nil)
+		return err
+	}
+	for _, stmt := range c.stmts {
+		if err := exec(stmt); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	c.e.mu.Lock()
+	gen, statement, lock := c.e.timeoutGen, c.e.statementTimeout, c.e.lockTimeout
+	c.e.mu.Unlock()
+	if gen > 0 {
+		if err := exec(c.e.timeoutStatement(statement, lock)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
 }
 
 // NewExecutor creates a new MySQL executor.
 func NewExecutor(config ConnectionConfig) (*Executor, error) {
-	db, err := sql.Open("mysql", config.DSN())
+	driverConfig, err := gomysql.ParseDSN(config.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to ping: %w", err)
+	connector, err := gomysql.NewConnector(driverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
-	return &Executor{
-		db:       db,
+	e := &Executor{
 		config:   config,
 		database: config.Database,
-	}, nil
+	}
+	e.db = sql.OpenDB(sessionConnector{connector, config.SessionStatements(), e})
+	if err := e.openSession(context.Background()); err != nil {
+		e.db.Close()
+		return nil, fmt.Errorf("failed to ping: %w", err)
+	}
+	return e, nil
+}
+
+// openSession opens the session connection and learns which server it is
+// talking to.
+func (e *Executor) openSession(ctx context.Context) error {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	var version string
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID(), VERSION()").Scan(&e.sessionID, &version); err != nil {
+		conn.Close()
+		return err
+	}
+	e.mariaDB = strings.Contains(version, "MariaDB")
+	e.session = conn
+	return nil
 }
 
 // Close closes the database connection.
 func (e *Executor) Close() error {
+	e.session.Close()
 	return e.db.Close()
 }
 
@@ -233,9 +406,16 @@ func (e *Executor) DB() *sql.DB {
 
 // Database returns the current database name.
 func (e *Executor) Database() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.database
 }
 
+// User returns the user the connection logged in as.
+func (e *Executor) User() string {
+	return e.config.User
+}
+
 // ServerVersion returns the MySQL server version.
 func (e *Executor) ServerVersion() (string, error) {
 	var version string
@@ -259,25 +439,137 @@ func (e *Executor) Execute(ctx context.Context, query string) (*format.QueryResu
 		strings.HasPrefix(upper, "WITH") ||
 		strings.HasPrefix(upper, "TABLE")
 
+	if err := e.applyTimeouts(ctx); err != nil {
+		return nil, err
+	}
+	var result *format.QueryResult
+	var err error
 	if isSelect {
-		return e.executeQuery(ctx, query)
+		result, err = e.executeQuery(ctx, query)
+	} else {
+		result, err = e.executeExec(ctx, query)
 	}
-	return e.executeExec(ctx, query)
+	if err != nil && ctx.Err() != nil {
+		e.killQuery()
+	}
+	return result, err
+}
+
+// killQueryTimeout bounds stopping an abandoned statement.
+const killQueryTimeout = 5 * time.Second
+
+// killQuery stops the statement the session connection was running when
+// its context ended. The driver gives up on the connection at once, but
+// the server would otherwise finish the statement, which matters for
+// writes: max_execution_time only covers SELECT.
+func (e *Executor) killQuery() {
+	ctx, cancel := context.WithTimeout(context.Background(), killQueryTimeout)
+	defer cancel()
+	e.db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", e.sessionID))
+}
+
+// SetTimeouts has the server stop SELECTs running longer than statement
+// and give up waiting for row locks after lock. Zero turns a timeout off.
+func (e *Executor) SetTimeouts(statement, lock time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if statement == e.statementTimeout && lock == e.lockTimeout {
+		return
+	}
+	e.statementTimeout, e.lockTimeout = statement, lock
+	e.timeoutGen++
+}
+
+// applyTimeouts sets the current timeouts on the session connection after
+// they change.
+func (e *Executor) applyTimeouts(ctx context.Context) error {
+	e.mu.Lock()
+	gen, statement, lock := e.timeoutGen, e.statementTimeout, e.lockTimeout
+	current := e.sessionGen == gen
+	e.mu.Unlock()
+	if current {
+		return nil
+	}
+	if _, err := e.session.ExecContext(ctx, e.timeoutStatement(statement, lock)); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.sessionGen = gen
+	e.mu.Unlock()
+	return nil
+}
+
+// timeoutStatement sets the session timeouts. MySQL counts
+// max_execution_time in milliseconds, MariaDB max_statement_time in
+// seconds, and innodb_lock_wait_timeout is whole seconds.
+func (e *Executor) timeoutStatement(statement, lockTimeout time.Duration) string {
+	lock := "DEFAULT"
+	if lockTimeout > 0 {
+		lock = strconv.FormatInt(int64((lockTimeout+time.Second-1)/time.Second), 10)
+	}
+	if e.mariaDB {
+		return fmt.Sprintf("SET SESSION max_statement_time = %g, SESSION innodb_lock_wait_timeout = %s",
+			statement.Seconds(), lock)
+	}
+	return fmt.Sprintf("SET SESSION max_execution_time = %d, SESSION innodb_lock_wait_timeout = %s",
+		statement.Milliseconds(), lock)
+}
+
+// IsTimeout reports whether err is a statement stopped by
+// max_execution_time, max_statement_time or innodb_lock_wait_timeout.
+func (e *Executor) IsTimeout(err error) bool {
+	var myErr *gomysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	switch myErr.Number {
+	case 3024, // ER_QUERY_TIMEOUT
+		1969, // MariaDB's ER_STATEMENT_TIMEOUT
+		1205: // ER_LOCK_WAIT_TIMEOUT
+		return true
+	}
+	return false
+}
+
+// IsConnectionLost reports whether err means the connection to the
+// server broke, so the session has to reconnect.
+func (e *Executor) IsConnectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, gomysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var myErr *gomysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1053, // ER_SERVER_SHUTDOWN
+			2006, // CR_SERVER_GONE_ERROR
+			2013, // CR_SERVER_LOST
+			4031: // ER_CLIENT_INTERACTION_TIMEOUT
+			return true
+		}
+		return false
+	}
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
 }
 
 func (e *Executor) executeQuery(ctx context.Context, query string) (*format.QueryResult, error) {
-	rows, err := e.db.QueryContext(ctx, query)
+	start := time.Now()
+	rows, err := e.session.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	answered := time.Now()
 
 	cols, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
+	colTypes := columnTypeNames(rows, len(cols))
 
 	var resultRows [][]string
+	var nulls [][]bool
 	for rows.Next() {
 		values := make([]interface{}, len(cols))
 		ptrs := make([]interface{}, len(cols))
@@ -288,25 +580,37 @@ func (e *Executor) executeQuery(ctx context.Context, query string) (*format.Quer
 			return nil, err
 		}
 		row := make([]string, len(cols))
+		rowNulls := make([]bool, len(cols))
 		for i, v := range values {
+			if t, ok := v.(time.Time); ok {
+				row[i] = format.TimeValue(t, colTypes[i])
+				continue
+			}
 			row[i] = formatValue(v)
+			rowNulls[i] = v == nil
 		}
 		resultRows = append(resultRows, row)
+		nulls = append(nulls, rowNulls)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
 	return &format.QueryResult{
-		Columns:    cols,
-		Rows:       resultRows,
-		StatusText: fmt.Sprintf("%d row%s in set", len(resultRows), pluralS(len(resultRows))),
-		RowCount:   len(resultRows),
+		Columns:     cols,
+		ColumnTypes: colTypes,
+		Rows:        resultRows,
+		Nulls:       nulls,
+		StatusText:  fmt.Sprintf("%d row%s in set", len(resultRows), pluralS(len(resultRows))),
+		RowCount:    len(resultRows),
+		ServerTime:  answered.Sub(start),
+		FetchTime:   time.Since(answered),
 	}, nil
 }
 
 func (e *Executor) executeExec(ctx context.Context, query string) (*format.QueryResult, error) {
-	result, err := e.db.ExecContext(ctx, query)
+	start := time.Now()
+	result, err := e.session.ExecContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -317,11 +621,15 @@ func (e *Executor) executeExec(ctx context.Context, query string) (*format.Query
 	if strings.HasPrefix(upper, "USE ") {
 		db := strings.TrimSpace(query[4:])
 		db = strings.Trim(db, "`\"' ;")
+		e.mu.Lock()
 		e.database = db
+		e.mu.Unlock()
 	}
 
 	return &format.QueryResult{
 		StatusText: fmt.Sprintf("Query OK, %d row%s affected", affected, pluralS(int(affected))),
+		RowCount:   int(affected),
+		ServerTime: time.Since(start),
 	}, nil
 }
 
@@ -337,6 +645,22 @@ func formatValue(v interface{}) string {
 	}
 }
 
+// columnTypeNames returns the database type name of each result column.
+// Drivers that cannot report types yield empty names.
+func columnTypeNames(rows *sql.Rows, n int) []string {
+	names := make([]string, n)
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return names
+	}
+	for i, ct := range types {
+		if i < n {
+			names[i] = ct.DatabaseTypeName()
+		}
+	}
+	return names
+}
+
 func pluralS(n int) string {
 	if n == 1 {
 		return ""
@@ -349,7 +673,7 @@ func pluralS(n int) string {
 // Tables returns all table names.
 func (e *Executor) Tables(ctx context.Context, database string) ([]string, error) {
 	if database == "" {
-		database = e.database
+		database = e.Database()
 	}
 	if database == "" {
 		return nil, nil
@@ -370,26 +694,135 @@ func (e *Executor) Columns(ctx context.Context, table string) ([]string, error)
 	} else {
 		query = `SELECT column_name FROM information_schema.columns
 			WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position`
-		args = []interface{}{e.database, parts[0]}
+		args = []interface{}{e.Database(), parts[0]}
 	}
 	return e.queryStrings(ctx, query, args...)
 }
 
+// Relations returns every user table and view with its columns, read from
+// information_schema in a single query.
+func (e *Executor) Relations(ctx context.Context) ([]completion.Relation, error) {
+	query := `SELECT t.table_schema, t.table_name, t.table_type = 'VIEW', COALESCE(c.column_name, '')
+	FROM information_schema.tables t
+	LEFT JOIN information_schema.columns c
+		ON c.table_schema = t.table_schema AND c.table_name = t.table_name
+	WHERE t.table_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+	ORDER BY t.table_schema, t.table_name, c.ordinal_position`
+
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRelations(rows)
+}
+
+// scanRelations groups (schema, name, view, column) rows ordered by
+// relation into relations.
+func scanRelations(rows *sql.Rows) ([]completion.Relation, error) {
+	var rels []completion.Relation
+	for rows.Next() {
+		var schema, name, column string
+		var view bool
+		if err := rows.Scan(&schema, &name, &view, &column); err != nil {
+			return nil, err
+		}
+		if n := len(rels); n == 0 || rels[n-1].Schema != schema || rels[n-1].Name != name {
+			rels = append(rels, completion.Relation{Schema: schema, Name: name, View: view})
+		}
+		if column != "" {
+			rel := &rels[len(rels)-1]
+			rel.Columns = append(rel.Columns, column)
+		}
+	}
+	return rels, rows.Err()
+}
+
 // Schemas returns all database/schema names.
 func (e *Executor) Schemas(ctx context.Context) ([]string, error) {
 	query := `SELECT schema_name FROM information_schema.schemata ORDER BY schema_name`
 	return e.queryStrings(ctx, query)
 }
 
+// SearchPath returns the databases whose tables can be named without a
+// database prefix: just the current one.
+func (e *Executor) SearchPath(ctx context.Context) ([]string, error) {
+	database := e.Database()
+	if database == "" {
+		return nil, nil
+	}
+	return []string{database}, nil
+}
+
 // Functions returns all function names.
 func (e *Executor) Functions(ctx context.Context, database string) ([]string, error) {
 	if database == "" {
-		database = e.database
+		database = e.Database()
 	}
 	query := `SELECT routine_name FROM information_schema.routines WHERE routine_schema = ? ORDER BY routine_name`
 	return e.queryStrings(ctx, query, database)
 }
 
+// FunctionSignatures returns the parameters and return type of the stored
+// functions and procedures in the current database.
+func (e *Executor) FunctionSignatures(ctx context.Context) ([]completion.Function, error) {
+	query := `SELECT r.routine_schema, r.routine_name,
+		COALESCE(GROUP_CONCAT(
+			CONCAT(COALESCE(CONCAT(p.parameter_mode, ' '), ''), p.parameter_name, ' ', p.dtd_identifier)
+			ORDER BY p.ordinal_position SEPARATOR ', '), ''),
+		COALESCE(r.dtd_identifier, '')
+	FROM information_schema.routines r
+	LEFT JOIN information_schema.parameters p
+		ON p.specific_schema = r.routine_schema AND p.specific_name = r.specific_name
+		AND p.ordinal_position > 0
+	WHERE r.routine_schema = ?
+	GROUP BY r.routine_schema, r.specific_name, r.routine_name, r.dtd_identifier
+	ORDER BY r.routine_name`
+
+	rows, err := e.db.QueryContext(ctx, query, e.Database())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fns []completion.Function
+	for rows.Next() {
+		var f completion.Function
+		if err := rows.Scan(&f.Schema, &f.Name, &f.Arguments, &f.Result); err != nil {
+			return nil, err
+		}
+		fns = append(fns, f)
+	}
+	return fns, rows.Err()
+}
+
+// ForeignKeys returns the foreign key relationships of the current database.
+func (e *Executor) ForeignKeys(ctx context.Context) ([]completion.ForeignKey, error) {
+	query := `SELECT table_schema, table_name, column_name,
+		referenced_table_schema, referenced_table_name, referenced_column_name,
+		constraint_name
+	FROM information_schema.key_column_usage
+	WHERE referenced_table_name IS NOT NULL AND table_schema = ?
+	ORDER BY table_name, constraint_name, ordinal_position`
+
+	rows, err := e.db.QueryContext(ctx, query, e.Database())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []completion.ForeignKey
+	for rows.Next() {
+		var fk completion.ForeignKey
+		if err := rows.Scan(&fk.ChildSchema, &fk.ChildTable, &fk.ChildColumn,
+			&fk.ParentSchema, &fk.ParentTable, &fk.ParentColumn, &fk.Constraint); err != nil {
+			return nil, err
+		}
+		fks = append(fks, fk)
+	}
+	return fks, rows.Err()
+}
+
 // Databases returns all database names.
 func (e *Executor) Databases(ctx context.Context) ([]string, error) {
 	query := `SELECT schema_name FROM information_schema.schemata ORDER BY schema_name`
@@ -398,17 +831,7 @@ func (e *Executor) Databases(ctx context.Context) ([]string, error) {
 
 // Datatypes returns common MySQL data types.
 func (e *Executor) Datatypes(_ context.Context) []string {
-	return []string{
-		"bigint", "binary", "bit", "blob", "boolean", "char",
-		"date", "datetime", "decimal", "double", "enum",
-		"float", "geometry", "int", "integer", "json",
-		"linestring", "longblob", "longtext", "mediumblob",
-		"mediumint", "mediumtext", "multilinestring", "multipoint",
-		"multipolygon", "numeric", "point", "polygon",
-		"real", "set", "smallint", "text", "time",
-		"timestamp", "tinyblob", "tinyint", "tinytext",
-		"varbinary", "varchar", "year",
-	}
+	return dialect.New(dialect.MySQL, "").Datatypes()
 }
 
 func (e *Executor) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
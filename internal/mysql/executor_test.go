@@ -1,10 +1,20 @@
 package mysql
 
 import (
+	"bytes"
+	"crypto/aes"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	gomysql "github.com/go-sql-driver/mysql"
 )
 
 func TestDefaultConfig_MySQL(t *testing.T) {
@@ -348,6 +358,65 @@ func TestConnectionConfig_DSN_WithOptions(t *testing.T) {
 	}
 }
 
+func TestConnectionConfig_ReadOnly(t *testing.T) {
+	cfg := DefaultConfig()
+	if len(cfg.SessionStatements()) != 0 {
+		t.Errorf("sessions should be writable by default, got %v", cfg.SessionStatements())
+	}
+	cfg.ReadOnly = true
+	stmts := cfg.SessionStatements()
+	if len(stmts) != 1 || stmts[0] != "SET SESSION TRANSACTION READ ONLY" {
+		t.Errorf("each session should be made read-only, got %v", stmts)
+	}
+
+	cfg.InitCommand = "SET NAMES utf8mb4"
+	if stmts := cfg.SessionStatements(); len(stmts) != 2 || stmts[1] != "SET NAMES utf8mb4" {
+		t.Errorf("each session should run the init command last, got %v", stmts)
+	}
+}
+
+func TestIsTimeout_MySQL(t *testing.T) {
+	e := &Executor{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&gomysql.MySQLError{Number: 3024, Message: "Query execution was interrupted, maximum statement execution time exceeded"}, true},
+		{&gomysql.MySQLError{Number: 1969, Message: "Query execution was interrupted (max_statement_time exceeded)"}, true},
+		{fmt.Errorf("exec: %w", &gomysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}), true},
+		{&gomysql.MySQLError{Number: 1317, Message: "Query execution was interrupted"}, false},
+		{&gomysql.MySQLError{Number: 1146, Message: "Table 'shop.users' doesn't exist"}, false},
+		{errors.New("timeout"), false},
+	}
+	for _, tt := range tests {
+		if got := e.IsTimeout(tt.err); got != tt.want {
+			t.Errorf("IsTimeout(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsConnectionLost_MySQL(t *testing.T) {
+	e := &Executor{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{gomysql.ErrInvalidConn, true},
+		{fmt.Errorf("read: %w", io.EOF), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{&gomysql.MySQLError{Number: 2006, Message: "MySQL server has gone away"}, true},
+		{&gomysql.MySQLError{Number: 4031, Message: "The client was disconnected by the server because of inactivity"}, true},
+		{&gomysql.MySQLError{Number: 1146, Message: "Table 'shop.users' doesn't exist"}, false},
+		{errors.New("syntax error"), false},
+	}
+	for _, tt := range tests {
+		if got := e.IsConnectionLost(tt.err); got != tt.want {
+			t.Errorf("IsConnectionLost(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
 func TestParseDSN_MysqlPlus(t *testing.T) {
 	cfg, err := ParseDSN("mysql+pymysql://user@host/db")
 	if err != nil {
@@ -375,3 +444,68 @@ host = mysqlhost
 		t.Errorf("should parse [mysql] section, got host=%q", result["host"])
 	}
 }
+
+func TestParseMyCnfGroups_LoginPath(t *testing.T) {
+	content := `[client]
+user = everyone
+
+[staging]
+host = staging.example.com
+user = deploy
+`
+	result := parseMyCnfGroups(content, "client", "mysql", "staging")
+	if result["host"] != "staging.example.com" || result["user"] != "deploy" {
+		t.Errorf("the login path should override [client], got %v", result)
+	}
+	if result := parseMyCnfData(content); result["user"] != "everyone" {
+		t.Errorf("other login paths should be ignored, got %v", result)
+	}
+}
+
+// encryptMyLoginCnf writes text the way mysql_config_editor does.
+func encryptMyLoginCnf(t *testing.T, text string) []byte {
+	t.Helper()
+	key := []byte("0123456789abcdefghij")
+	folded := make([]byte, aes.BlockSize)
+	for i, b := range key {
+		folded[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(folded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := append(make([]byte, 4), key...)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		pad := aes.BlockSize - len(line)%aes.BlockSize
+		plain := append([]byte(line), bytes.Repeat([]byte{byte(pad)}, pad)...)
+		cipher := make([]byte, len(plain))
+		for i := 0; i < len(plain); i += aes.BlockSize {
+			block.Encrypt(cipher[i:i+aes.BlockSize], plain[i:i+aes.BlockSize])
+		}
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(cipher)))
+		out = append(out, cipher...)
+	}
+	return out
+}
+
+func TestDecryptMyLoginCnf(t *testing.T) {
+	text := "[client]\nuser = root\n[prod]\nhost = db.example.com\npassword = s3cret!\n"
+	got, err := decryptMyLoginCnf(encryptMyLoginCnf(t, text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != text {
+		t.Errorf("decrypted %q, want %q", got, text)
+	}
+	if _, err := decryptMyLoginCnf([]byte("short")); err == nil {
+		t.Error("a truncated file should be an error")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".mylogin.cnf")
+	os.WriteFile(path, encryptMyLoginCnf(t, text), 0600)
+	data, err := readOptionFile(path)
+	if err != nil || parseMyCnfGroups(data, "prod")["password"] != "s3cret!" {
+		t.Errorf("readOptionFile should decrypt .mylogin.cnf, got %q, %v", data, err)
+	}
+}
@@ -4,28 +4,41 @@ package pg
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/tomblomfield/gocli/internal/completion"
+	"github.com/tomblomfield/gocli/internal/dialect"
 	"github.com/tomblomfield/gocli/internal/format"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 // ConnectionConfig holds PostgreSQL connection parameters.
 type ConnectionConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	Database string
-	SSLMode  string
-	Options  map[string]string
+	Host             string
+	Port             int
+	User             string
+	Password         string
+	Database         string
+	SSLMode          string
+	ReadOnly         bool   // every session is read-only
+	InitCommand      string // run by each new connection, after the session settings
+	SingleConnection bool   // metadata queries share the session's connection
+	Options          map[string]string
 }
 
 // DefaultConfig returns default connection parameters.
@@ -56,6 +69,9 @@ func (c ConnectionConfig) DSN() string {
 	if c.SSLMode != "" {
 		q.Set("sslmode", c.SSLMode)
 	}
+	if c.ReadOnly {
+		q.Set("default_transaction_read_only", "on")
+	}
 	for k, v := range c.Options {
 		q.Set(k, v)
 	}
@@ -195,32 +211,85 @@ func matchPgpassField(pattern, value string) bool {
 	return pattern == "*" || pattern == value
 }
 
-// Executor handles PostgreSQL query execution.
+// SessionStatements returns the statements each new connection runs
+// before it is used, so every connection in the pool has the same
+// session settings.
+func (c ConnectionConfig) SessionStatements() []string {
+	var stmts []string
+	if c.ReadOnly {
+		stmts = append(stmts, "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY")
+	}
+	if c.InitCommand != "" {
+		stmts = append(stmts, c.InitCommand)
+	}
+	return stmts
+}
+
+// Executor handles PostgreSQL query execution. User statements run on
+// one connection for the whole session, so SET, temporary tables and
+// transactions carry over between them; metadata queries use the other
+// connections of the pool.
 type Executor struct {
 	db       *sql.DB
+	session  *sql.Conn // nil in single-connection mode
 	config   ConnectionConfig
 	database string
+
+	// Statement and lock timeouts. Once either has been set, new
+	// connections get them when they open and the session connection gets
+	// them again before its next statement after a change.
+	mu               sync.Mutex
+	statementTimeout time.Duration
+	lockTimeout      time.Duration
+	timeoutGen       int      // counts changes to the timeouts
+	sessionGen       int      // the timeoutGen the session connection has
+	searchPath       []string // the session's search path once it has set one
 }
 
 // NewExecutor creates a new PostgreSQL executor.
 func NewExecutor(config ConnectionConfig) (*Executor, error) {
-	db, err := sql.Open("pgx", config.DSN())
+	connConfig, err := pgx.ParseConfig(config.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
-	if err := db.Ping(); err != nil {
+	e := &Executor{
+		config:   config,
+		database: config.Database,
+	}
+	stmts := config.SessionStatements()
+	db := stdlib.OpenDB(*connConfig, stdlib.OptionAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		for _, stmt := range stmts {
+			if _, err := conn.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		e.mu.Lock()
+		gen, statement, lock := e.timeoutGen, e.statementTimeout, e.lockTimeout
+		e.mu.Unlock()
+		if gen == 0 {
+			return nil
+		}
+		return setTimeouts(ctx, conn, statement, lock)
+	}))
+	if config.SingleConnection {
+		db.SetMaxOpenConns(1)
+		err = db.Ping()
+	} else {
+		e.session, err = db.Conn(context.Background())
+	}
+	if err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to ping: %w", err)
 	}
-	return &Executor{
-		db:       db,
-		config:   config,
-		database: config.Database,
-	}, nil
+	e.db = db
+	return e, nil
 }
 
 // Close closes the database connection.
 func (e *Executor) Close() error {
+	if e.session != nil {
+		e.session.Close()
+	}
 	return e.db.Close()
 }
 
@@ -234,6 +303,11 @@ func (e *Executor) Database() string {
 	return e.database
 }
 
+// User returns the user the connection logged in as.
+func (e *Executor) User() string {
+	return e.config.User
+}
+
 // ServerVersion returns the PostgreSQL server version.
 func (e *Executor) ServerVersion() (string, error) {
 	var version string
@@ -259,25 +333,165 @@ func (e *Executor) Execute(ctx context.Context, query string) (*format.QueryResu
 		strings.HasPrefix(upper, "FETCH") ||
 		strings.Contains(upper, "RETURNING")
 
+	conn, release, err := e.sessionConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := e.applyTimeouts(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	var result *format.QueryResult
 	if isSelect {
-		return e.executeQuery(ctx, query)
+		result, err = e.executeQuery(ctx, conn, query)
+	} else {
+		result, err = e.executeExec(ctx, conn, query)
+	}
+	if err == nil && strings.Contains(strings.ToLower(query), "search_path") {
+		e.noteSearchPath(ctx, conn)
 	}
-	return e.executeExec(ctx, query)
+	return result, err
 }
 
-func (e *Executor) executeQuery(ctx context.Context, query string) (*format.QueryResult, error) {
-	rows, err := e.db.QueryContext(ctx, query)
+// sessionConn returns the connection user statements run on. In
+// single-connection mode that is the pool's only connection, which
+// release hands back for metadata queries.
+func (e *Executor) sessionConn(ctx context.Context) (*sql.Conn, func(), error) {
+	if e.session != nil {
+		return e.session, func() {}, nil
+	}
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, func() { conn.Close() }, nil
+}
+
+// noteSearchPath records the session's search path after a statement that
+// may have changed it, for completion queries that run elsewhere.
+func (e *Executor) noteSearchPath(ctx context.Context, conn *sql.Conn) {
+	schemas, err := scanStrings(conn.QueryContext(ctx, "SELECT unnest(current_schemas(true))"))
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	e.searchPath = schemas
+	e.mu.Unlock()
+}
+
+// SetTimeouts has the server cancel statements running longer than
+// statement and give up waiting for locks after lock. Zero turns a
+// timeout off.
+func (e *Executor) SetTimeouts(statement, lock time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if statement == e.statementTimeout && lock == e.lockTimeout {
+		return
+	}
+	e.statementTimeout, e.lockTimeout = statement, lock
+	e.timeoutGen++
+}
+
+// applyTimeouts sets the current timeouts on the session connection
+// after they change. A connection in a transaction is left until it ends:
+// a failed transaction refuses everything but ROLLBACK, and a rollback
+// would undo the settings.
+func (e *Executor) applyTimeouts(ctx context.Context, conn *sql.Conn) error {
+	e.mu.Lock()
+	gen, statement, lock := e.timeoutGen, e.statementTimeout, e.lockTimeout
+	current := e.sessionGen == gen
+	e.mu.Unlock()
+	if current {
+		return nil
+	}
+	err := conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+		if pgxConn.PgConn().TxStatus() != 'I' {
+			return errInTransaction
+		}
+		return setTimeouts(ctx, pgxConn, statement, lock)
+	})
+	switch {
+	case errors.Is(err, errInTransaction):
+		return nil
+	case err != nil:
+		return err
+	}
+	e.mu.Lock()
+	e.sessionGen = gen
+	e.mu.Unlock()
+	return nil
+}
+
+// errInTransaction defers setting timeouts until a transaction ends.
+var errInTransaction = errors.New("connection is in a transaction")
+
+// setTimeouts sets the statement and lock timeouts of conn.
+func setTimeouts(ctx context.Context, conn *pgx.Conn, statement, lock time.Duration) error {
+	_, err := conn.Exec(ctx, "SELECT set_config('statement_timeout', $1, false), set_config('lock_timeout', $2, false)",
+		milliseconds(statement), milliseconds(lock))
+	return err
+}
+
+// IsTimeout reports whether err is a statement canceled by
+// statement_timeout or lock_timeout.
+func (e *Executor) IsTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "55P03": // lock_not_available
+		return true
+	case "57014": // query_canceled, also raised when the user cancels
+		return strings.Contains(pgErr.Message, "timeout")
+	}
+	return false
+}
+
+// IsConnectionLost reports whether err means the connection to the
+// server broke, so the session has to reconnect.
+func (e *Executor) IsConnectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "57P01", "57P02", "57P03": // admin_shutdown, crash_shutdown, cannot_connect_now
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, "08") // connection_exception
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr *net.OpError
+	return errors.As(err, &connectErr) || errors.As(err, &netErr)
+}
+
+// milliseconds formats d as a PostgreSQL timeout setting, "0" being none.
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+func (e *Executor) executeQuery(ctx context.Context, conn *sql.Conn, query string) (*format.QueryResult, error) {
+	start := time.Now()
+	rows, err := conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	answered := time.Now()
 
 	cols, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
+	colTypes := columnTypeNames(rows, len(cols))
 
 	var resultRows [][]string
+	var nulls [][]bool
 	for rows.Next() {
 		values := make([]interface{}, len(cols))
 		ptrs := make([]interface{}, len(cols))
@@ -288,25 +502,37 @@ func (e *Executor) executeQuery(ctx context.Context, query string) (*format.Quer
 			return nil, err
 		}
 		row := make([]string, len(cols))
+		rowNulls := make([]bool, len(cols))
 		for i, v := range values {
+			if t, ok := v.(time.Time); ok {
+				row[i] = format.TimeValue(t, colTypes[i])
+				continue
+			}
 			row[i] = formatValue(v)
+			rowNulls[i] = v == nil
 		}
 		resultRows = append(resultRows, row)
+		nulls = append(nulls, rowNulls)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
 	return &format.QueryResult{
-		Columns:    cols,
-		Rows:       resultRows,
-		StatusText: fmt.Sprintf("(%d row%s)", len(resultRows), pluralS(len(resultRows))),
-		RowCount:   len(resultRows),
+		Columns:     cols,
+		ColumnTypes: colTypes,
+		Rows:        resultRows,
+		Nulls:       nulls,
+		StatusText:  fmt.Sprintf("(%d row%s)", len(resultRows), pluralS(len(resultRows))),
+		RowCount:    len(resultRows),
+		ServerTime:  answered.Sub(start),
+		FetchTime:   time.Since(answered),
 	}, nil
 }
 
-func (e *Executor) executeExec(ctx context.Context, query string) (*format.QueryResult, error) {
-	result, err := e.db.ExecContext(ctx, query)
+func (e *Executor) executeExec(ctx context.Context, conn *sql.Conn, query string) (*format.QueryResult, error) {
+	start := time.Now()
+	result, err := conn.ExecContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -322,6 +548,8 @@ func (e *Executor) executeExec(ctx context.Context, query string) (*format.Query
 
 	return &format.QueryResult{
 		StatusText: statusText,
+		RowCount:   int(affected),
+		ServerTime: time.Since(start),
 	}, nil
 }
 
@@ -337,6 +565,22 @@ func formatValue(v interface{}) string {
 	}
 }
 
+// columnTypeNames returns the database type name of each result column.
+// Drivers that cannot report types yield empty names.
+func columnTypeNames(rows *sql.Rows, n int) []string {
+	names := make([]string, n)
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return names
+	}
+	for i, ct := range types {
+		if i < n {
+			names[i] = ct.DatabaseTypeName()
+		}
+	}
+	return names
+}
+
 func pluralS(n int) string {
 	if n == 1 {
 		return ""
@@ -346,19 +590,15 @@ func pluralS(n int) string {
 
 // Schema metadata queries
 
-// SearchPath returns the current search_path schemas.
+// SearchPath returns the current search_path schemas: the session's once
+// it has set one, otherwise the pool's, which start from the same
+// settings.
 func (e *Executor) SearchPath(ctx context.Context) ([]string, error) {
-	rows, err := e.db.QueryContext(ctx, "SELECT unnest(current_schemas(true))")
-	if err != nil {
-		return []string{"public"}, nil
-	}
-	defer rows.Close()
-	var schemas []string
-	for rows.Next() {
-		var s string
-		if err := rows.Scan(&s); err == nil {
-			schemas = append(schemas, s)
-		}
+	e.mu.Lock()
+	schemas := e.searchPath
+	e.mu.Unlock()
+	if schemas == nil {
+		schemas, _ = e.queryStrings(ctx, "SELECT unnest(current_schemas(true))")
 	}
 	if len(schemas) == 0 {
 		return []string{"public"}, nil
@@ -422,6 +662,47 @@ func (e *Executor) Columns(ctx context.Context, table string) ([]string, error)
 	return e.queryStrings(ctx, query, args...)
 }
 
+// Relations returns every user table, view and materialized view with its
+// columns, read from the catalog in a single query.
+func (e *Executor) Relations(ctx context.Context) ([]completion.Relation, error) {
+	query := `SELECT n.nspname, c.relname, c.relkind IN ('v', 'm'), COALESCE(a.attname, '')
+	FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+	WHERE c.relkind IN ('r', 'p', 'f', 'v', 'm')
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+	AND n.nspname NOT LIKE 'pg\_%'
+	ORDER BY n.nspname, c.relname, a.attnum`
+
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRelations(rows)
+}
+
+// scanRelations groups (schema, name, view, column) rows ordered by
+// relation into relations.
+func scanRelations(rows *sql.Rows) ([]completion.Relation, error) {
+	var rels []completion.Relation
+	for rows.Next() {
+		var schema, name, column string
+		var view bool
+		if err := rows.Scan(&schema, &name, &view, &column); err != nil {
+			return nil, err
+		}
+		if n := len(rels); n == 0 || rels[n-1].Schema != schema || rels[n-1].Name != name {
+			rels = append(rels, completion.Relation{Schema: schema, Name: name, View: view})
+		}
+		if column != "" {
+			rel := &rels[len(rels)-1]
+			rel.Columns = append(rel.Columns, column)
+		}
+	}
+	return rels, rows.Err()
+}
+
 // Schemas returns all schema names.
 func (e *Executor) Schemas(ctx context.Context) ([]string, error) {
 	query := `SELECT schema_name FROM information_schema.schemata ORDER BY schema_name`
@@ -441,31 +722,55 @@ func (e *Executor) Functions(ctx context.Context, schema string) ([]string, erro
 	return e.queryStrings(ctx, query)
 }
 
-// ForeignKey represents a foreign key relationship.
-type ForeignKey struct {
-	ParentSchema string
-	ParentTable  string
-	ParentColumn string
-	ChildSchema  string
-	ChildTable   string
-	ChildColumn  string
+// FunctionSignatures returns the argument list and result type of every
+// function, including the built-in ones used for parameter hints.
+func (e *Executor) FunctionSignatures(ctx context.Context) ([]completion.Function, error) {
+	query := `SELECT n.nspname, p.proname, pg_get_function_arguments(p.oid),
+		COALESCE(pg_get_function_result(p.oid), '')
+	FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE n.nspname <> 'information_schema'
+	ORDER BY n.nspname, p.proname, p.pronargs`
+
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fns []completion.Function
+	for rows.Next() {
+		var f completion.Function
+		if err := rows.Scan(&f.Schema, &f.Name, &f.Arguments, &f.Result); err != nil {
+			return nil, err
+		}
+		fns = append(fns, f)
+	}
+	return fns, rows.Err()
 }
 
+// ForeignKey represents a foreign key relationship. It is the completion
+// type so that the results can be handed straight to the completer.
+type ForeignKey = completion.ForeignKey
+
 // ForeignKeys returns all foreign key relationships.
 func (e *Executor) ForeignKeys(ctx context.Context) ([]ForeignKey, error) {
+	// conkey and confkey pair the columns of a composite key by position
 	query := `SELECT
 		cn.nspname AS child_schema, cc.relname AS child_table, ca.attname AS child_column,
-		pn.nspname AS parent_schema, pc.relname AS parent_table, pa.attname AS parent_column
+		pn.nspname AS parent_schema, pc.relname AS parent_table, pa.attname AS parent_column,
+		c.conname
 	FROM pg_constraint c
 	JOIN pg_class cc ON c.conrelid = cc.oid
 	JOIN pg_namespace cn ON cc.relnamespace = cn.oid
 	JOIN pg_class pc ON c.confrelid = pc.oid
 	JOIN pg_namespace pn ON pc.relnamespace = pn.oid
-	JOIN pg_attribute ca ON ca.attrelid = c.conrelid AND ca.attnum = ANY(c.conkey)
-	JOIN pg_attribute pa ON pa.attrelid = c.confrelid AND pa.attnum = ANY(c.confkey)
+	CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(child_attnum, parent_attnum, ord)
+	JOIN pg_attribute ca ON ca.attrelid = c.conrelid AND ca.attnum = k.child_attnum
+	JOIN pg_attribute pa ON pa.attrelid = c.confrelid AND pa.attnum = k.parent_attnum
 	WHERE c.contype = 'f'
 	AND cn.nspname NOT IN ('pg_catalog', 'information_schema')
-	ORDER BY cn.nspname, cc.relname`
+	ORDER BY cn.nspname, cc.relname, c.conname, k.ord`
 
 	rows, err := e.db.QueryContext(ctx, query)
 	if err != nil {
@@ -477,7 +782,7 @@ func (e *Executor) ForeignKeys(ctx context.Context) ([]ForeignKey, error) {
 	for rows.Next() {
 		var fk ForeignKey
 		if err := rows.Scan(&fk.ChildSchema, &fk.ChildTable, &fk.ChildColumn,
-			&fk.ParentSchema, &fk.ParentTable, &fk.ParentColumn); err != nil {
+			&fk.ParentSchema, &fk.ParentTable, &fk.ParentColumn, &fk.Constraint); err != nil {
 			return nil, err
 		}
 		fks = append(fks, fk)
@@ -485,6 +790,42 @@ func (e *Executor) ForeignKeys(ctx context.Context) ([]ForeignKey, error) {
 	return fks, rows.Err()
 }
 
+// UniqueKeys returns the columns of every primary key and unique index
+// that can serve as an ON CONFLICT target. Partial and expression indexes
+// are left out.
+func (e *Executor) UniqueKeys(ctx context.Context) ([]completion.UniqueKey, error) {
+	query := `SELECT n.nspname, c.relname, string_agg(a.attname, ',' ORDER BY k.ord)
+	FROM pg_index i
+	JOIN pg_class c ON c.oid = i.indrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+	JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+	WHERE i.indisunique AND i.indpred IS NULL AND i.indexprs IS NULL
+	AND k.ord <= i.indnkeyatts
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+	AND n.nspname NOT LIKE 'pg\_%'
+	GROUP BY n.nspname, c.relname, i.indexrelid, i.indisprimary
+	ORDER BY n.nspname, c.relname, i.indisprimary DESC`
+
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []completion.UniqueKey
+	for rows.Next() {
+		var key completion.UniqueKey
+		var cols string
+		if err := rows.Scan(&key.Schema, &key.Table, &cols); err != nil {
+			return nil, err
+		}
+		key.Columns = strings.Split(cols, ",")
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
 // Databases returns all database names.
 func (e *Executor) Databases(ctx context.Context) ([]string, error) {
 	query := `SELECT datname FROM pg_database WHERE datistemplate = false ORDER BY datname`
@@ -505,21 +846,15 @@ func (e *Executor) Views(ctx context.Context, schema string) ([]string, error) {
 
 // Datatypes returns common PostgreSQL data types.
 func (e *Executor) Datatypes(_ context.Context) []string {
-	return []string{
-		"bigint", "bigserial", "bit", "bit varying", "boolean", "box",
-		"bytea", "character", "character varying", "cidr", "circle",
-		"date", "double precision", "inet", "integer", "interval",
-		"json", "jsonb", "line", "lseg", "macaddr", "macaddr8",
-		"money", "numeric", "path", "pg_lsn", "pg_snapshot", "point",
-		"polygon", "real", "smallint", "smallserial", "serial",
-		"text", "time", "time with time zone", "timestamp",
-		"timestamp with time zone", "tsquery", "tsvector", "txid_snapshot",
-		"uuid", "xml",
-	}
+	return dialect.New(dialect.PostgreSQL, "").Datatypes()
 }
 
 func (e *Executor) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
-	rows, err := e.db.QueryContext(ctx, query, args...)
+	return scanStrings(e.db.QueryContext(ctx, query, args...))
+}
+
+// scanStrings reads the single-column rows of a query.
+func scanStrings(rows *sql.Rows, err error) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
@@ -1,10 +1,22 @@
 package pg
 
 import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -75,6 +87,85 @@ func TestConnectionConfig_DSN_WithOptions(t *testing.T) {
 	}
 }
 
+func TestConnectionConfig_ReadOnly(t *testing.T) {
+	cfg := ConnectionConfig{Host: "localhost", Port: 5432, Database: "db"}
+	if strings.Contains(cfg.DSN(), "read_only") || len(cfg.SessionStatements()) != 0 {
+		t.Errorf("sessions should be writable by default: %q, %v", cfg.DSN(), cfg.SessionStatements())
+	}
+
+	cfg.ReadOnly = true
+	if dsn := cfg.DSN(); !strings.Contains(dsn, "default_transaction_read_only=on") {
+		t.Errorf("DSN should make transactions read-only, got %q", dsn)
+	}
+	stmts := cfg.SessionStatements()
+	if len(stmts) != 1 || stmts[0] != "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY" {
+		t.Errorf("each session should be made read-only, got %v", stmts)
+	}
+
+	cfg.InitCommand = "SET search_path = app"
+	if stmts := cfg.SessionStatements(); len(stmts) != 2 || stmts[1] != "SET search_path = app" {
+		t.Errorf("each session should run the init command last, got %v", stmts)
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	e := &Executor{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}, true},
+		{&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}, true},
+		{fmt.Errorf("query: %w", &pgconn.PgError{Code: "55P03"}), true},
+		{&pgconn.PgError{Code: "57014", Message: "canceling statement due to user request"}, false},
+		{&pgconn.PgError{Code: "42P01", Message: "relation \"users\" does not exist"}, false},
+		{errors.New("timeout"), false},
+	}
+	for _, tt := range tests {
+		if got := e.IsTimeout(tt.err); got != tt.want {
+			t.Errorf("IsTimeout(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsConnectionLost(t *testing.T) {
+	e := &Executor{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true},
+		{&pgconn.PgError{Code: "57P01", Message: "terminating connection due to administrator command"}, true},
+		{&pgconn.PgError{Code: "08006", Message: "connection failure"}, true},
+		{&pgconn.PgError{Code: "42P01", Message: "relation \"users\" does not exist"}, false},
+		{errors.New("syntax error"), false},
+	}
+	for _, tt := range tests {
+		if got := e.IsConnectionLost(tt.err); got != tt.want {
+			t.Errorf("IsConnectionLost(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSetTimeouts(t *testing.T) {
+	e := &Executor{}
+	e.SetTimeouts(0, 0)
+	if e.timeoutGen != 0 {
+		t.Error("no timeouts should leave connections untouched")
+	}
+	e.SetTimeouts(30*time.Second, 0)
+	e.SetTimeouts(30*time.Second, 0)
+	if e.timeoutGen != 1 || milliseconds(e.statementTimeout) != "30000" || milliseconds(e.lockTimeout) != "0" {
+		t.Errorf("got %v, %v, %v", e.timeoutGen, e.statementTimeout, e.lockTimeout)
+	}
+	e.SetTimeouts(0, 0)
+	if e.timeoutGen != 2 {
+		t.Error("turning timeouts off should still reset them on each connection")
+	}
+}
+
 func TestParseDSN_URI(t *testing.T) {
 	tests := []struct {
 		dsn      string
@@ -387,3 +478,184 @@ func TestParseDSN_Defaults(t *testing.T) {
 		t.Errorf("default sslmode should be 'prefer', got %q", cfg.SSLMode)
 	}
 }
+
+// fakeServer speaks enough of the PostgreSQL protocol to run simple
+// queries, tracking transactions like a server: after an error in a
+// transaction it refuses everything but ROLLBACK. It returns the port and
+// the queries received.
+func fakeServer(t *testing.T) (int, func() []string, func() []int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	var queries []string
+	var conns []int // the connection each query came on
+	go func() {
+		for id := 1; ; id++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(id int) {
+				defer conn.Close()
+				b := pgproto3.NewBackend(conn, conn)
+				if _, err := b.ReceiveStartupMessage(); err != nil {
+					return
+				}
+				b.Send(&pgproto3.AuthenticationOk{})
+				b.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+				b.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+				b.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+				b.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+				b.Flush()
+				status := byte('I')
+				for {
+					msg, err := b.Receive()
+					if err != nil {
+						return
+					}
+					q, ok := msg.(*pgproto3.Query)
+					if !ok {
+						return
+					}
+					mu.Lock()
+					queries = append(queries, q.String)
+					conns = append(conns, id)
+					mu.Unlock()
+					upper := strings.ToUpper(q.String)
+					switch {
+					case status == 'E' && upper != "ROLLBACK":
+						b.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "25P02", Message: "current transaction is aborted"})
+					case strings.HasPrefix(upper, "--"):
+						b.Send(&pgproto3.EmptyQueryResponse{})
+					case upper == "BEGIN":
+						b.Send(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")})
+						status = 'T'
+					case upper == "ROLLBACK":
+						b.Send(&pgproto3.CommandComplete{CommandTag: []byte("ROLLBACK")})
+						status = 'I'
+					case strings.Contains(upper, "BOOM"):
+						b.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42703", Message: "column \"boom\" does not exist"})
+						if status == 'T' {
+							status = 'E'
+						}
+					default:
+						b.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 0")})
+					}
+					b.Send(&pgproto3.ReadyForQuery{TxStatus: status})
+					b.Flush()
+				}
+			}(id)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), queries...)
+		}, func() []int {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]int(nil), conns...)
+		}
+}
+
+func TestExecute_TimeoutsAfterFailedTransaction(t *testing.T) {
+	port, queries, _ := fakeServer(t)
+	e, err := NewExecutor(ConnectionConfig{
+		Host: "127.0.0.1", Port: port, User: "u", Database: "db", SSLMode: "disable",
+		Options: map[string]string{"default_query_exec_mode": "simple_protocol"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	e.DB().SetMaxOpenConns(1)
+	e.SetTimeouts(time.Minute, 0)
+
+	ctx := context.Background()
+	if _, err := e.Execute(ctx, "BEGIN"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Execute(ctx, "SELECT boom"); err == nil {
+		t.Fatal("expected the statement to fail")
+	}
+	e.SetTimeouts(2*time.Minute, 0) // \timeout inside the failed transaction
+	if _, err := e.Execute(ctx, "ROLLBACK"); err != nil {
+		t.Fatalf("ROLLBACK should reach the server: %v", err)
+	}
+	if _, err := e.Execute(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("the connection should be usable after ROLLBACK: %v", err)
+	}
+
+	var got []string
+	for _, q := range queries() {
+		if fields := strings.Fields(q); len(fields) > 2 {
+			got = append(got, fields[0]+" "+fields[1])
+		} else if !strings.HasPrefix(q, "--") {
+			got = append(got, q)
+		}
+	}
+	want := []string{"SELECT set_config('statement_timeout',", "BEGIN", "SELECT boom", "ROLLBACK", "SELECT set_config('statement_timeout',", "SELECT 1"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("timeouts should be set once per change outside transactions, got %q", queries())
+	}
+}
+
+func TestExecute_StatementsShareOneConnection(t *testing.T) {
+	port, queries, conns := fakeServer(t)
+	e, err := NewExecutor(ConnectionConfig{
+		Host: "127.0.0.1", Port: port, User: "u", Database: "db", SSLMode: "disable",
+		Options: map[string]string{"default_query_exec_mode": "simple_protocol"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// A completion refresh loads metadata while the user works
+	ctx := context.Background()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					e.Schemas(ctx)
+				}
+			}
+		}()
+	}
+	statements := []string{"BEGIN", "SET LOCAL work_mem = '1GB'", "SELECT 1", "ROLLBACK"}
+	for _, q := range statements {
+		if _, err := e.Execute(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	session := map[int]bool{}
+	got, ids := queries(), conns()
+	for i, q := range got {
+		if slices.Contains(statements, q) {
+			session[ids[i]] = true
+		}
+	}
+	if len(session) != 1 {
+		t.Errorf("user statements should share one connection, got %d", len(session))
+	}
+	for i, q := range got {
+		if strings.HasPrefix(q, "SELECT schema_name") && session[ids[i]] {
+			t.Fatalf("metadata queries should not use the session connection")
+		}
+	}
+}
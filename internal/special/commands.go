@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/tomblomfield/gocli/internal/completion"
+	"github.com/tomblomfield/gocli/internal/config"
 	"github.com/tomblomfield/gocli/internal/format"
 )
 
@@ -31,22 +34,87 @@ type Command struct {
 	CaseSensitive bool
 	Aliases       []string
 	Handler       CommandHandler
+	// Arg says what the argument names, for completion. Choices lists
+	// the accepted values when it is completion.ArgChoice.
+	Arg     completion.ArgKind
+	Choices []string
 }
 
 // CommandHandler is the function signature for special command handlers.
 type CommandHandler func(ctx context.Context, executor interface{}, arg string, verbose bool) ([]*format.QueryResult, error)
 
+// Hooks are application operations that special commands trigger but the
+// special package cannot perform itself. The application installs them;
+// a nil hook makes its commands fail with errNoHook.
+type Hooks struct {
+	// RefreshCompletions reloads the auto-completion metadata in the
+	// background, without using the connection later statements run on,
+	// so a refresh after \u cannot send them to another database.
+	RefreshCompletions func()
+	// Connect opens a new connection to database, or to the current
+	// database when it is empty, and makes it the executor for later
+	// commands. It returns the name of the database now connected.
+	Connect func(ctx context.Context, database string) (string, error)
+	// SetOutput sends later query results to target: a file name, or
+	// "|command" to pipe them. An empty target restores stdout.
+	SetOutput func(target string) error
+	// Tee also writes later query results to file, appending unless
+	// overwrite is set. An empty file stops it.
+	Tee func(file string, overwrite bool) error
+	// SetPrompt changes the prompt format.
+	SetPrompt func(format string)
+	// RunScript executes the statements and commands in file, printing
+	// their results as it goes.
+	RunScript func(ctx context.Context, file string) error
+	// RunQuery executes a query on the user's behalf, as a favorite does,
+	// applying the same checks and logging as typed statements. Without
+	// it such queries go straight to the executor.
+	RunQuery func(ctx context.Context, query string) (*format.QueryResult, error)
+}
+
+// TableFormats are the output formats accepted by \T and \pset format.
+var TableFormats = []string{"ascii", "unicode", "psql", "csv", "tsv", "json", "vertical"}
+
+// checkTableFormat reports an error unless name is one of TableFormats.
+func checkTableFormat(name string) error {
+	if !slices.Contains(TableFormats, name) {
+		return fmt.Errorf("unknown table format: %s (valid: %s)", name, strings.Join(TableFormats, ", "))
+	}
+	return nil
+}
+
+// errNoHook is returned by commands whose hook is not installed.
+var errNoHook = fmt.Errorf("command not available in this session")
+
 // Registry holds registered special commands.
 type Registry struct {
 	commands map[string]*Command
 	// Shared state
-	Expanded    bool
-	Timing      bool
+	Expanded bool
+	// ExpandedAuto switches to expanded output only for results wider
+	// than the terminal (\x auto). It is cleared when Expanded is set.
+	ExpandedAuto bool
+	Timing       bool
+	// Timeout and LockTimeout limit how long statements run and wait for
+	// locks. Zero means no limit.
+	Timeout     time.Duration
+	LockTimeout time.Duration
 	Pager       string
 	Editor      string
 	WatchSecs   int
 	TableFormat string
 	Favorites   map[string]string
+	// Delimiter ends statements in place of ";" after MySQL's delimiter
+	// command. Empty means ";".
+	Delimiter string
+	// Display holds the NULL, number and date settings changed via \pset.
+	Display format.Options
+	// LastResult is the most recent query result, pivoted by \crosstabview.
+	LastResult *format.QueryResult
+	// Hooks reach operations owned by the application.
+	Hooks Hooks
+
+	pendingOutput *QueryOutput
 }
 
 // NewRegistry creates a new command registry with common commands.
@@ -59,6 +127,7 @@ func NewRegistry() *Registry {
 		WatchSecs:   2,
 		TableFormat: "unicode",
 		Favorites:   make(map[string]string),
+		Display:     format.DefaultOptions(),
 	}
 	r.registerCommon()
 	return r
@@ -180,16 +249,13 @@ func (r *Registry) registerCommon() {
 	// \x - Expanded output
 	r.Register(&Command{
 		Name:        `\x`,
-		Syntax:      `\x`,
+		Syntax:      `\x [on|off|auto]`,
 		Description: "Toggle expanded output",
 		ArgType:     NoQuery,
-		Handler: func(_ context.Context, _ interface{}, _ string, _ bool) ([]*format.QueryResult, error) {
-			r.Expanded = !r.Expanded
-			state := "off"
-			if r.Expanded {
-				state = "on"
-			}
-			return []*format.QueryResult{{StatusText: fmt.Sprintf("Expanded display is %s.", state)}}, nil
+		Arg:         completion.ArgChoice,
+		Choices:     []string{"on", "off", "auto"},
+		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+			return r.setExpanded(strings.TrimSpace(arg))
 		},
 	})
 
@@ -209,6 +275,19 @@ func (r *Registry) registerCommon() {
 		},
 	})
 
+	// \timeout - Limit how long statements run
+	r.Register(&Command{
+		Name:        `\timeout`,
+		Syntax:      `\timeout [lock] [duration|off]`,
+		Description: "Show or set the statement (or lock wait) timeout",
+		ArgType:     ParsedQuery,
+		Arg:         completion.ArgChoice,
+		Choices:     []string{"off", "lock"},
+		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+			return r.setTimeout(strings.Fields(arg))
+		},
+	})
+
 	// \pager - Set pager
 	r.Register(&Command{
 		Name:        `\pager`,
@@ -252,6 +331,7 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\f [name]`,
 		Description: "List or execute favorite queries",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFavorite,
 		Handler:     r.favoritesHandler,
 	})
 
@@ -270,6 +350,7 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\fd name`,
 		Description: "Delete a favorite query",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFavorite,
 		Handler:     r.deleteFavoriteHandler,
 	})
 
@@ -298,7 +379,11 @@ func (r *Registry) registerCommon() {
 		ArgType:     NoQuery,
 		Aliases:     []string{`\refresh`, "rehash"},
 		Handler: func(_ context.Context, _ interface{}, _ string, _ bool) ([]*format.QueryResult, error) {
-			return []*format.QueryResult{{StatusText: "Auto-completions refreshed."}}, nil
+			if r.Hooks.RefreshCompletions == nil {
+				return nil, errNoHook
+			}
+			r.Hooks.RefreshCompletions()
+			return []*format.QueryResult{{StatusText: "Auto-completion refresh started in the background."}}, nil
 		},
 	})
 
@@ -308,16 +393,14 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\T [format]`,
 		Description: "Change the table format used to output results",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgChoice,
+		Choices:     TableFormats,
 		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
 			if arg == "" {
 				return []*format.QueryResult{{StatusText: fmt.Sprintf("Current table format: %s", r.TableFormat)}}, nil
 			}
-			validFormats := map[string]bool{
-				"ascii": true, "unicode": true, "psql": true,
-				"csv": true, "tsv": true, "json": true, "vertical": true,
-			}
-			if !validFormats[arg] {
-				return nil, fmt.Errorf("unknown table format: %s (valid: ascii, unicode, csv, tsv, json, vertical)", arg)
+			if err := checkTableFormat(arg); err != nil {
+				return nil, err
 			}
 			r.TableFormat = arg
 			return []*format.QueryResult{{StatusText: fmt.Sprintf("Changed table format to %s.", arg)}}, nil
@@ -363,39 +446,34 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\pset [key] [value]`,
 		Description: "Set table output option",
 		ArgType:     RawQuery,
-		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
-			parts := strings.Fields(arg)
-			if len(parts) == 0 {
-				return []*format.QueryResult{{StatusText: fmt.Sprintf("format = %s\nexpanded = %v\ntiming = %v", r.TableFormat, r.Expanded, r.Timing)}}, nil
-			}
-			key := strings.ToLower(parts[0])
-			val := ""
-			if len(parts) > 1 {
-				val = parts[1]
-			}
-			switch key {
-			case "format":
-				if val != "" {
-					r.TableFormat = val
-				}
-				return []*format.QueryResult{{StatusText: fmt.Sprintf("Output format is %s.", r.TableFormat)}}, nil
-			case "expanded":
-				if val == "on" {
-					r.Expanded = true
-				} else if val == "off" {
-					r.Expanded = false
-				} else {
-					r.Expanded = !r.Expanded
-				}
-				state := "off"
-				if r.Expanded {
-					state = "on"
-				}
-				return []*format.QueryResult{{StatusText: fmt.Sprintf("Expanded display is %s.", state)}}, nil
-			default:
-				return nil, fmt.Errorf("unknown pset option: %s", key)
-			}
-		},
+		Arg:         completion.ArgChoice,
+		Choices:     psetChoices(),
+		Handler:     r.psetHandler,
+	})
+
+	// \g - Run the query buffer, optionally to a file or command
+	r.Register(&Command{
+		Name:        `\g`,
+		Syntax:      `\g [(option=value ...)] [file | |command]`,
+		Description: "Execute query, sending results to a file or pipe",
+		ArgType:     RawQuery,
+		Handler:     r.queryOutputHandler(false),
+	})
+	r.Register(&Command{
+		Name:        `\gx`,
+		Syntax:      `\gx [(option=value ...)] [file | |command]`,
+		Description: "As \\g, but force expanded output",
+		ArgType:     RawQuery,
+		Handler:     r.queryOutputHandler(true),
+	})
+
+	// \crosstabview - Pivot the last result
+	r.Register(&Command{
+		Name:        `\crosstabview`,
+		Syntax:      `\crosstabview [colV [colH [colD [sortcolH]]]]`,
+		Description: "Show the last query result in a crosstab grid",
+		ArgType:     RawQuery,
+		Handler:     r.crosstabHandler,
 	})
 
 	// \n - Named queries (pgcli-compatible aliases)
@@ -404,6 +482,7 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\n[+] [name] [param1 param2 ...]`,
 		Description: "List or execute named queries",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFavorite,
 		Handler:     r.favoritesHandler,
 	})
 	r.Register(&Command{
@@ -418,6 +497,7 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\nd name`,
 		Description: "Delete a named query",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFavorite,
 		Handler:     r.deleteFavoriteHandler,
 	})
 	r.Register(&Command{
@@ -425,6 +505,7 @@ func (r *Registry) registerCommon() {
 		Syntax:      `\np name`,
 		Description: "Print a named query",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFavorite,
 		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
 			name := strings.TrimSpace(arg)
 			if name == "" {
@@ -439,6 +520,203 @@ func (r *Registry) registerCommon() {
 	})
 }
 
+// setExpanded implements \x and \pset expanded. With no value it toggles
+// between on and off, as psql does.
+func (r *Registry) setExpanded(val string) ([]*format.QueryResult, error) {
+	switch strings.ToLower(val) {
+	case "on", "true", "1":
+		r.Expanded, r.ExpandedAuto = true, false
+	case "off", "false", "0":
+		r.Expanded, r.ExpandedAuto = false, false
+	case "auto":
+		r.Expanded, r.ExpandedAuto = false, true
+	case "":
+		r.Expanded, r.ExpandedAuto = !r.Expanded && !r.ExpandedAuto, false
+	default:
+		return nil, fmt.Errorf("unrecognized value \"%s\" for expanded: on, off or auto expected", val)
+	}
+	state := r.expandedState()
+	if state == "auto" {
+		state = "used automatically"
+	}
+	return []*format.QueryResult{{StatusText: fmt.Sprintf("Expanded display is %s.", state)}}, nil
+}
+
+func (r *Registry) expandedState() string {
+	switch {
+	case r.ExpandedAuto:
+		return "auto"
+	case r.Expanded:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+// psetChoices lists the \pset options, with the values of those that
+// take one of a fixed set, for completion.
+func psetChoices() []string {
+	choices := []string{
+		"null", "null_csv", "null_json", "float_precision", "float_format",
+		"thousands_sep", "numericlocale", "decimal_mark", "date_format",
+		"timestamp_format", "timezone",
+	}
+	for _, f := range TableFormats {
+		choices = append(choices, "format "+f)
+	}
+	for _, v := range []string{"on", "off", "auto"} {
+		choices = append(choices, "expanded "+v)
+	}
+	for _, v := range []string{"on", "off"} {
+		choices = append(choices, "tuples_only "+v, "header "+v)
+	}
+	return choices
+}
+
+func (r *Registry) psetHandler(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+	parts := strings.Fields(arg)
+	if len(parts) == 0 {
+		d := r.Display
+		lines := []string{
+			fmt.Sprintf("format = %s", r.TableFormat),
+			fmt.Sprintf("expanded = %s", r.expandedState()),
+			fmt.Sprintf("timing = %v", r.Timing),
+			fmt.Sprintf("timeout = %s", timeoutState(r.Timeout)),
+			fmt.Sprintf("tuples_only = %v", r.Display.TuplesOnly),
+			fmt.Sprintf("null = '%s'", d.NullValue),
+			fmt.Sprintf("null_csv = '%s'", d.NullValues[format.CSVFormat]),
+			fmt.Sprintf("null_json = '%s'", d.NullValues[format.JSONFormat]),
+			fmt.Sprintf("float_format = '%s'", d.FloatFmt),
+			fmt.Sprintf("thousands_sep = '%s'", d.ThousandsSep),
+			fmt.Sprintf("decimal_mark = '%s'", d.DecimalMark),
+			fmt.Sprintf("date_format = '%s'", d.DateFormat),
+			fmt.Sprintf("timestamp_format = '%s'", d.TimestampFormat),
+			fmt.Sprintf("timezone = '%s'", d.TimeZone),
+		}
+		return []*format.QueryResult{{StatusText: strings.Join(lines, "\n")}}, nil
+	}
+	key := strings.ToLower(parts[0])
+	// The value is the rest of the line so layouts such as "Jan 2 2006" survive;
+	// psql-style single quotes allow empty values and surrounding spaces.
+	val := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(arg), parts[0]))
+	if len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'' {
+		val = val[1 : len(val)-1]
+	}
+	setting := func(name, value string) ([]*format.QueryResult, error) {
+		return []*format.QueryResult{{StatusText: fmt.Sprintf("%s is \"%s\".", name, value)}}, nil
+	}
+
+	switch key {
+	case "format":
+		if val != "" {
+			if err := checkTableFormat(val); err != nil {
+				return nil, err
+			}
+			r.TableFormat = val
+		}
+		return []*format.QueryResult{{StatusText: fmt.Sprintf("Output format is %s.", r.TableFormat)}}, nil
+	case "expanded":
+		return r.setExpanded(val)
+	case "tuples_only", "header":
+		// header is the inverse of tuples_only
+		invert := key == "header"
+		on, err := parsePsetBool(val, r.Display.TuplesOnly != invert)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+		r.Display.TuplesOnly = on != invert
+		state := "off"
+		if r.Display.TuplesOnly {
+			state = "on"
+		}
+		return []*format.QueryResult{{StatusText: fmt.Sprintf("Tuples only is %s.", state)}}, nil
+	case "null":
+		r.Display.NullValue = val
+		return setting("Null display", val)
+	case "null_csv", "null_json":
+		f := format.CSVFormat
+		if key == "null_json" {
+			f = format.JSONFormat
+		}
+		nulls := make(map[format.OutputFormat]string, len(r.Display.NullValues)+1)
+		for k, v := range r.Display.NullValues {
+			nulls[k] = v
+		}
+		nulls[f] = val
+		if f == format.CSVFormat {
+			nulls[format.TSVFormat] = val
+		}
+		r.Display.NullValues = nulls
+		return setting(fmt.Sprintf("Null display for %s", f), val)
+	case "float_precision":
+		if val == "" || val == "-1" {
+			r.Display.FloatFmt = ""
+			return setting("Float precision", "default")
+		}
+		var n int
+		if _, err := fmt.Sscanf(val, "%d", &n); err != nil || n < 0 {
+			return nil, fmt.Errorf("float_precision must be a non-negative integer")
+		}
+		r.Display.FloatFmt = fmt.Sprintf("%%.%df", n)
+		return setting("Float precision", val)
+	case "float_format":
+		if val != "" && strings.Contains(fmt.Sprintf(val, 1.5), "%!") {
+			return nil, fmt.Errorf("float_format needs exactly one float verb, such as %%.2f")
+		}
+		r.Display.FloatFmt = val
+		return setting("Float format", val)
+	case "thousands_sep":
+		r.Display.ThousandsSep = val
+		return setting("Thousands separator", val)
+	case "numericlocale":
+		// psql's on/off switch; without a locale to ask, on groups
+		// thousands with commas
+		on, err := parsePsetBool(val, r.Display.ThousandsSep != "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+		r.Display.ThousandsSep = ""
+		state := "off"
+		if on {
+			r.Display.ThousandsSep = ","
+			state = "on"
+		}
+		return []*format.QueryResult{{StatusText: fmt.Sprintf("Locale-adjusted numeric output is %s.", state)}}, nil
+	case "decimal_mark":
+		r.Display.DecimalMark = val
+		return setting("Decimal mark", val)
+	case "date_format":
+		r.Display.DateFormat = val
+		return setting("Date format", val)
+	case "timestamp_format":
+		r.Display.TimestampFormat = val
+		return setting("Timestamp format", val)
+	case "timezone":
+		if val != "" {
+			if _, err := time.LoadLocation(val); err != nil {
+				return nil, fmt.Errorf("unknown time zone: %s", val)
+			}
+		}
+		r.Display.TimeZone = val
+		return setting("Time zone", val)
+	default:
+		return nil, fmt.Errorf("unknown pset option: %s", key)
+	}
+}
+
+// parsePsetBool reads an on/off \pset value; an empty value toggles current.
+func parsePsetBool(val string, current bool) (bool, error) {
+	switch strings.ToLower(val) {
+	case "":
+		return !current, nil
+	case "on", "true", "1":
+		return true, nil
+	case "off", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("unrecognized value \"%s\": on or off expected", val)
+}
+
 func (r *Registry) helpHandler(_ context.Context, _ interface{}, _ string, _ bool) ([]*format.QueryResult, error) {
 	var rows [][]string
 	for _, cmd := range r.Commands() {
@@ -498,7 +776,7 @@ func (r *Registry) shellHandler(_ context.Context, _ interface{}, arg string, _
 	return nil, nil
 }
 
-func (r *Registry) favoritesHandler(_ context.Context, executor interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+func (r *Registry) favoritesHandler(ctx context.Context, executor interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
 	if arg == "" {
 		// List all favorites
 		var rows [][]string
@@ -530,6 +808,13 @@ func (r *Registry) favoritesHandler(_ context.Context, executor interface{}, arg
 	}
 
 	// Actually execute the query
+	if r.Hooks.RunQuery != nil {
+		result, err := r.Hooks.RunQuery(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return []*format.QueryResult{result}, nil
+	}
 	if executor == nil {
 		return []*format.QueryResult{{StatusText: fmt.Sprintf("Query: %s", query)}}, nil
 	}
@@ -571,6 +856,35 @@ func (r *Registry) deleteFavoriteHandler(_ context.Context, _ interface{}, arg s
 // ErrQuit is returned when the user wants to quit.
 var ErrQuit = fmt.Errorf("quit")
 
+// setTimeout shows or sets the statement timeout, or the lock timeout
+// when the first argument is "lock".
+func (r *Registry) setTimeout(args []string) ([]*format.QueryResult, error) {
+	name, timeout := "Statement timeout", &r.Timeout
+	if len(args) > 0 && strings.EqualFold(args[0], "lock") {
+		name, timeout = "Lock timeout", &r.LockTimeout
+		args = args[1:]
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		d, err := config.ParseTimeout(args[0])
+		if err != nil {
+			return nil, err
+		}
+		*timeout = d
+	default:
+		return nil, fmt.Errorf("usage: \\timeout [lock] [duration|off]")
+	}
+	return []*format.QueryResult{{StatusText: fmt.Sprintf("%s is %s.", name, timeoutState(*timeout))}}, nil
+}
+
+func timeoutState(d time.Duration) string {
+	if d == 0 {
+		return "off"
+	}
+	return d.String()
+}
+
 // FormatTiming returns a human-readable timing string.
 func FormatTiming(d time.Duration) string {
 	if d < time.Second {
@@ -2,9 +2,12 @@ package special
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/tomblomfield/gocli/internal/completion"
 	"github.com/tomblomfield/gocli/internal/format"
 )
 
@@ -123,6 +126,29 @@ func TestExecute_TimingToggle(t *testing.T) {
 	}
 }
 
+func TestExecute_Timeout(t *testing.T) {
+	r := NewRegistry()
+	ctx := context.Background()
+
+	results, err := r.Execute(ctx, nil, `\timeout`)
+	if err != nil || results[0].StatusText != "Statement timeout is off." {
+		t.Fatalf("got %v, %v", results, err)
+	}
+	results, err = r.Execute(ctx, nil, `\timeout 30s`)
+	if err != nil || r.Timeout != 30*time.Second || results[0].StatusText != "Statement timeout is 30s." {
+		t.Errorf("\\timeout 30s: got %v, %v, %v", r.Timeout, results, err)
+	}
+	if _, err := r.Execute(ctx, nil, `\timeout lock 5`); err != nil || r.LockTimeout != 5*time.Second {
+		t.Errorf("\\timeout lock 5: got %v, %v", r.LockTimeout, err)
+	}
+	if _, err := r.Execute(ctx, nil, `\timeout off`); err != nil || r.Timeout != 0 || r.LockTimeout != 5*time.Second {
+		t.Errorf("\\timeout off should only clear the statement timeout, got %v, %v, %v", r.Timeout, r.LockTimeout, err)
+	}
+	if _, err := r.Execute(ctx, nil, `\timeout soon`); err == nil {
+		t.Error("an invalid duration should error")
+	}
+}
+
 func TestExecute_Pager(t *testing.T) {
 	r := NewRegistry()
 
@@ -265,19 +291,155 @@ func TestExecute_Watch(t *testing.T) {
 func TestExecute_Refresh(t *testing.T) {
 	r := NewRegistry()
 
+	if _, err := r.Execute(context.Background(), nil, `\#`); err == nil {
+		t.Error("\\# should fail without a refresh hook")
+	}
+
+	refreshes := 0
+	r.Hooks.RefreshCompletions = func() { refreshes++ }
 	results, err := r.Execute(context.Background(), nil, `\#`)
 	if err != nil {
 		t.Fatalf("\\# should not error: %v", err)
 	}
-	if len(results) == 0 || !strings.Contains(results[0].StatusText, "refreshed") {
+	if len(results) == 0 || !strings.Contains(results[0].StatusText, "refresh") {
 		t.Error("should confirm refresh")
 	}
 
 	// Test alias
-	results, err = r.Execute(context.Background(), nil, "rehash")
-	if err != nil {
+	if _, err := r.Execute(context.Background(), nil, "rehash"); err != nil {
 		t.Fatalf("rehash should not error: %v", err)
 	}
+	if refreshes != 2 {
+		t.Errorf("expected 2 refreshes, got %d", refreshes)
+	}
+}
+
+func TestExecute_OutputHook(t *testing.T) {
+	r := NewRegistry()
+	RegisterPG(r)
+
+	var target string
+	r.Hooks.SetOutput = func(t string) error {
+		target = t
+		return nil
+	}
+	if _, err := r.Execute(context.Background(), nil, `\o |grep foo`); err != nil {
+		t.Fatalf("\\o should not error: %v", err)
+	}
+	if target != "|grep foo" {
+		t.Errorf("output target = %q", target)
+	}
+	results, err := r.Execute(context.Background(), nil, `\o`)
+	if err != nil || target != "" || !strings.Contains(results[0].StatusText, "stdout") {
+		t.Errorf("\\o without argument should reset output, got %q, %v", target, err)
+	}
+
+	r.Hooks.SetOutput = func(string) error { return fmt.Errorf("permission denied") }
+	if _, err := r.Execute(context.Background(), nil, `\o /root/out`); err == nil {
+		t.Error("hook errors should be reported")
+	}
+}
+
+func TestExecute_ConnectHook(t *testing.T) {
+	r := NewRegistry()
+	RegisterPG(r)
+
+	if _, err := r.Execute(context.Background(), nil, `\c other`); err == nil {
+		t.Error("\\c should fail without a connect hook")
+	}
+
+	r.Hooks.Connect = func(_ context.Context, db string) (string, error) {
+		if db == "" {
+			db = "current"
+		}
+		return db, nil
+	}
+	results, err := r.Execute(context.Background(), nil, `\c other`)
+	if err != nil {
+		t.Fatalf("\\c should not error: %v", err)
+	}
+	if results[0].StatusText != `You are now connected to database "other".` {
+		t.Errorf("unexpected status: %q", results[0].StatusText)
+	}
+	results, _ = r.Execute(context.Background(), nil, `\connect`)
+	if !strings.Contains(results[0].StatusText, "current") {
+		t.Errorf("\\connect without database should reconnect, got %q", results[0].StatusText)
+	}
+}
+
+func TestExecute_MySQLHooks(t *testing.T) {
+	r := NewRegistry()
+	RegisterMySQL(r)
+
+	var teeFile string
+	var overwrite bool
+	r.Hooks.Tee = func(file string, o bool) error {
+		teeFile, overwrite = file, o
+		return nil
+	}
+	if _, err := r.Execute(context.Background(), nil, "tee -o out.txt"); err != nil {
+		t.Fatalf("tee should not error: %v", err)
+	}
+	if teeFile != "out.txt" || !overwrite {
+		t.Errorf("tee -o: file %q overwrite %v", teeFile, overwrite)
+	}
+	r.Execute(context.Background(), nil, "notee")
+	if teeFile != "" {
+		t.Errorf("notee should stop tee, got %q", teeFile)
+	}
+
+	var prompt string
+	r.Hooks.SetPrompt = func(p string) { prompt = p }
+	if _, err := r.Execute(context.Background(), nil, `\R \u@\h> `); err != nil {
+		t.Fatalf("\\R should not error: %v", err)
+	}
+	if prompt != `\u@\h>` {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	r.Hooks.Connect = func(_ context.Context, db string) (string, error) { return db, nil }
+	results, err := r.Execute(context.Background(), nil, `\r shop`)
+	if err != nil || results[0].StatusText != "Reconnected to: shop" {
+		t.Errorf("\\r should reconnect, got %v, %v", results, err)
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\. setup.sql`); err == nil {
+		t.Error("source without a hook should error")
+	}
+	var script string
+	r.Hooks.RunScript = func(_ context.Context, file string) error {
+		script = file
+		return nil
+	}
+	if _, err := r.Execute(context.Background(), nil, "source setup.sql"); err != nil || script != "setup.sql" {
+		t.Errorf("source should run the script, got %q, %v", script, err)
+	}
+}
+
+func TestExecute_Delimiter(t *testing.T) {
+	r := NewRegistry()
+	RegisterMySQL(r)
+
+	results, err := r.Execute(context.Background(), nil, "delimiter //")
+	if err != nil {
+		t.Fatalf("delimiter should not error: %v", err)
+	}
+	if r.Delimiter != "//" || results[0].StatusText != "Delimiter set to: //" {
+		t.Errorf("delimiter = %q, status %q", r.Delimiter, results[0].StatusText)
+	}
+	if _, err := r.Execute(context.Background(), nil, `delimiter \\`); err == nil {
+		t.Error("a backslash delimiter should be rejected")
+	}
+	if _, err := r.Execute(context.Background(), nil, "delimiter"); err == nil {
+		t.Error("delimiter without an argument should error")
+	}
+	if r.Delimiter != "//" {
+		t.Errorf("failed commands should keep the delimiter, got %q", r.Delimiter)
+	}
+	r.Execute(context.Background(), nil, "delimiter ;")
+	if r.Delimiter != ";" {
+		t.Errorf("delimiter ; should restore the default, got %q", r.Delimiter)
+	}
 }
 
 func TestExecute_Shell(t *testing.T) {
@@ -305,9 +467,9 @@ func TestExecute_UnknownCommand(t *testing.T) {
 
 func TestParseSpecialCommand(t *testing.T) {
 	tests := []struct {
-		input       string
-		cmd, arg    string
-		verbose     bool
+		input    string
+		cmd, arg string
+		verbose  bool
 	}{
 		{`\dt`, `\dt`, "", false},
 		{`\dt+`, `\dt`, "", true},
@@ -477,6 +639,12 @@ func TestExecute_Pset(t *testing.T) {
 	if r.TableFormat != "csv" {
 		t.Errorf("format should be 'csv', got %q", r.TableFormat)
 	}
+	if _, err := r.Execute(context.Background(), nil, `\pset format xml`); err == nil {
+		t.Error("\\pset format should refuse unknown formats")
+	}
+	if r.TableFormat != "csv" {
+		t.Errorf("an unknown format should leave the format alone, got %q", r.TableFormat)
+	}
 
 	// Toggle expanded
 	r.Expanded = false
@@ -495,6 +663,33 @@ func TestExecute_Pset(t *testing.T) {
 	}
 }
 
+func TestExecute_PsetNumbers(t *testing.T) {
+	r := NewRegistry()
+	ctx := context.Background()
+
+	if _, err := r.Execute(ctx, nil, `\pset numericlocale on`); err != nil || r.Display.ThousandsSep != "," {
+		t.Errorf("numericlocale on should group thousands, got %q, %v", r.Display.ThousandsSep, err)
+	}
+	if _, err := r.Execute(ctx, nil, `\pset numericlocale off`); err != nil || r.Display.ThousandsSep != "" {
+		t.Errorf("numericlocale off should stop grouping, got %q, %v", r.Display.ThousandsSep, err)
+	}
+	if _, err := r.Execute(ctx, nil, `\pset numericlocale maybe`); err == nil {
+		t.Error("numericlocale should only take on or off")
+	}
+
+	if _, err := r.Execute(ctx, nil, `\pset float_format %.3f`); err != nil || r.Display.FloatFmt != "%.3f" {
+		t.Errorf("got %q, %v", r.Display.FloatFmt, err)
+	}
+	for _, bad := range []string{"%d", "%s %f", "plain"} {
+		if _, err := r.Execute(ctx, nil, `\pset float_format `+bad); err == nil {
+			t.Errorf("float_format %q should be rejected", bad)
+		}
+	}
+	if r.Display.FloatFmt != "%.3f" {
+		t.Errorf("a rejected float_format should keep the old one, got %q", r.Display.FloatFmt)
+	}
+}
+
 func TestExecute_NamedQueries(t *testing.T) {
 	r := NewRegistry()
 
@@ -568,3 +763,146 @@ func TestRegisterPG_Commands(t *testing.T) {
 		t.Error("describe should be an alias for \\d")
 	}
 }
+
+func TestCommandArgKinds(t *testing.T) {
+	pgReg := NewRegistry()
+	RegisterPG(pgReg)
+	myReg := NewRegistry()
+	RegisterMySQL(myReg)
+
+	tests := []struct {
+		r    *Registry
+		name string
+		want completion.ArgKind
+	}{
+		{pgReg, `\d`, completion.ArgRelation},
+		{pgReg, `\dt`, completion.ArgRelation},
+		{pgReg, `\dv`, completion.ArgRelation},
+		{pgReg, `\sf`, completion.ArgFunction},
+		{pgReg, `\ef`, completion.ArgFunction},
+		{pgReg, `\c`, completion.ArgDatabase},
+		{pgReg, `\i`, completion.ArgFile},
+		{pgReg, `\o`, completion.ArgFile},
+		{pgReg, `\f`, completion.ArgFavorite},
+		{pgReg, `\fd`, completion.ArgFavorite},
+		{pgReg, `\np`, completion.ArgFavorite},
+		{pgReg, `\T`, completion.ArgChoice},
+		{pgReg, `\pset`, completion.ArgChoice},
+		{pgReg, `\du`, completion.ArgNone},
+		{myReg, `\u`, completion.ArgDatabase},
+		{myReg, "use", completion.ArgDatabase},
+		{myReg, `\.`, completion.ArgFile},
+		{myReg, "source", completion.ArgFile},
+	}
+	for _, tt := range tests {
+		cmd, ok := tt.r.commands[tt.name]
+		if !ok {
+			t.Errorf("%s is not registered", tt.name)
+			continue
+		}
+		if cmd.Arg != tt.want {
+			t.Errorf("%s argument kind = %v, want %v", tt.name, cmd.Arg, tt.want)
+		}
+	}
+
+	if got := pgReg.commands[`\T`].Choices; len(got) != len(TableFormats) {
+		t.Errorf("\\T should offer the table formats, got %v", got)
+	}
+	choices := strings.Join(pgReg.commands[`\pset`].Choices, ",")
+	if !strings.Contains(choices, "format unicode") || !strings.Contains(choices, "null_json") {
+		t.Errorf("\\pset choices missing options: %v", choices)
+	}
+}
+
+func TestExecute_PsetDisplay(t *testing.T) {
+	r := NewRegistry()
+
+	if _, err := r.Execute(context.Background(), nil, `\pset null <null>`); err != nil {
+		t.Fatalf("\\pset null should not error: %v", err)
+	}
+	if r.Display.NullValue != "<null>" {
+		t.Errorf("null display should be '<null>', got %q", r.Display.NullValue)
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\pset null_csv ''`); err != nil {
+		t.Fatalf("\\pset null_csv should not error: %v", err)
+	}
+	if r.Display.NullValues[format.CSVFormat] != "" || r.Display.NullValues[format.TSVFormat] != "" {
+		t.Error("null_csv should set CSV and TSV null display")
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\pset float_precision 3`); err != nil {
+		t.Fatalf("\\pset float_precision should not error: %v", err)
+	}
+	if r.Display.FloatFmt != "%.3f" {
+		t.Errorf("float format should be '%%.3f', got %q", r.Display.FloatFmt)
+	}
+	if _, err := r.Execute(context.Background(), nil, `\pset float_precision abc`); err == nil {
+		t.Error("non-numeric float_precision should error")
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\pset date_format Jan 2 2006`); err != nil {
+		t.Fatalf("\\pset date_format should not error: %v", err)
+	}
+	if r.Display.DateFormat != "Jan 2 2006" {
+		t.Errorf("date format should keep spaces, got %q", r.Display.DateFormat)
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\pset timezone Not/AZone`); err == nil {
+		t.Error("unknown time zone should error")
+	}
+}
+
+func TestExecute_ExpandedAuto(t *testing.T) {
+	r := NewRegistry()
+
+	results, err := r.Execute(context.Background(), nil, `\x auto`)
+	if err != nil {
+		t.Fatalf("\\x auto should not error: %v", err)
+	}
+	if r.Expanded || !r.ExpandedAuto {
+		t.Error("\\x auto should enable automatic expansion only")
+	}
+	if !strings.Contains(results[0].StatusText, "automatically") {
+		t.Errorf("unexpected status: %q", results[0].StatusText)
+	}
+
+	// Toggling from auto turns expanded output off
+	if _, err := r.Execute(context.Background(), nil, `\x`); err != nil {
+		t.Fatalf("\\x should not error: %v", err)
+	}
+	if r.Expanded || r.ExpandedAuto {
+		t.Error("\\x after auto should turn expanded output off")
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\pset expanded on`); err != nil {
+		t.Fatalf("\\pset expanded on should not error: %v", err)
+	}
+	if !r.Expanded || r.ExpandedAuto {
+		t.Error("\\pset expanded on should clear auto")
+	}
+
+	if _, err := r.Execute(context.Background(), nil, `\x sideways`); err == nil {
+		t.Error("\\x with an invalid value should error")
+	}
+}
+
+func TestExecute_PsetTuplesOnly(t *testing.T) {
+	r := NewRegistry()
+
+	if _, err := r.Execute(context.Background(), nil, `\pset tuples_only on`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Display.TuplesOnly {
+		t.Error("tuples_only should be on")
+	}
+	if _, err := r.Execute(context.Background(), nil, `\pset header`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Display.TuplesOnly {
+		t.Error("toggling header should turn tuples_only off")
+	}
+	if _, err := r.Execute(context.Background(), nil, `\pset header maybe`); err == nil {
+		t.Error("invalid boolean should error")
+	}
+}
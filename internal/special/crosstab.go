@@ -0,0 +1,209 @@
+package special
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+// maxCrosstabColumns matches the PostgreSQL column limit that psql enforces.
+const maxCrosstabColumns = 1600
+
+func (r *Registry) crosstabHandler(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+	if r.LastResult == nil || len(r.LastResult.Columns) == 0 {
+		return nil, fmt.Errorf(`\crosstabview: no query result to display`)
+	}
+	pivot, err := Crosstab(r.LastResult, strings.Fields(arg))
+	if err != nil {
+		return nil, fmt.Errorf(`\crosstabview: %s`, err)
+	}
+	return []*format.QueryResult{pivot}, nil
+}
+
+// Crosstab pivots result the way psql's \crosstabview does. args holds up
+// to four column references (name or 1-based number): the column whose
+// values label the rows (colV), the one whose values become the header
+// (colH), the one that fills the cells (colD) and an optional integer
+// column that orders the header (sortcolH).
+//
+// colV and colH default to the first two columns. colD may only be left
+// out when the result has exactly three columns. Row and header values
+// appear in order of first occurrence, and two rows with the same
+// (colV, colH) pair are an error.
+func Crosstab(result *format.QueryResult, args []string) (*format.QueryResult, error) {
+	if len(args) > 4 {
+		return nil, fmt.Errorf("too many arguments")
+	}
+	if len(result.Columns) < 3 {
+		return nil, fmt.Errorf("query must return at least three columns")
+	}
+
+	colV, colH, colD, colSort := 0, 1, -1, -1
+	var err error
+	if len(args) > 0 {
+		if colV, err = crosstabColumn(result, args[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) > 1 {
+		if colH, err = crosstabColumn(result, args[1]); err != nil {
+			return nil, err
+		}
+	}
+	if colV == colH {
+		return nil, fmt.Errorf("vertical and horizontal headers must be different columns")
+	}
+	if len(args) > 2 {
+		if colD, err = crosstabColumn(result, args[2]); err != nil {
+			return nil, err
+		}
+	} else {
+		if len(result.Columns) != 3 {
+			return nil, fmt.Errorf("data column must be specified when query returns more than three columns")
+		}
+		colD = 3 - colV - colH
+	}
+	if len(args) > 3 {
+		if colSort, err = crosstabColumn(result, args[3]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Collect distinct header and row values in order of first appearance
+	var headers, labels []string
+	headerIdx := make(map[string]int)
+	labelIdx := make(map[string]int)
+	sortKeys := make(map[string]int)
+	for i, row := range result.Rows {
+		h := crosstabKey(result, i, colH)
+		if _, ok := headerIdx[h]; !ok {
+			headerIdx[h] = len(headers)
+			headers = append(headers, h)
+			if colSort >= 0 && !result.IsNull(i, colSort) {
+				n, err := strconv.Atoi(strings.TrimSpace(cellAt(row, colSort)))
+				if err != nil {
+					return nil, fmt.Errorf("invalid integer value \"%s\" in sort column", cellAt(row, colSort))
+				}
+				sortKeys[h] = n
+			}
+		}
+		v := crosstabKey(result, i, colV)
+		if _, ok := labelIdx[v]; !ok {
+			labelIdx[v] = len(labels)
+			labels = append(labels, v)
+		}
+	}
+	if len(headers)+1 > maxCrosstabColumns {
+		return nil, fmt.Errorf("maximum number of columns (%d) exceeded", maxCrosstabColumns)
+	}
+
+	if colSort >= 0 {
+		sort.SliceStable(headers, func(i, j int) bool {
+			return sortKeys[headers[i]] < sortKeys[headers[j]]
+		})
+		for i, h := range headers {
+			headerIdx[h] = i
+		}
+	}
+
+	pivot := &format.QueryResult{
+		Columns: append([]string{result.Columns[colV]}, make([]string, len(headers))...),
+		Rows:    make([][]string, len(labels)),
+		Nulls:   make([][]bool, len(labels)),
+	}
+	for i, h := range headers {
+		pivot.Columns[i+1] = crosstabLabel(h)
+	}
+	if len(result.ColumnTypes) == len(result.Columns) {
+		pivot.ColumnTypes = make([]string, len(pivot.Columns))
+		pivot.ColumnTypes[0] = result.ColumnTypes[colV]
+		for i := range headers {
+			pivot.ColumnTypes[i+1] = result.ColumnTypes[colD]
+		}
+	}
+	for i, v := range labels {
+		pivot.Rows[i] = make([]string, len(pivot.Columns))
+		pivot.Nulls[i] = make([]bool, len(pivot.Columns))
+		pivot.Rows[i][0] = crosstabLabel(v)
+		pivot.Nulls[i][0] = v == crosstabNull
+	}
+
+	filled := make(map[[2]int]bool)
+	for i, row := range result.Rows {
+		r := labelIdx[crosstabKey(result, i, colV)]
+		c := headerIdx[crosstabKey(result, i, colH)] + 1
+		if filled[[2]int{r, c}] {
+			return nil, fmt.Errorf("query result contains multiple data values for row \"%s\", column \"%s\"",
+				pivot.Rows[r][0], pivot.Columns[c])
+		}
+		filled[[2]int{r, c}] = true
+		pivot.Rows[r][c] = cellAt(row, colD)
+		pivot.Nulls[r][c] = result.IsNull(i, colD)
+	}
+
+	pivot.RowCount = len(pivot.Rows)
+	pivot.StatusText = fmt.Sprintf("(%d row%s)", len(pivot.Rows), pluralS(len(pivot.Rows)))
+	return pivot, nil
+}
+
+// crosstabNull stands in for NULL in row and header keys so that it never
+// collides with a string value.
+const crosstabNull = "\x00NULL"
+
+func crosstabKey(result *format.QueryResult, row, col int) string {
+	if result.IsNull(row, col) {
+		return crosstabNull
+	}
+	return cellAt(result.Rows[row], col)
+}
+
+func crosstabLabel(key string) string {
+	if key == crosstabNull {
+		return "NULL"
+	}
+	return key
+}
+
+// crosstabColumn resolves a column reference given as a 1-based number or
+// a name. Double-quoted names match exactly; others ignore case.
+func crosstabColumn(result *format.QueryResult, ref string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(result.Columns) {
+			return 0, fmt.Errorf("column number %d is out of range 1..%d", n, len(result.Columns))
+		}
+		return n - 1, nil
+	}
+	if len(ref) >= 2 && ref[0] == '"' && ref[len(ref)-1] == '"' {
+		name := ref[1 : len(ref)-1]
+		for i, col := range result.Columns {
+			if col == name {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("column name not found: \"%s\"", name)
+	}
+	for i, col := range result.Columns {
+		if strings.EqualFold(col, ref) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("column name not found: \"%s\"", ref)
+}
+
+func cellAt(row []string, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return ""
+}
+
+func pluralS(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
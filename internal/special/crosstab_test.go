@@ -0,0 +1,102 @@
+package special
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+func salesResult() *format.QueryResult {
+	return &format.QueryResult{
+		Columns:     []string{"region", "quarter", "amount", "qnum"},
+		ColumnTypes: []string{"TEXT", "TEXT", "INT4", "INT4"},
+		Rows: [][]string{
+			{"east", "Q2", "20", "2"},
+			{"west", "Q1", "5", "1"},
+			{"east", "Q1", "10", "1"},
+		},
+	}
+}
+
+func TestCrosstab(t *testing.T) {
+	pivot, err := Crosstab(salesResult(), []string{"region", "quarter", "amount"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Header values keep their order of first appearance
+	if strings.Join(pivot.Columns, ",") != "region,Q2,Q1" {
+		t.Errorf("unexpected columns: %v", pivot.Columns)
+	}
+	if len(pivot.Rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(pivot.Rows))
+	}
+	if strings.Join(pivot.Rows[0], ",") != "east,20,10" {
+		t.Errorf("unexpected first row: %v", pivot.Rows[0])
+	}
+	// Missing cells are empty, not NULL
+	if pivot.Rows[1][1] != "" || pivot.IsNull(1, 1) {
+		t.Errorf("missing cell should be empty, got %q", pivot.Rows[1][1])
+	}
+	if pivot.ColumnTypes[1] != "INT4" {
+		t.Errorf("data columns should keep the data type, got %v", pivot.ColumnTypes)
+	}
+}
+
+func TestCrosstab_SortColumnAndNumbers(t *testing.T) {
+	pivot, err := Crosstab(salesResult(), []string{"1", "2", "3", "qnum"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(pivot.Columns, ",") != "region,Q1,Q2" {
+		t.Errorf("header should be sorted by qnum, got %v", pivot.Columns)
+	}
+}
+
+func TestCrosstab_Errors(t *testing.T) {
+	dup := salesResult()
+	dup.Rows = append(dup.Rows, []string{"east", "Q1", "99", "1"})
+
+	tests := []struct {
+		name   string
+		result *format.QueryResult
+		args   []string
+		errMsg string
+	}{
+		{"same columns", salesResult(), []string{"region", "region", "amount"}, "must be different"},
+		{"data column required", salesResult(), nil, "data column must be specified"},
+		{"unknown column", salesResult(), []string{"nope"}, "not found"},
+		{"out of range", salesResult(), []string{"9"}, "out of range"},
+		{"duplicate cell", dup, []string{"region", "quarter", "amount"}, `row "east", column "Q1"`},
+		{"bad sort value", salesResult(), []string{"region", "quarter", "amount", "region"}, "invalid integer"},
+		{"too few columns", &format.QueryResult{Columns: []string{"a", "b"}}, nil, "at least three"},
+	}
+
+	for _, tt := range tests {
+		_, err := Crosstab(tt.result, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.errMsg, err)
+		}
+	}
+}
+
+func TestExecute_Crosstabview(t *testing.T) {
+	r := NewRegistry()
+	if _, err := r.Execute(context.Background(), nil, `\crosstabview`); err == nil {
+		t.Error("\\crosstabview without a previous result should error")
+	}
+
+	r.LastResult = &format.QueryResult{
+		Columns: []string{"v", "h", "d"},
+		Rows:    [][]string{{"a", "x", "1"}, {"b", "y", "2"}},
+	}
+	results, err := r.Execute(context.Background(), nil, `\crosstabview`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(results[0].Columns, ",") != "v,x,y" || results[0].StatusText != "(2 rows)" {
+		t.Errorf("unexpected pivot: %v %q", results[0].Columns, results[0].StatusText)
+	}
+}
@@ -3,7 +3,9 @@ package special
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/tomblomfield/gocli/internal/completion"
 	"github.com/tomblomfield/gocli/internal/format"
 	"github.com/tomblomfield/gocli/internal/mysql"
 )
@@ -16,6 +18,7 @@ func RegisterMySQL(r *Registry) {
 		Syntax:      `\dt[+] [table]`,
 		Description: "List or describe tables",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Handler:     mysqlListTables,
 	})
 
@@ -44,8 +47,15 @@ func RegisterMySQL(r *Registry) {
 		Syntax:      `\u database`,
 		Description: "Switch default database",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgDatabase,
 		Aliases:     []string{"use"},
-		Handler:     mysqlUse,
+		Handler: func(ctx context.Context, executor interface{}, arg string, verbose bool) ([]*format.QueryResult, error) {
+			results, err := r.mysqlUse(ctx, executor, arg)
+			if err == nil && r.Hooks.RefreshCompletions != nil {
+				r.Hooks.RefreshCompletions()
+			}
+			return results, err
+		},
 	})
 
 	// connect / \r - Reconnect
@@ -54,9 +64,17 @@ func RegisterMySQL(r *Registry) {
 		Syntax:      `\r [database]`,
 		Description: "Reconnect to the server",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgDatabase,
 		Aliases:     []string{"connect"},
-		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
-			return []*format.QueryResult{{StatusText: fmt.Sprintf("Reconnecting to: %s", arg)}}, nil
+		Handler: func(ctx context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+			if r.Hooks.Connect == nil {
+				return nil, errNoHook
+			}
+			db, err := r.Hooks.Connect(ctx, arg)
+			if err != nil {
+				return nil, err
+			}
+			return []*format.QueryResult{{StatusText: fmt.Sprintf("Reconnected to: %s", db)}}, nil
 		},
 	})
 
@@ -66,12 +84,16 @@ func RegisterMySQL(r *Registry) {
 		Syntax:      `\. filename`,
 		Description: "Execute SQL from file",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFile,
 		Aliases:     []string{"source"},
-		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+		Handler: func(ctx context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
 			if arg == "" {
 				return nil, fmt.Errorf("filename required")
 			}
-			return []*format.QueryResult{{StatusText: fmt.Sprintf("Executing: %s", arg)}}, nil
+			if r.Hooks.RunScript == nil {
+				return nil, errNoHook
+			}
+			return nil, r.Hooks.RunScript(ctx, arg)
 		},
 	})
 
@@ -96,22 +118,25 @@ func RegisterMySQL(r *Registry) {
 		Description: "Change query delimiter",
 		ArgType:     RawQuery,
 		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
-			if arg == "" {
+			fields := strings.Fields(arg)
+			if len(fields) == 0 {
 				return nil, fmt.Errorf("delimiter string required")
 			}
-			return []*format.QueryResult{{StatusText: fmt.Sprintf("Delimiter set to: %s", arg)}}, nil
+			if strings.Contains(fields[0], `\`) {
+				return nil, fmt.Errorf("delimiter cannot contain a backslash character")
+			}
+			r.Delimiter = fields[0]
+			return []*format.QueryResult{{StatusText: fmt.Sprintf("Delimiter set to: %s", r.Delimiter)}}, nil
 		},
 	})
 
-	// \G - Vertical output
+	// \G - Vertical output, the MySQL spelling of \gx
 	r.Register(&Command{
-		Name:        `\g`,
+		Name:        `\G`,
 		Syntax:      `\G`,
-		Description: "Display results vertically",
-		ArgType:     NoQuery,
-		Handler: func(_ context.Context, _ interface{}, _ string, _ bool) ([]*format.QueryResult, error) {
-			return []*format.QueryResult{{StatusText: "Next result will be displayed vertically."}}, nil
-		},
+		Description: "Execute query and display results vertically",
+		ArgType:     RawQuery,
+		Handler:     r.queryOutputHandler(true),
 	})
 
 	// warnings / nowarnings
@@ -142,10 +167,22 @@ func RegisterMySQL(r *Registry) {
 		Syntax:      "tee [-o] filename",
 		Description: "Append all results to given file",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFile,
 		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
-			if arg == "" {
+			overwrite := false
+			if rest, ok := strings.CutPrefix(arg, "-o "); ok {
+				overwrite = true
+				arg = strings.TrimSpace(rest)
+			}
+			if arg == "" || arg == "-o" {
 				return nil, fmt.Errorf("filename required")
 			}
+			if r.Hooks.Tee == nil {
+				return nil, errNoHook
+			}
+			if err := r.Hooks.Tee(arg, overwrite); err != nil {
+				return nil, err
+			}
 			return []*format.QueryResult{{StatusText: fmt.Sprintf("Logging to: %s", arg)}}, nil
 		},
 	})
@@ -156,6 +193,12 @@ func RegisterMySQL(r *Registry) {
 		Description: "Stop writing results to file",
 		ArgType:     NoQuery,
 		Handler: func(_ context.Context, _ interface{}, _ string, _ bool) ([]*format.QueryResult, error) {
+			if r.Hooks.Tee == nil {
+				return nil, errNoHook
+			}
+			if err := r.Hooks.Tee("", false); err != nil {
+				return nil, err
+			}
 			return []*format.QueryResult{{StatusText: "Logging stopped."}}, nil
 		},
 	})
@@ -175,13 +218,19 @@ func RegisterMySQL(r *Registry) {
 
 	// prompt / \R
 	r.Register(&Command{
-		Name:        `\r`,
-		Syntax:      `\R [format]`,
+		Name:        `\R`,
+		Syntax:      `\R format`,
 		Description: "Change prompt format",
 		ArgType:     RawQuery,
 		Aliases:     []string{"prompt"},
-		Hidden:      true,
 		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+			if arg == "" {
+				return nil, fmt.Errorf("prompt format required")
+			}
+			if r.Hooks.SetPrompt == nil {
+				return nil, errNoHook
+			}
+			r.Hooks.SetPrompt(arg)
 			return []*format.QueryResult{{StatusText: fmt.Sprintf("Prompt set to: %s", arg)}}, nil
 		},
 	})
@@ -269,17 +318,23 @@ func mysqlStatus(ctx context.Context, executor interface{}, _ string, _ bool) ([
 	}}, nil
 }
 
-func mysqlUse(ctx context.Context, executor interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
-	e := getMySQLExecutor(executor)
-	if e == nil {
-		return nil, fmt.Errorf("not connected to MySQL")
-	}
+// mysqlUse switches database with USE, run through the RunQuery hook when
+// there is one so the switch is logged like typed statements.
+func (r *Registry) mysqlUse(ctx context.Context, executor interface{}, arg string) ([]*format.QueryResult, error) {
 	if arg == "" {
 		return nil, fmt.Errorf("database name required")
 	}
+	run := r.Hooks.RunQuery
+	if run == nil {
+		e := getMySQLExecutor(executor)
+		if e == nil {
+			return nil, fmt.Errorf("not connected to MySQL")
+		}
+		run = e.Execute
+	}
 
 	query := fmt.Sprintf("USE `%s`", arg)
-	_, err := e.Execute(ctx, query)
+	_, err := run(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,98 @@
+package special
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tomblomfield/gocli/internal/format"
+)
+
+// QueryOutput describes where and how \g sends the output of the query
+// buffer. The app takes it with TakeQueryOutput after running the command.
+type QueryOutput struct {
+	File     string // write output to this file
+	Command  string // pipe output to this shell command
+	Expanded bool   // \gx
+	// Pset holds \pset settings given in parentheses, applied for this
+	// query only.
+	Pset map[string]string
+}
+
+// ParseQueryOutput parses the arguments of \g: an optional parenthesized
+// list of pset options such as "(format=csv header)" followed by a file
+// name or "|command".
+func ParseQueryOutput(arg string) (*QueryOutput, error) {
+	out := &QueryOutput{Pset: make(map[string]string)}
+	arg = strings.TrimSpace(arg)
+
+	if strings.HasPrefix(arg, "(") {
+		end := strings.Index(arg, ")")
+		if end < 0 {
+			return nil, fmt.Errorf(`\g: unterminated option list`)
+		}
+		for _, opt := range strings.Fields(arg[1:end]) {
+			key, val, ok := strings.Cut(opt, "=")
+			if !ok {
+				// A bare option name turns a boolean option on
+				val = "on"
+			}
+			out.Pset[strings.ToLower(key)] = val
+		}
+		arg = strings.TrimSpace(arg[end+1:])
+	}
+
+	if strings.HasPrefix(arg, "|") {
+		out.Command = strings.TrimSpace(arg[1:])
+		if out.Command == "" {
+			return nil, fmt.Errorf(`\g: missing command after "|"`)
+		}
+	} else {
+		out.File = arg
+	}
+	return out, nil
+}
+
+// queryOutputHandler returns the handler for \g and \gx. It only records
+// the request; the app runs the query buffer and routes its output.
+func (r *Registry) queryOutputHandler(expanded bool) CommandHandler {
+	return func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+		out, err := ParseQueryOutput(arg)
+		if err != nil {
+			return nil, err
+		}
+		out.Expanded = expanded
+		r.pendingOutput = out
+		return nil, nil
+	}
+}
+
+// TakeQueryOutput returns and clears the request left by \g or \gx, or nil
+// if the last command was not one of them.
+func (r *Registry) TakeQueryOutput() *QueryOutput {
+	out := r.pendingOutput
+	r.pendingOutput = nil
+	return out
+}
+
+// ApplyQueryOutput applies the per-query settings of out on top of the
+// current \pset state and returns a function that restores that state.
+func (r *Registry) ApplyQueryOutput(out *QueryOutput) (restore func(), err error) {
+	tableFormat, display := r.TableFormat, r.Display
+	expanded, expandedAuto := r.Expanded, r.ExpandedAuto
+	restore = func() {
+		r.TableFormat, r.Display = tableFormat, display
+		r.Expanded, r.ExpandedAuto = expanded, expandedAuto
+	}
+
+	for key, val := range out.Pset {
+		if _, err := r.psetHandler(context.Background(), nil, key+" "+val, false); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	if out.Expanded {
+		r.Expanded, r.ExpandedAuto = true, false
+	}
+	return restore, nil
+}
@@ -0,0 +1,83 @@
+package special
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseQueryOutput(t *testing.T) {
+	tests := []struct {
+		arg     string
+		file    string
+		command string
+		pset    map[string]string
+	}{
+		{"", "", "", nil},
+		{"out.txt", "out.txt", "", nil},
+		{"| wc -l", "", "wc -l", nil},
+		{"(format=csv header) out.csv", "out.csv", "", map[string]string{"format": "csv", "header": "on"}},
+		{"(expanded=on)", "", "", map[string]string{"expanded": "on"}},
+	}
+
+	for _, tt := range tests {
+		out, err := ParseQueryOutput(tt.arg)
+		if err != nil {
+			t.Fatalf("ParseQueryOutput(%q): unexpected error: %v", tt.arg, err)
+		}
+		if out.File != tt.file || out.Command != tt.command {
+			t.Errorf("ParseQueryOutput(%q) = file %q, command %q", tt.arg, out.File, out.Command)
+		}
+		if len(out.Pset) != len(tt.pset) {
+			t.Errorf("ParseQueryOutput(%q) pset = %v, want %v", tt.arg, out.Pset, tt.pset)
+		}
+		for k, v := range tt.pset {
+			if out.Pset[k] != v {
+				t.Errorf("ParseQueryOutput(%q) pset[%s] = %q, want %q", tt.arg, k, out.Pset[k], v)
+			}
+		}
+	}
+
+	for _, bad := range []string{"(format=csv", "|"} {
+		if _, err := ParseQueryOutput(bad); err == nil {
+			t.Errorf("ParseQueryOutput(%q) should error", bad)
+		}
+	}
+}
+
+func TestExecute_QueryOutput(t *testing.T) {
+	r := NewRegistry()
+
+	results, err := r.Execute(context.Background(), nil, `\gx (format=csv) out.csv`)
+	if err != nil || results != nil {
+		t.Fatalf("\\gx should only record the request, got %v, %v", results, err)
+	}
+	out := r.TakeQueryOutput()
+	if out == nil || !out.Expanded || out.File != "out.csv" {
+		t.Fatalf("unexpected output request: %+v", out)
+	}
+	if r.TakeQueryOutput() != nil {
+		t.Error("TakeQueryOutput should clear the request")
+	}
+
+	restore, err := r.ApplyQueryOutput(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.TableFormat != "csv" || !r.Expanded {
+		t.Errorf("options not applied: format %q, expanded %v", r.TableFormat, r.Expanded)
+	}
+	restore()
+	if r.TableFormat != "unicode" || r.Expanded {
+		t.Errorf("options not restored: format %q, expanded %v", r.TableFormat, r.Expanded)
+	}
+
+	if _, err := r.ApplyQueryOutput(&QueryOutput{Pset: map[string]string{"bogus": "1"}}); err == nil {
+		t.Error("unknown pset option should error")
+	}
+	if _, err := r.ApplyQueryOutput(&QueryOutput{Pset: map[string]string{"format": "bogus"}}); err == nil {
+		t.Error("unknown format should error")
+	}
+	if r.TableFormat != "unicode" {
+		t.Error("failed options should leave settings unchanged")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/tomblomfield/gocli/internal/completion"
 	"github.com/tomblomfield/gocli/internal/format"
 	"github.com/tomblomfield/gocli/internal/pg"
 )
@@ -18,6 +19,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\dt[+] [pattern]`,
 		Description: "List tables",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Handler:     pgListTables,
 	})
 
@@ -27,6 +29,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\dv[+] [pattern]`,
 		Description: "List views",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Handler:     pgListViews,
 	})
 
@@ -54,6 +57,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\df[+] [pattern]`,
 		Description: "List functions",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgFunction,
 		Handler:     pgListFunctions,
 	})
 
@@ -63,6 +67,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\dn[+] [pattern]`,
 		Description: "List schemas",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgSchema,
 		Handler:     pgListSchemas,
 	})
 
@@ -81,6 +86,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\l[+] [pattern]`,
 		Description: "List databases",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgDatabase,
 		Handler:     pgListDatabases,
 	})
 
@@ -90,6 +96,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\d[+] [pattern]`,
 		Description: "Describe table or list tables",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Aliases:     []string{"describe"},
 		Handler:     pgDescribe,
 	})
@@ -127,6 +134,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\dp [pattern]`,
 		Description: "List access privileges",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Aliases:     []string{`\z`},
 		Handler:     pgListPrivileges,
 	})
@@ -137,6 +145,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\sf[+] funcname`,
 		Description: "Show function definition",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgFunction,
 		Handler:     pgShowFunction,
 	})
 
@@ -169,16 +178,31 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\i filename`,
 		Description: "Execute commands from file",
 		ArgType:     RawQuery,
-		Handler:     pgExecuteFile,
+		Arg:         completion.ArgFile,
+		Handler: func(ctx context.Context, executor interface{}, arg string, verbose bool) ([]*format.QueryResult, error) {
+			// Run statement by statement when the app can, so each is
+			// checked, logged and timed like typed input
+			if file := strings.TrimSpace(arg); file != "" && r.Hooks.RunScript != nil {
+				return nil, r.Hooks.RunScript(ctx, file)
+			}
+			return pgExecuteFile(ctx, executor, arg, verbose)
+		},
 	})
 
 	// \o - Output to file
 	r.Register(&Command{
 		Name:        `\o`,
 		Syntax:      `\o [filename]`,
-		Description: "Send all query results to file",
+		Description: "Send all query results to file or |pipe",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgFile,
 		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+			if r.Hooks.SetOutput == nil {
+				return nil, errNoHook
+			}
+			if err := r.Hooks.SetOutput(arg); err != nil {
+				return nil, err
+			}
 			if arg == "" {
 				return []*format.QueryResult{{StatusText: "Output reset to stdout."}}, nil
 			}
@@ -201,6 +225,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\dm[+] [pattern]`,
 		Description: "List materialized views",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Handler:     pgListMaterializedViews,
 	})
 
@@ -216,15 +241,20 @@ func RegisterPG(r *Registry) {
 	// \c - Change database
 	r.Register(&Command{
 		Name:        `\c`,
-		Syntax:      `\c[onnect] database_name`,
-		Description: "Change to a new database",
+		Syntax:      `\c[onnect] [database_name]`,
+		Description: "Connect to a new database",
 		ArgType:     RawQuery,
+		Arg:         completion.ArgDatabase,
 		Aliases:     []string{`\connect`},
-		Handler: func(_ context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
-			if arg == "" {
-				return nil, fmt.Errorf("usage: \\c database_name")
+		Handler: func(ctx context.Context, _ interface{}, arg string, _ bool) ([]*format.QueryResult, error) {
+			if r.Hooks.Connect == nil {
+				return nil, errNoHook
+			}
+			db, err := r.Hooks.Connect(ctx, arg)
+			if err != nil {
+				return nil, err
 			}
-			return []*format.QueryResult{{StatusText: fmt.Sprintf("To change database, reconnect with: pgcli -d %s", arg)}}, nil
+			return []*format.QueryResult{{StatusText: fmt.Sprintf("You are now connected to database %q.", db)}}, nil
 		},
 	})
 
@@ -234,6 +264,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\ef funcname`,
 		Description: "Edit function definition in external editor",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgFunction,
 		Handler: func(_ context.Context, _ interface{}, name string, _ bool) ([]*format.QueryResult, error) {
 			if name == "" {
 				return nil, fmt.Errorf("function name required")
@@ -248,6 +279,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\ev viewname`,
 		Description: "Edit view definition in external editor",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Handler: func(_ context.Context, _ interface{}, name string, _ bool) ([]*format.QueryResult, error) {
 			if name == "" {
 				return nil, fmt.Errorf("view name required")
@@ -262,6 +294,7 @@ func RegisterPG(r *Registry) {
 		Syntax:      `\sv[+] viewname`,
 		Description: "Show view definition",
 		ArgType:     ParsedQuery,
+		Arg:         completion.ArgRelation,
 		Handler:     pgShowView,
 	})
 
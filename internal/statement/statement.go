@@ -0,0 +1,270 @@
+// Package statement splits SQL input into statements and tells whether
+// interactive input is complete. It understands each backend's quoting,
+// comments and statement delimiter, so a delimiter inside a string, a
+// comment or a function body never ends a statement.
+package statement
+
+import "strings"
+
+// Syntax describes the lexical rules of a backend. The zero Syntax is
+// standard SQL: single-quoted strings, double-quoted identifiers, -- and
+// /* */ comments and a ";" delimiter.
+type Syntax struct {
+	DollarQuotes     bool   // $$...$$ and $tag$...$tag$ strings
+	EscapeStrings    bool   // E'...' strings with backslash escapes
+	NestedComments   bool   // /* /* */ */ nests
+	BackslashEscapes bool   // \ escapes the next character in every string
+	HashComments     bool   // # starts a line comment
+	Backticks        bool   // `quoted` identifiers
+	DelimiterCommand bool   // a DELIMITER line changes the delimiter
+	BackslashCommand bool   // a line starting with \ is one command
+	Delimiter        string // ends a statement; "" means ";"
+}
+
+var (
+	// PostgreSQL is the syntax of PostgreSQL.
+	PostgreSQL = Syntax{DollarQuotes: true, EscapeStrings: true, NestedComments: true, BackslashCommand: true}
+	// MySQL is the syntax of MySQL, with its default delimiter.
+	MySQL = Syntax{BackslashEscapes: true, HashComments: true, Backticks: true, DelimiterCommand: true, BackslashCommand: true}
+)
+
+// WithDelimiter returns s ending statements with delimiter instead.
+func (s Syntax) WithDelimiter(delimiter string) Syntax {
+	s.Delimiter = delimiter
+	return s
+}
+
+func (s Syntax) delimiter() string {
+	if s.Delimiter == "" {
+		return ";"
+	}
+	return s.Delimiter
+}
+
+// scan is the result of lexing some input.
+type scan struct {
+	stmts    []string // delimited statements, trimmed, without delimiters
+	rest     string   // what follows the last delimiter
+	restCode bool     // rest has more than whitespace and comments
+	open     bool     // input ends inside a string, comment or quote
+}
+
+func (s Syntax) scan(input string) scan {
+	delim := s.delimiter()
+	var res scan
+	start, code := 0, false
+	for i := 0; i < len(input); {
+		if s.DelimiterCommand && !code && lineStart(input, i) {
+			if d, end, ok := delimiterCommand(input[i:]); ok {
+				delim = d
+				i += end
+				start = i
+				continue
+			}
+		}
+		if s.BackslashCommand && !code && input[i] == '\\' && lineStart(input, i) {
+			// A client command such as \dt ends at the newline, as in psql
+			nl := strings.IndexByte(input[i:], '\n')
+			if nl < 0 {
+				res.rest, res.restCode = input[i:], true
+				return res
+			}
+			cmd := strings.TrimSpace(input[i : i+nl])
+			res.stmts = append(res.stmts, strings.TrimSpace(strings.TrimSuffix(cmd, delim)))
+			i += nl + 1
+			start = i
+			continue
+		}
+		if strings.HasPrefix(input[i:], delim) {
+			if code {
+				res.stmts = append(res.stmts, strings.TrimSpace(input[start:i]))
+			}
+			i += len(delim)
+			start, code = i, false
+			continue
+		}
+		end, isCode, closed := s.element(input, i)
+		code = code || isCode
+		if !closed {
+			res.open = true
+		}
+		i = end
+	}
+	res.rest = input[start:]
+	res.restCode = code
+	return res
+}
+
+// lineStart reports whether only spaces and tabs precede i on its line.
+func lineStart(input string, i int) bool {
+	for j := i - 1; j >= 0 && input[j] != '\n'; j-- {
+		if input[j] != ' ' && input[j] != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// delimiterCommand reads a "DELIMITER x" line at the start of line,
+// returning the new delimiter and the length of the line.
+func delimiterCommand(line string) (delimiter string, end int, ok bool) {
+	end = len(line)
+	if nl := strings.IndexByte(line, '\n'); nl >= 0 {
+		end = nl + 1
+	}
+	fields := strings.Fields(line[:end])
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "delimiter") {
+		return "", 0, false
+	}
+	return fields[1], end, true
+}
+
+// element returns the end of the lexical element starting at i, whether
+// it is code rather than whitespace or a comment, and whether it is
+// terminated.
+func (s Syntax) element(input string, i int) (end int, code, closed bool) {
+	ch := input[i]
+	next := byte(0)
+	if i+1 < len(input) {
+		next = input[i+1]
+	}
+	switch {
+	case ch == '-' && next == '-', ch == '#' && s.HashComments:
+		if nl := strings.IndexByte(input[i:], '\n'); nl >= 0 {
+			return i + nl + 1, false, true
+		}
+		return len(input), false, true
+	case ch == '/' && next == '*':
+		end, closed = s.blockComment(input, i)
+		return end, false, closed
+	case ch == '\'':
+		end, closed = quoted(input, i, '\'', s.BackslashEscapes)
+		return end, true, closed
+	case (ch == 'E' || ch == 'e') && next == '\'' && s.EscapeStrings && !identBefore(input, i):
+		end, closed = quoted(input, i+1, '\'', true)
+		return end, true, closed
+	case ch == '"':
+		end, closed = quoted(input, i, '"', s.BackslashEscapes)
+		return end, true, closed
+	case ch == '`' && s.Backticks:
+		end, closed = quoted(input, i, '`', false)
+		return end, true, closed
+	case ch == '$' && s.DollarQuotes && !identBefore(input, i):
+		if tag := DollarTag(input, i); tag != "" {
+			body := i + len(tag)
+			if close := strings.Index(input[body:], tag); close >= 0 {
+				return body + close + len(tag), true, true
+			}
+			return len(input), true, false
+		}
+	}
+	return i + 1, !isSpace(ch), true
+}
+
+// blockComment finds the end of the /* comment at i, counting nested
+// comments when the syntax allows them.
+func (s Syntax) blockComment(input string, i int) (end int, closed bool) {
+	depth := 0
+	for j := i; j+1 < len(input); j++ {
+		switch {
+		case input[j] == '/' && input[j+1] == '*':
+			if depth == 0 || s.NestedComments {
+				depth++
+			}
+			j++
+		case input[j] == '*' && input[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return len(input), false
+}
+
+// quoted finds the end of the string or identifier opened by quote at i.
+// A doubled quote stands for itself; with backslash set, so does a quote
+// after a backslash.
+func quoted(input string, i int, quote byte, backslash bool) (end int, closed bool) {
+	for j := i + 1; j < len(input); j++ {
+		switch {
+		case backslash && input[j] == '\\':
+			j++
+		case input[j] == quote:
+			if j+1 < len(input) && input[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return len(input), false
+}
+
+// DollarTag returns the dollar-quote opener at pos, such as "$$" or
+// "$body$", or "" if there is none. Tags follow identifier rules, so
+// positional parameters like $1 are not tags.
+func DollarTag(sql string, pos int) string {
+	if pos >= len(sql) || sql[pos] != '$' {
+		return ""
+	}
+	for end := pos + 1; end < len(sql); end++ {
+		ch := sql[end]
+		switch {
+		case ch == '$':
+			return sql[pos : end+1]
+		case isIdentStart(ch), end > pos+1 && ch >= '0' && ch <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentStart(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_' || ch >= 0x80
+}
+
+// identBefore reports whether the byte before i continues an identifier,
+// as in foo$1 or name', where a quote or dollar starts nothing new.
+func identBefore(input string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	ch := input[i-1]
+	return isIdentStart(ch) || (ch >= '0' && ch <= '9') || ch == '$'
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f'
+}
+
+// Split splits input into statements at each delimiter outside strings,
+// quoted identifiers, comments and dollar quotes. Statements are trimmed
+// and lose their delimiter; a final statement needs none. Statements that
+// are only comments are dropped. With DelimiterCommand, DELIMITER lines
+// between statements change the delimiter for the rest of input and are
+// dropped too. With BackslashCommand, a line starting with a backslash
+// between statements is a statement of its own.
+func Split(input string, s Syntax) []string {
+	res := s.scan(input)
+	stmts := res.stmts
+	if res.restCode {
+		stmts = append(stmts, strings.TrimSpace(res.rest))
+	}
+	return stmts
+}
+
+// Complete reports whether input holds at least one statement and ends
+// with a delimiter, followed by nothing but whitespace and comments.
+func Complete(input string, s Syntax) bool {
+	res := s.scan(input)
+	return len(res.stmts) > 0 && !res.restCode && !res.open
+}
+
+// Open reports whether input ends inside a string, quoted identifier,
+// block comment or dollar quote, where a line break can't end it.
+func Open(input string, s Syntax) bool {
+	return s.scan(input).open
+}
@@ -0,0 +1,146 @@
+package statement
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		syntax Syntax
+		want   []string
+	}{
+		{"simple", "SELECT 1; SELECT 2", Syntax{}, []string{"SELECT 1", "SELECT 2"}},
+		{"empty statements", ";; SELECT 1;;", Syntax{}, []string{"SELECT 1"}},
+		{"string", "SELECT 'a;b'; SELECT 2", Syntax{}, []string{"SELECT 'a;b'", "SELECT 2"}},
+		{"doubled quote", "SELECT 'it''s;'; SELECT 2", Syntax{}, []string{"SELECT 'it''s;'", "SELECT 2"}},
+		{"identifier", `SELECT "a;b" FROM t; SELECT 2`, Syntax{}, []string{`SELECT "a;b" FROM t`, "SELECT 2"}},
+		{"line comment", "SELECT 1 -- x; y\n; SELECT 2", Syntax{}, []string{"SELECT 1 -- x; y", "SELECT 2"}},
+		{"comment only", "SELECT 1; -- done", Syntax{}, []string{"SELECT 1"}},
+		{
+			"dollar quote",
+			"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql; SELECT f()",
+			PostgreSQL,
+			[]string{"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			"tagged dollar quote",
+			"DO $body$ BEGIN PERFORM '$$;'; END $body$; SELECT 2",
+			PostgreSQL,
+			[]string{"DO $body$ BEGIN PERFORM '$$;'; END $body$", "SELECT 2"},
+		},
+		{"positional parameter", "SELECT $1; SELECT $2", PostgreSQL, []string{"SELECT $1", "SELECT $2"}},
+		{"escape string", `SELECT E'it\'s;'; SELECT 2`, PostgreSQL, []string{`SELECT E'it\'s;'`, "SELECT 2"}},
+		{"standard string keeps backslash", `SELECT 'C:\'; SELECT 2`, PostgreSQL, []string{`SELECT 'C:\'`, "SELECT 2"}},
+		{"nested comment", "SELECT /* a /* b; */ c; */ 1; SELECT 2", PostgreSQL, []string{"SELECT /* a /* b; */ c; */ 1", "SELECT 2"}},
+		{"unnested comment", "SELECT /* a /* b */ 1; SELECT 2", MySQL, []string{"SELECT /* a /* b */ 1", "SELECT 2"}},
+		{"mysql backslash", `SELECT 'it\'s;'; SELECT 2`, MySQL, []string{`SELECT 'it\'s;'`, "SELECT 2"}},
+		{"mysql double-quoted string", `SELECT "say \"hi\";"; SELECT 2`, MySQL, []string{`SELECT "say \"hi\";"`, "SELECT 2"}},
+		{"mysql hash comment", "SELECT 1 # x; y\n; SELECT 2", MySQL, []string{"SELECT 1 # x; y", "SELECT 2"}},
+		{"mysql backticks", "SELECT `a;b` FROM t; SELECT 2", MySQL, []string{"SELECT `a;b` FROM t", "SELECT 2"}},
+		{
+			"custom delimiter",
+			"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END //\nCALL p() //",
+			MySQL.WithDelimiter("//"),
+			[]string{"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END", "CALL p()"},
+		},
+		{
+			"delimiter command",
+			"DELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND //\ndelimiter ;\nCALL p();",
+			MySQL,
+			[]string{"CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND", "CALL p()"},
+		},
+		{
+			"delimiter word inside a statement",
+			"SELECT a,\ndelimiter FROM t; SELECT 2",
+			MySQL,
+			[]string{"SELECT a,\ndelimiter FROM t", "SELECT 2"},
+		},
+		{"backslash command", "\\dt\nSELECT 1;\n  \\x on;\nSELECT 2", PostgreSQL, []string{`\dt`, "SELECT 1", `\x on`, "SELECT 2"}},
+		{"backslash inside a statement", "SELECT 1\n\\g out.txt\n", PostgreSQL, []string{"SELECT 1\n\\g out.txt"}},
+		{"mysql backslash command", "\\u shop\nSELECT 1", MySQL, []string{`\u shop`, "SELECT 1"}},
+		{"backslash without the syntax", "\\dt\nSELECT 1", Syntax{}, []string{"\\dt\nSELECT 1"}},
+		{"delimiter line without a command", "DELIMITER //\nSELECT 1; SELECT 2", Syntax{}, []string{"DELIMITER //\nSELECT 1", "SELECT 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.input, tt.syntax); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		input  string
+		syntax Syntax
+		want   bool
+	}{
+		{"SELECT 1;", Syntax{}, true},
+		{"SELECT 1;  \n", Syntax{}, true},
+		{"SELECT 1; -- trailing note", Syntax{}, true},
+		{"SELECT 1", Syntax{}, false},
+		{";", Syntax{}, false},
+		{"SELECT 1; SELECT", Syntax{}, false},
+		{"SELECT 'a;", Syntax{}, false},
+		{"SELECT 1 /* ; */", Syntax{}, false},
+		{"SELECT 1 /* open;", Syntax{}, false},
+		{"CREATE FUNCTION f() AS $$\nBEGIN\n  RETURN 1;", PostgreSQL, false},
+		{"CREATE FUNCTION f() AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;", PostgreSQL, true},
+		{"BEGIN SELECT 1;", MySQL.WithDelimiter("$$"), false},
+		{"BEGIN SELECT 1; END $$", MySQL.WithDelimiter("$$"), true},
+	}
+	for _, tt := range tests {
+		if got := Complete(tt.input, tt.syntax); got != tt.want {
+			t.Errorf("Complete(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		input  string
+		syntax Syntax
+		want   bool
+	}{
+		{"SELECT 1", Syntax{}, false},
+		{"SELECT 'abc", Syntax{}, true},
+		{`SELECT "abc`, Syntax{}, true},
+		{"SELECT /* abc", Syntax{}, true},
+		{"SELECT 1 -- abc", Syntax{}, false},
+		{"SELECT $$abc", PostgreSQL, true},
+		{"SELECT $$abc", Syntax{}, false},
+		{`SELECT 'a\'`, MySQL, true},
+		{`SELECT 'a\'`, PostgreSQL, false},
+		{"SELECT /* a /* b */", PostgreSQL, true},
+		{"SELECT /* a /* b */", MySQL, false},
+	}
+	for _, tt := range tests {
+		if got := Open(tt.input, tt.syntax); got != tt.want {
+			t.Errorf("Open(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDollarTag(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$$ body $$", "$$"},
+		{"$fn$ body $fn$", "$fn$"},
+		{"$_x1$", "$_x1$"},
+		{"$1", ""},
+		{"$1$", ""},
+		{"$a b$", ""},
+		{"x", ""},
+	}
+	for _, tt := range tests {
+		if got := DollarTag(tt.input, 0); got != tt.want {
+			t.Errorf("DollarTag(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}